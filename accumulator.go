@@ -0,0 +1,249 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// An Accumulator computes the exact sum of Decimal values (and of their
+// products) and rounds it only once, on demand. The zero value for an
+// Accumulator is an empty sum, equal to +0.
+//
+// Finite terms are added to an unbounded fixed point buffer aligned on Word
+// boundaries, so that adding a term costs time proportional to its length and
+// allocates only when the buffer grows. The size of the buffer depends on the
+// range of the exponents of the terms: summing 1e-1000 and 1e1000 requires a
+// buffer of 2000 digits.
+//
+// An Accumulator must not be copied after first use.
+type Accumulator struct {
+	mant dec   // |sum of finite terms| = mant × 10**exp; normalized
+	exp  int64 // exponent of mant's least significant digit; a multiple of _DW
+	neg  bool  // sign of the sum of finite terms
+	inf  int8  // -1 or +1 if -Inf or +Inf has been added
+	zero int8  // sign of the sum if exactly zero: -1 if all terms are -0, +1 if any term is not -0
+	nz   bool  // a nonzero finite term has been added
+	t, p dec   // scratch buffers for aligned and product mantissae
+}
+
+// Reset resets a to an empty sum.
+func (a *Accumulator) Reset() {
+	a.mant = a.mant[:0]
+	a.exp = 0
+	a.neg = false
+	a.inf = 0
+	a.zero = 0
+	a.nz = false
+}
+
+// Add adds x to the sum. Add panics with ErrNaN if x and a previously added
+// term are infinities with opposite signs.
+func (a *Accumulator) Add(x *Decimal) {
+	a.add(x, x.neg)
+}
+
+// Sub subtracts x from the sum. Sub panics with ErrNaN if x and a previously
+// added term are infinities with equal signs.
+func (a *Accumulator) Sub(x *Decimal) {
+	a.add(x, !x.neg)
+}
+
+// AddProduct adds the exact product x×y to the sum. AddProduct panics with
+// ErrNaN if one of x or y is zero and the other an infinity, or if the
+// product and a previously added term are infinities with opposite signs.
+func (a *Accumulator) AddProduct(x, y *Decimal) {
+	if debugDecimal {
+		x.validate()
+		y.validate()
+	}
+	neg := x.neg != y.neg
+	if x.form == finite && y.form == finite {
+		if x == y {
+			a.p = a.p.sqr(x.mant)
+		} else {
+			a.p = a.p.mul(x.mant, y.mant)
+		}
+		e := int64(x.exp) - int64(len(x.mant))*_DW + int64(y.exp) - int64(len(y.mant))*_DW
+		a.addMant(neg, a.p, e)
+		return
+	}
+	switch {
+	case x.form == inf && y.form == zero || x.form == zero && y.form == inf:
+		panic(ErrNaN{"multiplication of zero with infinity"})
+	case x.form == inf || y.form == inf:
+		a.addInf(neg)
+	default:
+		a.addZero(neg)
+	}
+}
+
+// add adds x with the sign neg to the sum.
+func (a *Accumulator) add(x *Decimal, neg bool) {
+	if debugDecimal {
+		x.validate()
+	}
+	switch x.form {
+	case finite:
+		a.addMant(neg, x.mant, int64(x.exp)-int64(len(x.mant))*_DW)
+	case zero:
+		a.addZero(neg)
+	case inf:
+		a.addInf(neg)
+	}
+}
+
+func (a *Accumulator) addZero(neg bool) {
+	if !neg {
+		a.zero = 1
+	} else if a.zero == 0 {
+		a.zero = -1
+	}
+}
+
+func (a *Accumulator) addInf(neg bool) {
+	if a.inf != 0 && (a.inf < 0) != neg {
+		panic(ErrNaN{"addition of infinities with opposite signs"})
+	}
+	a.inf = 1
+	if neg {
+		a.inf = -1
+	}
+}
+
+// addMant adds the signed integer mantissa m × 10**e to the sum. m must be
+// normalized.
+func (a *Accumulator) addMant(neg bool, m dec, e int64) {
+	if len(m) == 0 {
+		a.addZero(neg)
+		return
+	}
+	a.zero = 1
+	a.nz = true
+
+	// align m on a Word boundary
+	if r := e % _DW; r != 0 {
+		if r < 0 {
+			r += _DW
+		}
+		a.t = a.t.shl(m, uint(r))
+		m = a.t
+		e -= r
+	}
+
+	if len(a.mant) == 0 {
+		a.mant = a.mant.set(m)
+		a.exp = e
+		a.neg = neg
+		return
+	}
+	if e < a.exp {
+		// extend a.mant downwards
+		n, k := len(a.mant), int((a.exp-e)/_DW)
+		a.grow(n + k)
+		copy(a.mant[k:], a.mant[:n])
+		a.mant[:k].clear()
+		a.exp = e
+	}
+
+	k := int((e - a.exp) / _DW) // offset of m in a.mant
+	if neg == a.neg {
+		// |a| + |m|
+		a.grow(k + len(m) + 1)
+		c := add10VV(a.mant[k:k+len(m)], a.mant[k:k+len(m)], m)
+		add10VW(a.mant[k+len(m):], a.mant[k+len(m):], c)
+		a.mant = a.mant.norm()
+		return
+	}
+	if a.cmpAt(m, k) >= 0 {
+		// |a| - |m|; len(a.mant) >= k + len(m)
+		c := sub10VV(a.mant[k:k+len(m)], a.mant[k:k+len(m)], m)
+		sub10VW(a.mant[k+len(m):], a.mant[k+len(m):], c)
+		a.mant = a.mant.norm()
+		return
+	}
+	// |m| - |a|
+	a.grow(k + len(m))
+	var c Word
+	for i := range a.mant[:k] {
+		a.mant[i], c = sub10WWW_g(0, a.mant[i], c)
+	}
+	for i, w := range m {
+		a.mant[k+i], c = sub10WWW_g(w, a.mant[k+i], c)
+	}
+	a.mant = a.mant.norm()
+	a.neg = neg
+}
+
+// grow zero-extends a.mant to n words if it is shorter.
+func (a *Accumulator) grow(n int) {
+	if m := len(a.mant); m < n {
+		a.mant = append(a.mant, make(dec, n-m)...)
+	}
+}
+
+// cmpAt compares |a| and m × _DB**k.
+func (a *Accumulator) cmpAt(m dec, k int) int {
+	if la, lm := len(a.mant), k+len(m); la != lm {
+		if la < lm {
+			return -1
+		}
+		return +1
+	}
+	for i := len(a.mant) - 1; i >= 0; i-- {
+		var w Word
+		if i >= k {
+			w = m[i-k]
+		}
+		switch x := a.mant[i]; {
+		case x < w:
+			return -1
+		case x > w:
+			return +1
+		}
+	}
+	return 0
+}
+
+// Decimal sets z to the sum rounded according to z's precision and rounding
+// mode, and returns z. If z is nil, a new Decimal is allocated. If z's
+// precision is 0, it is changed to the larger of the minimum precision of the
+// sum or DefaultDecimalPrec (and rounding will have no effect). z's accuracy
+// reports the result error relative to the exact sum. a is unchanged and more
+// terms can be added afterwards.
+//
+// If the exact sum is zero, the result is -0 if all the terms are -0, or if any
+// term is nonzero and z's rounding mode is ToNegativeInf. It is +0 otherwise.
+func (a *Accumulator) Decimal(z *Decimal) *Decimal {
+	if z == nil {
+		z = new(Decimal)
+	}
+	z.acc = Exact
+	if a.inf != 0 {
+		z.form = inf
+		z.neg = a.inf < 0
+		return z
+	}
+	if len(a.mant) == 0 {
+		z.form = zero
+		z.neg = a.zero < 0 || a.nz && z.mode == ToNegativeInf
+		return z
+	}
+	if z.prec == 0 {
+		z.prec = umax32(uint32(a.mant.digits()-a.mant.trailingZeroDigits()), DefaultDecimalPrec)
+	}
+	// Only the n most significant words are needed for rounding, the others
+	// are folded into the sticky bit.
+	m, sbit := a.mant, uint(0)
+	if n := int((uint64(z.prec)+(_DW-1))/_DW) + 1; len(m) > n {
+		for _, w := range m[:len(m)-n] {
+			if w != 0 {
+				sbit = 1
+				break
+			}
+		}
+		m = m[len(m)-n:]
+	}
+	z.neg = a.neg
+	z.mant = z.mant.set(m)
+	z.setExpAndRound(a.exp+int64(len(a.mant))*_DW-dnorm(z.mant), sbit)
+	return z
+}
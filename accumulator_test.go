@@ -0,0 +1,204 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestAccumulator(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var a Accumulator
+		sum := new(big.Rat)
+		n := 1 + r.Intn(50)
+		maxExp := []int{2, 20, 100, 1000}[r.Intn(4)]
+		for j := 0; j < n; j++ {
+			x := rndDecimal(r, uint(1+r.Intn(60)), maxExp)
+			xr, _ := x.Rat(nil)
+			switch r.Intn(3) {
+			case 0:
+				a.Add(x)
+				sum.Add(sum, xr)
+			case 1:
+				a.Sub(x)
+				sum.Sub(sum, xr)
+			case 2:
+				y := rndDecimal(r, uint(1+r.Intn(30)), maxExp/2)
+				yr, _ := y.Rat(nil)
+				a.AddProduct(x, y)
+				sum.Add(sum, xr.Mul(xr, yr))
+			}
+			if r.Intn(10) == 0 {
+				// exact cancellation of the current sum
+				z := a.Decimal(new(Decimal).SetPrec(MaxPrec))
+				if zr, _ := z.Rat(nil); zr.Cmp(sum) != 0 || z.Acc() != Exact {
+					t.Fatalf("exact sum = %s (%s); want %s", z, z.Acc(), sum.FloatString(10))
+				}
+				a.Sub(z)
+				sum.SetInt64(0)
+			}
+		}
+		for _, mode := range []RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf} {
+			prec := uint(1 + r.Intn(50))
+			want := new(Decimal).SetPrec(prec).SetMode(mode).SetRat(sum)
+			got := a.Decimal(new(Decimal).SetPrec(prec).SetMode(mode))
+			if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+				t.Fatalf("sum (prec %d, %s) = %s (%s); want %s (%s)", prec, mode, got, got.Acc(), want, want.Acc())
+			}
+		}
+	}
+}
+
+func TestAccumulatorSpecialValues(t *testing.T) {
+	var (
+		pz   = makeDecimal("0")
+		nz   = makeDecimal("-0")
+		one  = makeDecimal("1")
+		pinf = makeDecimal("+Inf")
+		ninf = makeDecimal("-Inf")
+	)
+	for _, test := range []struct {
+		add  []*Decimal
+		sub  []*Decimal
+		mode RoundingMode
+		want string
+	}{
+		{nil, nil, ToNearestEven, "0"},
+		{[]*Decimal{nz}, nil, ToNearestEven, "-0"},
+		{[]*Decimal{nz, nz}, nil, ToNearestEven, "-0"},
+		{[]*Decimal{nz, pz}, nil, ToNearestEven, "0"},
+		{[]*Decimal{nz}, []*Decimal{pz}, ToNearestEven, "-0"},
+		{[]*Decimal{nz}, []*Decimal{nz}, ToNearestEven, "0"},
+		{[]*Decimal{one}, []*Decimal{one}, ToNearestEven, "0"},
+		{[]*Decimal{one}, []*Decimal{one}, ToNegativeInf, "-0"},
+		{[]*Decimal{one, pinf}, nil, ToNearestEven, "+Inf"},
+		{[]*Decimal{one, pinf, pinf}, []*Decimal{ninf}, ToNearestEven, "+Inf"},
+		{[]*Decimal{ninf}, []*Decimal{one}, ToNearestEven, "-Inf"},
+	} {
+		var a Accumulator
+		for _, x := range test.add {
+			a.Add(x)
+		}
+		for _, x := range test.sub {
+			a.Sub(x)
+		}
+		z := a.Decimal(new(Decimal).SetMode(test.mode))
+		if want := makeDecimal(test.want); !alike(z, want) || z.Acc() != Exact {
+			t.Errorf("sum %v - %v (%s) = %s (%s); want %s", test.add, test.sub, test.mode, z, z.Acc(), test.want)
+		}
+	}
+
+	for _, f := range []func(a *Accumulator){
+		func(a *Accumulator) { a.Add(pinf); a.Add(ninf) },
+		func(a *Accumulator) { a.Add(pinf); a.Sub(pinf) },
+		func(a *Accumulator) { a.AddProduct(pinf, one); a.AddProduct(ninf, one) },
+		func(a *Accumulator) { a.AddProduct(pinf, pz) },
+		func(a *Accumulator) { a.AddProduct(nz, ninf) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(ErrNaN); !ok {
+					t.Errorf("expected ErrNaN panic")
+				}
+			}()
+			f(new(Accumulator))
+		}()
+	}
+
+	var a Accumulator
+	a.AddProduct(ninf, new(Decimal).Neg(one))
+	a.AddProduct(nz, one)
+	if z := a.Decimal(nil); !alike(z, pinf) {
+		t.Errorf("-Inf × -1 + -0 × 1 = %s; want +Inf", z)
+	}
+	a.Reset()
+	a.AddProduct(nz, one)
+	if z := a.Decimal(nil); !alike(z, makeDecimal("-0")) {
+		t.Errorf("-0 × 1 = %s; want -0", z)
+	}
+
+	// precision 0 and Reset
+	a.Reset()
+	a.Add(makeDecimal("1e100"))
+	a.Add(makeDecimal("1e-100"))
+	z := a.Decimal(nil)
+	if z.Prec() != 201 || z.Cmp(makeDecimal("1e100")) <= 0 || z.Acc() != Exact {
+		t.Errorf("1e100 + 1e-100 = %s (prec %d, %s)", z, z.Prec(), z.Acc())
+	}
+	if z := a.Decimal(new(Decimal).SetPrec(10)); z.Cmp(makeDecimal("1e100")) != 0 || z.Acc() != Below {
+		t.Errorf("1e100 + 1e-100 = %s (%s); want 1e100 (Below)", z, z.Acc())
+	}
+	a.Reset()
+	if z := a.Decimal(nil); !alike(z, makeDecimal("0")) {
+		t.Errorf("empty sum = %s; want 0", z)
+	}
+
+	// overflow
+	a.Add(makeExp("0.9", MaxExp))
+	a.Add(makeExp("0.9", MaxExp))
+	if z := a.Decimal(new(Decimal).SetPrec(10)); !alike(z, pinf) || z.Acc() != Above {
+		t.Errorf("overflowing sum = %s (%s); want +Inf (Above)", z, z.Acc())
+	}
+}
+
+func TestAccumulatorAllocs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	xs := make([]*Decimal, 100)
+	for i := range xs {
+		xs[i] = rndDecimal(r, uint(1+r.Intn(40)), 10)
+	}
+	var a Accumulator
+	z := new(Decimal).SetPrec(34)
+	// warm up
+	for _, x := range xs {
+		a.Add(x)
+		a.AddProduct(x, x)
+	}
+	a.Decimal(z)
+	allocs := testing.AllocsPerRun(10, func() {
+		a.Reset()
+		for _, x := range xs {
+			a.Add(x)
+			a.AddProduct(x, x)
+		}
+		a.Decimal(z)
+	})
+	if allocs > 0 {
+		t.Errorf("got %v allocs; want 0", allocs)
+	}
+}
+
+func BenchmarkAccumulator(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	xs := make([]*Decimal, 1000)
+	for i := range xs {
+		xs[i] = rndDecimal(r, uint(1+r.Intn(20)), 5)
+	}
+	b.Run("Accumulator", func(b *testing.B) {
+		b.ReportAllocs()
+		var a Accumulator
+		z := new(Decimal).SetPrec(34)
+		for i := 0; i < b.N; i++ {
+			a.Reset()
+			for _, x := range xs {
+				a.Add(x)
+			}
+			a.Decimal(z)
+		}
+	})
+	b.Run("Decimal.Add", func(b *testing.B) {
+		b.ReportAllocs()
+		z := new(Decimal).SetPrec(34)
+		for i := 0; i < b.N; i++ {
+			z.SetInt64(0)
+			for _, x := range xs {
+				z.Add(z, x)
+			}
+		}
+	})
+}
@@ -6,7 +6,7 @@
 
 package decimal
 
-var support_adx = false
+var support_adx = x86HasADX && x86HasBMI2
 
 // keep golint quiet
 var _ = support_adx
@@ -0,0 +1,35 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !math_big_pure_go
+
+package decimal
+
+import "testing"
+
+// TestAddMulVVWADX checks addMulVVW against addMulVVW_g, with and without the
+// ADX kernel if it is supported.
+func TestAddMulVVWADX(t *testing.T) {
+	defer func(old bool) { support_adx = old }(support_adx)
+	for _, adx := range []bool{false, support_adx} {
+		support_adx = adx
+		for _, n := range []int{0, 1, 2, 7, 8, 9, 16, 17, 100} {
+			for i := 0; i < 100; i++ {
+				x := rndV(n)
+				y := rndW()
+				z, zg := rndV(n), make([]Word, n)
+				copy(zg, z)
+				c, cg := addMulVVW(z, x, y), addMulVVW_g(zg, x, y)
+				if c != cg {
+					t.Fatalf("adx=%v: addMulVVW carry = %d; want %d", adx, c, cg)
+				}
+				for j := range z {
+					if z[j] != zg[j] {
+						t.Fatalf("adx=%v: addMulVVW z[%d] = %d; want %d", adx, j, z[j], zg[j])
+					}
+				}
+			}
+		}
+	}
+}
@@ -27,6 +27,16 @@
 // the receiver z, but its value is undefined). Further operations with the
 // context will be no-ops until (Context).Err is called to check for errors.
 //
+// A Context can also round results stochastically (see SetRandSource): results
+// are rounded away from zero with a probability equal to the discarded fraction
+// of a unit in the last place, which makes rounding errors unbiased on average.
+// The rounding caveat above does not apply in this case: operations are carried
+// out with extra digits and rounded only once, to c's precision.
+//
+// Arithmetic operators can take their temporaries from a decimal.Workspace
+// attached to the Context (see SetWorkspace) rather than from the decimal
+// package's shared pools.
+//
 // Although it does not exactly provide IEEE-754 NaNs, it provides a form of
 // support for quiet NaNs.
 //
@@ -40,17 +50,24 @@ package context
 import (
 	"errors"
 	"math/big"
+	"math/rand"
 
 	"github.com/db47h/decimal"
 )
 
 const handleNaNs = true
 
+// stochasticGuard is the number of extra digits with which results are computed
+// before being rounded stochastically.
+const stochasticGuard = 20
+
 // A Context is a wrapper around Decimals that facilitates management of
 // rounding modes, precision and error handling.
 type Context struct {
 	prec uint32
 	mode decimal.RoundingMode
+	src  rand.Source
+	ws   *decimal.Workspace
 	err  error
 }
 
@@ -80,6 +97,47 @@ func (c *Context) SetMode(mode decimal.RoundingMode) *Context {
 	return c
 }
 
+// RandSource returns c's source of random numbers for stochastic rounding, or
+// nil if stochastic rounding is disabled.
+func (c *Context) RandSource() rand.Source {
+	return c.src
+}
+
+// SetRandSource enables stochastic rounding of the results of c's operators,
+// using random numbers from src, and returns c. Stochastic rounding replaces c's
+// rounding mode, which still applies to the decimal.Decimal values created by
+// c's factory functions. If src is nil, stochastic rounding is disabled.
+//
+// Results are computed with 20 extra digits, rounded toward zero, before being
+// rounded with (*decimal.Decimal).RoundStochastic. The probability of rounding
+// away from zero is therefore exact for results that fit in c.Prec()+20 digits,
+// and within 10**-20 of the exact probability otherwise. For a given src seed,
+// results are deterministic.
+//
+// A rand.Source is usually not safe for concurrent use; neither is a Context
+// with a random source.
+func (c *Context) SetRandSource(src rand.Source) *Context {
+	c.src = src
+	return c
+}
+
+// Workspace returns the workspace used by c's operators, or nil if none.
+func (c *Context) Workspace() *decimal.Workspace {
+	return c.ws
+}
+
+// SetWorkspace makes c's Add, Sub, Mul, Quo and Sqrt operators take their
+// temporaries from w, and returns c. This makes them allocation free once w
+// has grown to the size needed by the operands, unless c rounds
+// stochastically. If w is nil, the package's shared pools are used.
+//
+// Since a Workspace is not safe for concurrent use, neither is a Context with
+// a workspace, nor its copies.
+func (c *Context) SetWorkspace(w *decimal.Workspace) *Context {
+	c.ws = w
+	return c
+}
+
 func setPrec(prec uint) uint32 {
 	// special case
 	if prec == 0 {
@@ -174,6 +232,9 @@ func (c *Context) Set(z, x *decimal.Decimal) *decimal.Decimal {
 			return z
 		}
 	}
+	if c.src != nil {
+		return c.finish(z, c.prepare(z).Set(x))
+	}
 	return c.apply(z.Copy(x))
 }
 
@@ -186,6 +247,33 @@ func (c *Context) apply(z *decimal.Decimal) *decimal.Decimal {
 	return z
 }
 
+// prepare returns the Decimal in which to compute the result of an operation
+// with receiver z: z itself with c's precision and rounding mode applied or, if
+// c rounds stochastically, a temporary Decimal with stochasticGuard extra
+// digits that rounds toward zero.
+func (c *Context) prepare(z *decimal.Decimal) *decimal.Decimal {
+	if c.src == nil {
+		return c.apply(z)
+	}
+	return new(decimal.Decimal).SetMode(decimal.ToZero).SetPrec(uint(c.prec) + stochasticGuard)
+}
+
+// finish sets z to the result t of an operation computed in c.prepare(z) and
+// returns z.
+func (c *Context) finish(z, t *decimal.Decimal) *decimal.Decimal {
+	if c.src == nil {
+		return t
+	}
+	if t.Acc() != decimal.Exact && t.Sign() != 0 && !t.IsInf() {
+		// t was truncated: append a nonzero sticky digit so that the
+		// discarded fraction is not mistaken for zero, neither when rounding
+		// nor when reporting the accuracy.
+		p := t.Prec()
+		t.SetPrec(p+1).Add(t, decimal.NewDecimal(int64(t.Sign()), t.MantExp(nil)-int(p)-1)) // exact
+	}
+	return c.apply(z).RoundStochastic(t, c.src)
+}
+
 // Add sets z to the rounded sum x+y and returns z.
 func (c *Context) Add(z, x, y *decimal.Decimal) (r *decimal.Decimal) {
 	if handleNaNs {
@@ -201,7 +289,7 @@ func (c *Context) Add(z, x, y *decimal.Decimal) (r *decimal.Decimal) {
 			}
 		}()
 	}
-	return c.apply(z).Add(x, y)
+	return c.finish(z, c.ws.Add(c.prepare(z), x, y))
 }
 
 // Sub sets z to the rounded difference x+y and returns z.
@@ -219,7 +307,7 @@ func (c *Context) Sub(z, x, y *decimal.Decimal) (r *decimal.Decimal) {
 			}
 		}()
 	}
-	return c.apply(z).Sub(x, y)
+	return c.finish(z, c.ws.Sub(c.prepare(z), x, y))
 }
 
 // FMA sets z to x * y + u, computed with only one rounding. That is, FMA
@@ -238,7 +326,7 @@ func (c *Context) FMA(z, x, y, u *decimal.Decimal) (r *decimal.Decimal) {
 			}
 		}()
 	}
-	return c.apply(z).FMA(x, y, u)
+	return c.finish(z, c.prepare(z).FMA(x, y, u))
 }
 
 // Mul sets z to the rounded product x×y and returns z.
@@ -256,7 +344,7 @@ func (c *Context) Mul(z, x, y *decimal.Decimal) (r *decimal.Decimal) {
 			}
 		}()
 	}
-	return c.apply(z).Mul(x, y)
+	return c.finish(z, c.ws.Mul(c.prepare(z), x, y))
 }
 
 // Quo sets z to the rounded quotient x/y and returns z.
@@ -274,7 +362,7 @@ func (c *Context) Quo(z, x, y *decimal.Decimal) (r *decimal.Decimal) {
 			}
 		}()
 	}
-	return c.apply(z).Quo(x, y)
+	return c.finish(z, c.ws.Quo(c.prepare(z), x, y))
 }
 
 // Neg sets z to the (possibly rounded) value of x with its sign negated,
@@ -285,7 +373,7 @@ func (c *Context) Neg(z, x *decimal.Decimal) *decimal.Decimal {
 			return z
 		}
 	}
-	return c.apply(z).Neg(x)
+	return c.finish(z, c.prepare(z).Neg(x))
 }
 
 // Abs sets z to the (possibly rounded) value |x| (the absolute value of x)
@@ -296,7 +384,7 @@ func (c *Context) Abs(z, x *decimal.Decimal) *decimal.Decimal {
 			return z
 		}
 	}
-	return c.apply(z).Abs(x)
+	return c.finish(z, c.prepare(z).Abs(x))
 }
 
 // Sqrt sets z to the rounded square root of x, and returns z.
@@ -315,5 +403,5 @@ func (c *Context) Sqrt(z, x *decimal.Decimal) (r *decimal.Decimal) {
 			}
 		}()
 	}
-	return c.apply(z).Sqrt(x)
+	return c.finish(z, c.ws.Sqrt(c.prepare(z), x))
 }
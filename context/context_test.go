@@ -3,6 +3,7 @@ package context
 import (
 	"errors"
 	"math"
+	"math/rand"
 	"strconv"
 	"testing"
 
@@ -24,6 +25,121 @@ func TestContext_handleErrors(t *testing.T) {
 	}
 }
 
+func TestContext_stochastic(t *testing.T) {
+	c := New(3, decimal.ToNearestEven)
+	if c.RandSource() != nil {
+		t.Fatal("stochastic rounding enabled by default")
+	}
+	// adding 1e-4 to 1 ten thousand times: rounding to nearest stagnates at
+	// 1, stochastic rounding is unbiased.
+	sum := func(c *Context) *decimal.Decimal {
+		z, x := c.NewInt64(1), c.NewFloat64(1e-4)
+		for i := 0; i < 10000; i++ {
+			c.Add(z, z, x)
+		}
+		return z
+	}
+	if z := sum(&c); z.Cmp(c.NewInt64(1)) != 0 {
+		t.Fatalf("sum with %s = %s; want 1", c.Mode(), z)
+	}
+	c.SetRandSource(rand.NewSource(1))
+	z := sum(&c)
+	if z.Cmp(c.NewFloat64(1.5)) < 0 || z.Cmp(c.NewFloat64(2.5)) > 0 || z.Prec() != 3 || z.Mode() != decimal.ToNearestEven {
+		t.Fatalf("stochastic sum = %s (prec %d, %s); want ~2", z, z.Prec(), z.Mode())
+	}
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// deterministic for a given seed
+	c.SetRandSource(rand.NewSource(1))
+	if z2 := sum(&c); z2.Cmp(z) != 0 {
+		t.Fatalf("stochastic sum = %s; previous run gave %s", z2, z)
+	}
+
+	// 1/3 rounds to 0.334 with probability 1/3
+	c.SetRandSource(rand.NewSource(2))
+	up := 0
+	one, three, want := c.NewInt64(1), c.NewInt64(3), c.NewFloat64(0.334)
+	for i := 0; i < 3000; i++ {
+		z := c.Quo(c.New(), one, three)
+		if z.Cmp(want) == 0 {
+			up++
+		}
+	}
+	if up < 850 || up > 1150 {
+		t.Fatalf("1/3 rounded up %d times out of 3000; want ~1000", up)
+	}
+
+	// z aliases x: no double rounding
+	z = c.NewFloat64(1.25)
+	c.SetPrec(2).SetRandSource(rand.NewSource(3))
+	c.Set(z, z)
+	if z.Cmp(c.NewFloat64(1.2)) != 0 && z.Cmp(c.NewFloat64(1.3)) != 0 {
+		t.Fatalf("Set(z, z) = %s; want 1.2 or 1.3", z)
+	}
+
+	// the digits truncated before rounding count as a nonzero sticky digit
+	c.SetPrec(5).SetRandSource(rand.NewSource(4))
+	for i := 0; i < 10; i++ {
+		x := c.NewFloat64(1e-30)
+		if i&1 != 0 {
+			x.Neg(x)
+		}
+		z := c.Add(c.New(), c.NewInt64(1), x)
+		// z is 1 or its neighbor on the side of x
+		acc := decimal.Above
+		if (z.Cmp(c.NewInt64(1)) == 0) == (x.Sign() > 0) {
+			acc = decimal.Below
+		}
+		if z.Acc() != acc {
+			t.Fatalf("1 + %s = %s (%s); want %s", x, z, z.Acc(), acc)
+		}
+	}
+
+	// errors are still reported
+	c.Quo(c.New(), c.New(), c.New())
+	var nan decimal.ErrNaN
+	if err := c.Err(); !errors.As(err, &nan) {
+		t.Fatalf("err %v is not ErrNaN", err)
+	}
+	c.SetRandSource(nil)
+	if c.RandSource() != nil {
+		t.Fatal("stochastic rounding not disabled")
+	}
+}
+
+func TestContext_workspace(t *testing.T) {
+	c := New(100, decimal.ToNearestEven)
+	if c.Workspace() != nil {
+		t.Fatal("workspace set by default")
+	}
+	x, y, z := c.NewInt64(2), c.NewInt64(3), c.New()
+	f := func() {
+		c.Quo(z, x, y)
+		c.Sqrt(z, z)
+		c.Mul(z, z, z)
+		c.Add(z, z, x)
+		c.Sub(z, z, x)
+		c.Sqrt(z, z)
+	}
+	f()
+	want := c.New().Copy(z)
+
+	var w decimal.Workspace
+	c.SetWorkspace(&w)
+	f()
+	if z.Cmp(want) != 0 {
+		t.Fatalf("got %s; want %s", z, want)
+	}
+	if allocs := testing.AllocsPerRun(10, f); allocs != 0 {
+		t.Errorf("got %v allocs; want 0", allocs)
+	}
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 var (
 	eight     = new(decimal.Decimal).SetPrec(9).SetUint64(8)
 	thirtyTwo = new(decimal.Decimal).SetPrec(9).SetUint64(32)
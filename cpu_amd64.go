@@ -0,0 +1,21 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// cpuid is implemented in cpu_amd64.s.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// CPU features used by the assembly kernels, detected like in
+// golang.org/x/sys/cpu. BMI2 provides MULX, ADX provides ADCX and ADOX.
+var x86HasBMI2, x86HasADX = x86Features()
+
+func x86Features() (bmi2, adx bool) {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false, false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	return ebx7&(1<<8) != 0, ebx7&(1<<19) != 0
+}
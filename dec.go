@@ -11,18 +11,43 @@ import (
 )
 
 // The following thresholds are hugely different from their counterparts
-// in math/big.
+// in math/big. Their defaults are computed by dec_calibrate_test.go for the
+// calibrated architectures and can be changed with SetThresholds.
 
 // Operands that are shorter than decKaratsubaThreshold are multiplied using
 // "grade school" multiplication; for longer operands the Karatsuba algorithm
 // is used.
-var decKaratsubaThreshold = 30 // computed by calibrate_test.go
+var decKaratsubaThreshold = archThresholds.Karatsuba
+
+// Operands that are at least decToom3Threshold long are multiplied using the
+// Toom-Cook 3-way algorithm. The same applies to squaring with
+// decToom3SqrThreshold.
+var decToom3Threshold = archThresholds.Toom3
+var decToom3SqrThreshold = archThresholds.Toom3Sqr
+
+// Operands that are at least decNTTThreshold long are multiplied using a number
+// theoretic transform. The same applies to squaring with decNTTSqrThreshold.
+var decNTTThreshold = archThresholds.NTT
+var decNTTSqrThreshold = archThresholds.NTTSqr
+
+// Divisors that are shorter than decDivRecursiveThreshold are handled by
+// schoolbook division; for longer divisors we use recursive division.
+var decDivRecursiveThreshold = archThresholds.DivRecursive
+
+// Divisions where both the divisor and the quotient are at least
+// decDivNewtonThreshold long are computed using Newton's method.
+var decDivNewtonThreshold = archThresholds.DivNewton
 
 // Operands that are shorter than decBasicSqrThreshold are squared using
 // "grade school" multiplication; for operands longer than karatsubaSqrThreshold
 // we use the Karatsuba algorithm optimized for x == y.
-var decBasicSqrThreshold = 10     // computed by calibrate_test.go
-var decKaratsubaSqrThreshold = 50 // computed by calibrate_test.go
+var decBasicSqrThreshold = archThresholds.BasicSqr
+var decKaratsubaSqrThreshold = archThresholds.KaratsubaSqr
+
+// Operands that are shorter than decConvThreshold are converted between dec
+// and big.Word slices using quadratic word-by-word conversion; for longer
+// operands we use divide and conquer.
+var decConvThreshold = archThresholds.Conv
 
 // dec is an unsigned integer x of the form
 //
@@ -169,7 +194,20 @@ func (x dec) toUint64() (uint64, bool) {
 	return uint64(r)<<32 | uint64(lo), z0 == 0
 }
 
+// decToNat converts x to a big.Word slice, using z as storage if possible.
 func decToNat(z []big.Word, x dec) []big.Word {
+	if len(x) < decConvThreshold {
+		return decToNatBasic(z, x)
+	}
+	// The result of decToNatRec is a temporary; copy its bits to z.
+	t := decToNatRec(x).Bits()
+	z = makeNat(z, len(t))
+	copy(z, t)
+	return z
+}
+
+// decToNatBasic is the quadratic version of decToNat.
+func decToNatBasic(z []big.Word, x dec) []big.Word {
 	if len(x) == 0 {
 		return z[:0]
 	}
@@ -200,13 +238,42 @@ func decToNat(z []big.Word, x dec) []big.Word {
 	return z[0:i]
 }
 
-// setNat sets z = x.mant
+// decToNatRec converts x to a big.Int using divide and conquer: with k the
+// largest power of two < len(x), x = hi × _DB**k + lo, where hi and lo are
+// converted recursively and recombined with a single multiplication by a
+// cached power of _DB.
+func decToNatRec(x dec) *big.Int {
+	if len(x) < decConvThreshold {
+		return new(big.Int).SetBits(decToNatBasic(nil, x))
+	}
+	i := bits.Len(uint(len(x)-1)) - 1 // 2**i < len(x) <= 2**(i+1)
+	k := 1 << uint(i)
+	z := decToNatRec(x[k:])
+	z.Mul(z, decConvPowers.nat(i))
+	return z.Add(z, decToNatRec(x[:k].norm()))
+}
+
+// setNat sets z = x, using z as storage if possible.
 func (z dec) setNat(x []big.Word) dec {
+	x = normNat(x)
+	if len(x) < decConvThreshold {
+		return z.setNatBasic(x)
+	}
+	return z.set(natToDecRec(x))
+}
+
+// setNatBasic is the quadratic version of setNat. x must be normalized.
+func (z dec) setNatBasic(x []big.Word) dec {
+	if len(x) == 0 {
+		return z[:0]
+	}
 	// here we cannot directly copy(b, bb) because big.Word != decimal.Word.
 	b := make([]Word, len(x))
 	for i := 0; i < len(b) && i < len(x); i++ {
 		b[i] = Word(x[i])
 	}
+	// digits = bits * Log(2) / Log(10) + 1
+	z = z.make((int(float64(len(x)*_W)*log10_2) + _DW) / _DW)
 	for i := 0; i < len(z); i++ {
 		z[i] = divWVW(b, 0, b, _DB)
 	}
@@ -214,6 +281,69 @@ func (z dec) setNat(x []big.Word) dec {
 	return z
 }
 
+// natToDecRec is the converse of decToNatRec: with k the largest power of two
+// < len(x), x = hi × 2**(_W×k) + lo, and hi and lo are converted recursively.
+// x must be normalized.
+func natToDecRec(x []big.Word) dec {
+	if len(x) < decConvThreshold {
+		return dec(nil).setNatBasic(x)
+	}
+	i := bits.Len(uint(len(x)-1)) - 1 // 2**i < len(x) <= 2**(i+1)
+	k := 1 << uint(i)
+	z := natToDecRec(x[k:])
+	z = z.mul(z, decConvPowers.dec(i))
+	return z.add(z, natToDecRec(normNat(x[:k])))
+}
+
+// normNat returns x without its leading zero Words.
+func normNat(x []big.Word) []big.Word {
+	i := len(x)
+	for i > 0 && x[i-1] == 0 {
+		i--
+	}
+	return x[0:i]
+}
+
+// decConvCache caches the powers of _DB and 2**_W used by decToNatRec and
+// natToDecRec. The cached values are shared and must not be modified.
+type decConvCache struct {
+	sync.Mutex
+	n []*big.Int // n[i] = _DB**(2**i)
+	d []dec      // d[i] = 2**(_W×2**i)
+}
+
+var decConvPowers decConvCache
+
+// nat returns _DB**(2**i).
+func (c *decConvCache) nat(i int) *big.Int {
+	c.Lock()
+	defer c.Unlock()
+	for len(c.n) <= i {
+		if len(c.n) == 0 {
+			c.n = append(c.n, new(big.Int).SetUint64(_DB))
+			continue
+		}
+		p := c.n[len(c.n)-1]
+		c.n = append(c.n, new(big.Int).Mul(p, p))
+	}
+	return c.n[i]
+}
+
+// dec returns 2**(_W×2**i).
+func (c *decConvCache) dec(i int) dec {
+	c.Lock()
+	defer c.Unlock()
+	for len(c.d) <= i {
+		if len(c.d) == 0 {
+			c.d = append(c.d, dec(nil).mulAddWW(dec(nil).setUint64(1<<(_W-1)), 2, 0))
+			continue
+		}
+		p := c.d[len(c.d)-1]
+		c.d = append(c.d, dec(nil).sqr(p))
+	}
+	return c.d[i]
+}
+
 // sticky returns 1 if there's a non zero digit within the
 // i least significant digits, otherwise it returns 0.
 func (x dec) sticky(i uint) uint {
@@ -338,6 +468,11 @@ func (z dec) divW(x dec, y Word) (q dec, r Word) {
 }
 
 func (z dec) div(z2, u, v dec) (q, r dec) {
+	return z.divWith(nil, z2, u, v)
+}
+
+// divWith is like div, but takes its temporaries from w.
+func (z dec) divWith(w *Workspace, z2, u, v dec) (q, r dec) {
 	if len(v) == 0 {
 		panic("division by zero")
 	}
@@ -355,7 +490,12 @@ func (z dec) div(z2, u, v dec) (q, r dec) {
 		return
 	}
 
-	q, r = z.divLarge(z2, u, v)
+	if len(v) >= decDivNewtonThreshold && len(u)-len(v) >= decDivNewtonThreshold {
+		q, r = z.divNewton(w, z2, u, v)
+		return
+	}
+
+	q, r = z.divLarge(w, z2, u, v)
 	return
 }
 
@@ -386,14 +526,14 @@ var decPool sync.Pool
 //    len(vIn) >= 2
 //    len(uIn) >= len(vIn)
 //    u must not alias z
-func (z dec) divLarge(u, uIn, vIn dec) (q, r dec) {
+func (z dec) divLarge(w *Workspace, u, uIn, vIn dec) (q, r dec) {
 	n := len(vIn)
 	m := len(uIn)
 
 	// D1.
 	d := _DB / (vIn[n-1] + 1)
 	// do not modify vIn, it may be used by another goroutine simultaneously
-	vp := getDec(n)
+	vp := w.getDec(n)
 	v := *vp
 	mulAdd10VWW(v, vIn, d, 0)
 
@@ -407,12 +547,12 @@ func (z dec) divLarge(u, uIn, vIn dec) (q, r dec) {
 	}
 	q = z.make(m - n + 1)
 
-	if n < divRecursiveThreshold {
-		q.divBasic(u, v)
+	if n < decDivRecursiveThreshold {
+		q.divBasic(w, u, v)
 	} else {
-		q.divRecursive(u, v)
+		q.divRecursive(w, u, v)
 	}
-	putDec(vp)
+	w.putDec(vp)
 
 	q = q.norm()
 	r, _ = u.divW(u, d)
@@ -428,11 +568,11 @@ func (z dec) divLarge(u, uIn, vIn dec) (q, r dec) {
 // - q is large enough to hold the quotient u / v
 //   which has a maximum length of len(u)-len(v)+1.
 // - v[len(v)-1] >= _DB/2
-func (q dec) divBasic(u, v dec) {
+func (q dec) divBasic(w *Workspace, u, v dec) {
 	n := len(v)
 	m := len(u) - n
 
-	qhatvp := getDec(n + 1)
+	qhatvp := w.getDec(n + 1)
 	qhatv := *qhatvp
 	// D2.
 	vn1 := v[n-1]
@@ -488,7 +628,7 @@ func (q dec) divBasic(u, v dec) {
 		q[j] = qhat
 	}
 
-	putDec(qhatvp)
+	w.putDec(qhatvp)
 }
 
 // modW returns x % d.
@@ -512,6 +652,26 @@ func (z dec) mulAddWW(x dec, y, r Word) dec {
 	return z.norm()
 }
 
+// z = x**n
+func (z dec) expWW(x Word, n uint64) dec {
+	z = z.setWord(1)
+	if n == 0 {
+		return z
+	}
+	// x**n == product of x**(2**i) for all i where bit i of n is set
+	p := dec(nil).setWord(x)
+	for {
+		if n&1 != 0 {
+			z = z.mul(z, p)
+		}
+		if n >>= 1; n == 0 {
+			break
+		}
+		p = p.sqr(p)
+	}
+	return z
+}
+
 // z = x * 10**s
 func (z dec) shl(x dec, s uint) dec {
 	if s == 0 {
@@ -567,6 +727,11 @@ func (z dec) shr(x dec, s uint) dec {
 
 // z = x*x
 func (z dec) sqr(x dec) dec {
+	return z.sqrWith(nil, x)
+}
+
+// sqrWith is like sqr, but takes its temporaries from w.
+func (z dec) sqrWith(w *Workspace, x dec) dec {
 	n := len(x)
 	switch {
 	case n == 0:
@@ -587,9 +752,19 @@ func (z dec) sqr(x dec) dec {
 		decBasicMul(z, x, x)
 		return z.norm()
 	}
+	if n >= decNTTSqrThreshold {
+		z = z.make(2 * n)
+		decNTT(w, z, x, x)
+		return z.norm()
+	}
+	if n >= decToom3SqrThreshold {
+		z = z.make(2 * n)
+		decToom3(w, z, x, x)
+		return z.norm()
+	}
 	if n < decKaratsubaSqrThreshold {
 		z = z.make(2 * n)
-		decBasicSqr(z, x)
+		decBasicSqr(w, z, x)
 		return z.norm()
 	}
 	// Use Karatsuba multiplication optimized for x == y.
@@ -601,21 +776,22 @@ func (z dec) sqr(x dec) dec {
 
 	x0 := x[0:k]
 	z = z.make(max(6*k, 2*n))
-	decKaratsubaSqr(z, x0) // z = x0^2
+	decKaratsubaSqr(w, z, x0) // z = x0^2
 	z = z[0 : 2*n]
 	z[2*k:].clear()
 
 	if k < n {
-		tp := getDec(2 * k)
+		tp := w.getDec(2 * k)
 		t := *tp
 		x0 := x0.norm()
 		x1 := x[k:]
-		t = t.mul(x0, x1)
+		t = t.mulWith(w, x0, x1)
 		decAddAt(z, t, k)
 		decAddAt(z, t, k) // z = 2*x1*x0*b + x0^2
-		t = t.sqr(x1)
+		t = t.sqrWith(w, x1)
 		decAddAt(z, t, 2*k) // z = x1^2*b^2 + 2*x1*x0*b + x0^2
-		putDec(tp)
+		*tp = t
+		w.putDec(tp)
 	}
 
 	return z.norm()
@@ -625,9 +801,9 @@ func (z dec) sqr(x dec) dec {
 // by about a factor of 2, but slower for small arguments due to overhead.
 // Requirements: len(x) > 0, len(z) == 2*len(x)
 // The (non-normalized) result is placed in z.
-func decBasicSqr(z, x dec) {
+func decBasicSqr(w *Workspace, z, x dec) {
 	n := len(x)
-	tp := getDec(2 * n)
+	tp := w.getDec(2 * n)
 	t := *tp // temporary variable to hold the products
 	t.clear()
 	z[1], z[0] = mul10WW(x[0], x[0]) // the initial square
@@ -641,7 +817,7 @@ func decBasicSqr(z, x dec) {
 	// t[2*n-1] = shlVU(t[1:2*n-1], t[1:2*n-1], 1) // double the j < i products
 	t[2*n-1] = mulAdd10VWW(t[1:2*n-1], t[1:2*n-1], 2, 0)
 	add10VV(z, z, t) // combine the result
-	putDec(tp)
+	w.putDec(tp)
 }
 
 // decKaratsubaSqr squares x and leaves the result in z.
@@ -649,19 +825,24 @@ func decBasicSqr(z, x dec) {
 // The (non-normalized) result is placed in z[0 : 2*len(x)].
 //
 // The algorithm and the layout of z are the same as for karatsuba.
-func decKaratsubaSqr(z, x dec) {
+func decKaratsubaSqr(w *Workspace, z, x dec) {
 	n := len(x)
 
 	if n&1 != 0 || n < decKaratsubaSqrThreshold || n < 2 {
-		decBasicSqr(z[:2*n], x)
+		decBasicSqr(w, z[:2*n], x)
+		return
+	}
+
+	if decParallel(n) {
+		decKaratsubaSqrParallel(w, z, x)
 		return
 	}
 
 	n2 := n >> 1
 	x1, x0 := x[n2:], x[0:n2]
 
-	decKaratsubaSqr(z, x0)
-	decKaratsubaSqr(z[n:], x1)
+	decKaratsubaSqr(w, z, x0)
+	decKaratsubaSqr(w, z[n:], x1)
 
 	// s = sign(xd*yd) == -1 for xd != 0; s == 1 for xd == 0
 	xd := z[2*n : 2*n+n2]
@@ -670,7 +851,7 @@ func decKaratsubaSqr(z, x dec) {
 	}
 
 	p := z[n*3:]
-	decKaratsubaSqr(p, xd)
+	decKaratsubaSqr(w, p, xd)
 
 	r := z[n*4:]
 	copy(r, z[:n*2])
@@ -692,12 +873,17 @@ func decBasicMul(z, x, y dec) {
 }
 
 func (z dec) mul(x, y dec) dec {
+	return z.mulWith(nil, x, y)
+}
+
+// mulWith is like mul, but takes its temporaries from w.
+func (z dec) mulWith(w *Workspace, x, y dec) dec {
 	m := len(x)
 	n := len(y)
 
 	switch {
 	case m < n:
-		return z.mul(y, x)
+		return z.mulWith(w, y, x)
 	case m == 0 || n == 0:
 		return z[:0]
 	case n == 1:
@@ -718,6 +904,18 @@ func (z dec) mul(x, y dec) dec {
 	}
 	// m >= n && n >= karatsubaThreshold && n >= 2
 
+	// use NTT or Toom-3 for large numbers
+	switch {
+	case n >= decNTTThreshold:
+		z = z.make(m + n)
+		decMulChunks(w, z, x, y, decNTT)
+		return z.norm()
+	case n >= decToom3Threshold:
+		z = z.make(m + n)
+		decMulChunks(w, z, x, y, decToom3)
+		return z.norm()
+	}
+
 	// determine Karatsuba length k such that
 	//
 	//   x = xh*b + x0  (0 <= x0 < b)
@@ -731,7 +929,7 @@ func (z dec) mul(x, y dec) dec {
 	x0 := x[0:k]              // x0 is not normalized
 	y0 := y[0:k]              // y0 is not normalized
 	z = z.make(max(6*k, m+n)) // enough space for karatsuba of x0*y0 and full result of x*y
-	decKaratsuba(z, x0, y0)
+	decKaratsuba(w, z, x0, y0)
 	z = z[0 : m+n]  // z has final length but may be incomplete
 	z[2*k:].clear() // upper portion of z is garbage (and 2*k <= m+n since k <= n <= m)
 
@@ -749,13 +947,13 @@ func (z dec) mul(x, y dec) dec {
 	// be a larger valid threshold contradicting the assumption about k.
 	//
 	if k < n || m != n {
-		tp := getDec(3 * k)
+		tp := w.getDec(3 * k)
 		t := *tp
 
 		// add x0*y1*b
 		x0 := x0.norm()
-		y1 := y[k:]       // y1 is normalized because y is
-		t = t.mul(x0, y1) // update t so we don't lose t's underlying array
+		y1 := y[k:]              // y1 is normalized because y is
+		t = t.mulWith(w, x0, y1) // update t so we don't lose t's underlying array
 		decAddAt(z, t, k)
 
 		// add xi*y0<<i, xi*y1*b<<(i+k)
@@ -766,13 +964,14 @@ func (z dec) mul(x, y dec) dec {
 				xi = xi[:k]
 			}
 			xi = xi.norm()
-			t = t.mul(xi, y0)
+			t = t.mulWith(w, xi, y0)
 			decAddAt(z, t, i)
-			t = t.mul(xi, y1)
+			t = t.mulWith(w, xi, y1)
 			decAddAt(z, t, i+k)
 		}
 
-		putDec(tp)
+		*tp = t
+		w.putDec(tp)
 	}
 
 	return z.norm()
@@ -788,28 +987,28 @@ func (z dec) mul(x, y dec) dec {
 // See Burnikel, Ziegler, "Fast Recursive Division", Algorithm 1 and 2.
 // TODO(db47h): review https://pure.mpg.de/rest/items/item_1819444_4/component/file_2599480/content
 // and make sure that when calling divBasic, the preconditions are met.
-func (z dec) divRecursive(u, v dec) {
+func (z dec) divRecursive(w *Workspace, u, v dec) {
 	// Recursion depth is less than 2 log2(len(v))
 	// Allocate a slice of temporaries to be reused across recursion.
 	recDepth := 2 * bits.Len(uint(len(v)))
 	// large enough to perform Karatsuba on operands as large as v
-	tmp := getDec(3 * len(v))
-	temps := make([]*dec, recDepth)
+	tmp := w.getDec(3 * len(v))
+	temps := w.getTemps(recDepth)
 	z.clear()
-	z.divRecursiveStep(u, v, 0, tmp, temps)
+	z.divRecursiveStep(w, u, v, 0, tmp, temps)
 	for _, n := range temps {
 		if n != nil {
-			putDec(n)
+			w.putDec(n)
 		}
 	}
-	putDec(tmp)
+	w.putDec(tmp)
 }
 
 // divRecursiveStep computes the division of u by v.
 // - z must be large enough to hold the quotient
 // - the quotient will overwrite z
 // - the remainder will overwrite u
-func (z dec) divRecursiveStep(u, v dec, depth int, tmp *dec, temps []*dec) {
+func (z dec) divRecursiveStep(w *Workspace, u, v dec, depth int, tmp *dec, temps []*dec) {
 	u = u.norm()
 	v = v.norm()
 
@@ -818,8 +1017,8 @@ func (z dec) divRecursiveStep(u, v dec, depth int, tmp *dec, temps []*dec) {
 		return
 	}
 	n := len(v)
-	if n < divRecursiveThreshold {
-		z.divBasic(u, v)
+	if n < decDivRecursiveThreshold {
+		z.divBasic(w, u, v)
 		return
 	}
 	m := len(u) - n
@@ -835,7 +1034,7 @@ func (z dec) divRecursiveStep(u, v dec, depth int, tmp *dec, temps []*dec) {
 
 	// Allocate a nat for qhat below.
 	if temps[depth] == nil {
-		temps[depth] = getDec(n)
+		temps[depth] = w.getDec(n)
 	} else {
 		*temps[depth] = temps[depth].make(B + 1)
 	}
@@ -859,7 +1058,7 @@ func (z dec) divRecursiveStep(u, v dec, depth int, tmp *dec, temps []*dec) {
 
 		qhat := *temps[depth]
 		qhat.clear()
-		qhat.divRecursiveStep(uu[s:B+n], v[s:], depth+1, tmp, temps)
+		qhat.divRecursiveStep(w, uu[s:B+n], v[s:], depth+1, tmp, temps)
 		qhat = qhat.norm()
 		// Adjust the quotient:
 		//    u = u_h << s + u_l
@@ -873,7 +1072,7 @@ func (z dec) divRecursiveStep(u, v dec, depth int, tmp *dec, temps []*dec) {
 		// But it may be a bit too large, in which case q̂ needs to be smaller.
 		qhatv := tmp.make(3 * n)
 		qhatv.clear()
-		qhatv = qhatv.mul(qhat, v[:s])
+		qhatv = qhatv.mulWith(w, qhat, v[:s])
 		for i := 0; i < 2; i++ {
 			e := qhatv.cmp(uu.norm())
 			if e <= 0 {
@@ -902,11 +1101,11 @@ func (z dec) divRecursiveStep(u, v dec, depth int, tmp *dec, temps []*dec) {
 	s := B
 	qhat := *temps[depth]
 	qhat.clear()
-	qhat.divRecursiveStep(u[s:].norm(), v[s:], depth+1, tmp, temps)
+	qhat.divRecursiveStep(w, u[s:].norm(), v[s:], depth+1, tmp, temps)
 	qhat = qhat.norm()
 	qhatv := tmp.make(3 * n)
 	qhatv.clear()
-	qhatv = qhatv.mul(qhat, v[:s])
+	qhatv = qhatv.mulWith(w, qhat, v[:s])
 	// Set the correct remainder as before.
 	for i := 0; i < 2; i++ {
 		if e := qhatv.cmp(u.norm()); e > 0 {
@@ -966,7 +1165,7 @@ func decKaratsubaSub(z, x dec, n int) {
 // Both x and y must have the same length n and n must be a
 // power of 2. The result vector z must have len(z) >= 6*n.
 // The (non-normalized) result is placed in z[0 : 2*n].
-func decKaratsuba(z, x, y dec) {
+func decKaratsuba(w *Workspace, z, x, y dec) {
 	n := len(y)
 
 	// Switch to basic multiplication if numbers are odd or small.
@@ -978,6 +1177,11 @@ func decKaratsuba(z, x, y dec) {
 	}
 	// n&1 == 0 && n >= karatsubaThreshold && n >= 2
 
+	if decParallel(n) {
+		decKaratsubaParallel(w, z, x, y)
+		return
+	}
+
 	// Karatsuba multiplication is based on the observation that
 	// for two numbers x and y with:
 	//
@@ -1016,8 +1220,8 @@ func decKaratsuba(z, x, y dec) {
 	// caller's z.
 
 	// compute z0 and z2 with the result "in place" in z
-	decKaratsuba(z, x0, y0)     // z0 = x0*y0
-	decKaratsuba(z[n:], x1, y1) // z2 = x1*y1
+	decKaratsuba(w, z, x0, y0)     // z0 = x0*y0
+	decKaratsuba(w, z[n:], x1, y1) // z2 = x1*y1
 
 	// compute xd (or the negative value if underflow occurs)
 	s := 1 // sign of product xd*yd
@@ -1037,7 +1241,7 @@ func decKaratsuba(z, x, y dec) {
 	// p = (x1-x0)*(y0-y1) == x1*y0 - x1*y1 - x0*y0 + x0*y1 for s > 0
 	// p = (x0-x1)*(y0-y1) == x0*y0 - x0*y1 - x1*y0 + x1*y1 for s < 0
 	p := z[n*3:]
-	decKaratsuba(p, xd, yd)
+	decKaratsuba(w, p, xd, yd)
 
 	// save original z2:z0
 	// (ok to use upper half of z since we're done recursing)
@@ -0,0 +1,417 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !decimal_pure_go
+
+#include "textflag.h"
+
+#define _DB  1000000000
+#define _DMax 999999999
+#define _DW 9
+
+// This file provides fast assembly versions for the elementary
+// arithmetic operations on vectors implemented in dec_arith.go.
+//
+// With _DB = 1e9, the sum of two Words never overflows 32 bits, and the
+// product of two Words plus two Words fits in EDX:EAX with a high Word below
+// _DB, so that DIVL by _DB never overflows.
+
+// func mul10WW(x, y Word) (z1, z0 Word)
+TEXT ·mul10WW(SB),NOSPLIT,$0
+	MOVL x+0(FP), AX
+	MULL y+4(FP)
+	MOVL $_DB, CX
+	DIVL CX
+	MOVL AX, z1+8(FP)
+	MOVL DX, z0+12(FP)
+	RET
+
+
+// func div10WW(x1, x0, y Word) (q, r Word)
+TEXT ·div10WW(SB),NOSPLIT,$0
+	MOVL x1+0(FP), AX
+	MOVL $_DB, CX
+	MULL CX			// DX:AX = x1 * _DB
+	ADDL x0+4(FP), AX
+	ADCL $0, DX
+	DIVL y+8(FP)
+	MOVL AX, q+12(FP)
+	MOVL DX, r+16(FP)
+	RET
+
+
+// func div10W(n1, n0 Word) (q, r Word)
+TEXT ·div10W(SB),NOSPLIT,$0
+	MOVL n1+0(FP), DX
+	MOVL n0+4(FP), AX
+	MOVL $_DB, CX
+	DIVL CX
+	MOVL AX, q+8(FP)
+	MOVL DX, r+12(FP)
+	RET
+
+
+// func add10VV(z, x, y []Word) (c Word)
+TEXT ·add10VV(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL y+24(FP), CX
+	MOVL $0, BX		// i = 0
+	MOVL $0, DX		// c = 0
+	JMP E1
+
+L1:	MOVL (SI)(BX*4), AX
+	ADDL (CX)(BX*4), AX
+	ADDL DX, AX		// s = x[i] + y[i] + c < 2*_DB
+	XORL DX, DX
+	LEAL -_DB(AX), BP
+	CMPL AX, $_DB
+	CMOVLCC BP, AX	// if s >= _DB { s -= _DB }
+	SETCC DL		// c = s >= _DB
+	MOVL AX, (DI)(BX*4)
+	ADDL $1, BX		// i++
+
+E1:	CMPL BX, z_len+4(FP)	// i < n
+	JL L1
+
+	MOVL DX, c+36(FP)
+	RET
+
+
+// func sub10VV(z, x, y []Word) (c Word)
+TEXT ·sub10VV(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL y+24(FP), CX
+	MOVL $0, BX		// i = 0
+	MOVL $0, DX		// c = 0
+	JMP E2
+
+L2:	MOVL (CX)(BX*4), BP
+	ADDL DX, BP		// y[i] + c <= _DB
+	MOVL (SI)(BX*4), AX
+	SUBL BP, AX
+	SBBL DX, DX		// DX = -borrow
+	MOVL $_DB, BP
+	ANDL DX, BP
+	ADDL BP, AX		// if borrow { d += _DB }
+	NEGL DX			// c = borrow
+	MOVL AX, (DI)(BX*4)
+	ADDL $1, BX		// i++
+
+E2:	CMPL BX, z_len+4(FP)	// i < n
+	JL L2
+
+	MOVL DX, c+36(FP)
+	RET
+
+
+// func add10VW(z, x []Word, y Word) (c Word)
+TEXT ·add10VW(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL y+24(FP), DX	// c = y
+	MOVL z_len+4(FP), BP
+	MOVL $0, BX		// i = 0
+	JMP E3
+
+L3:	MOVL (SI)(BX*4), AX
+	ADDL DX, AX
+	MOVL $0, DX
+	CMPL AX, $_DB
+	JCS C3			// if s < _DB, c = 0: copy remaining Words
+	SUBL $_DB, AX
+	MOVL $1, DX
+	MOVL AX, (DI)(BX*4)
+	ADDL $1, BX		// i++
+
+E3:	CMPL BX, BP		// i < n
+	JL L3
+
+	MOVL DX, c+28(FP)
+	RET
+
+C3:	MOVL AX, (DI)(BX*4)
+	MOVL $0, c+28(FP)
+	CMPL SI, DI
+	JEQ N3			// don't copy if &x[0] == &z[0]
+	ADDL $1, BX
+	JMP decCpy(SB)
+N3:	RET
+
+
+// func sub10VW(z, x []Word, y Word) (c Word)
+// (same as add10VW except for the borrow handling and label names)
+TEXT ·sub10VW(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL y+24(FP), DX	// c = y
+	MOVL z_len+4(FP), BP
+	MOVL $0, BX		// i = 0
+	JMP E4
+
+L4:	MOVL (SI)(BX*4), AX
+	SUBL DX, AX
+	MOVL $0, DX
+	JCC C4			// if no borrow, c = 0: copy remaining Words
+	ADDL $_DB, AX
+	MOVL $1, DX
+	MOVL AX, (DI)(BX*4)
+	ADDL $1, BX		// i++
+
+E4:	CMPL BX, BP		// i < n
+	JL L4
+
+	MOVL DX, c+28(FP)
+	RET
+
+C4:	MOVL AX, (DI)(BX*4)
+	MOVL $0, c+28(FP)
+	CMPL SI, DI
+	JEQ N4			// don't copy if &x[0] == &z[0]
+	ADDL $1, BX
+	JMP decCpy(SB)
+N4:	RET
+
+
+// func decCpy(dst = DI, src = SI, i = BX, n = BP)
+TEXT decCpy(SB),NOSPLIT,$0
+	SUBL $4, BP		// n -= 4
+	JMP CV
+
+CU:	// i <= n - 4
+	MOVL 0(SI)(BX*4), AX
+	MOVL 4(SI)(BX*4), CX
+	MOVL 8(SI)(BX*4), DX
+	MOVL AX, 0(DI)(BX*4)
+	MOVL CX, 4(DI)(BX*4)
+	MOVL DX, 8(DI)(BX*4)
+	MOVL 12(SI)(BX*4), AX
+	MOVL AX, 12(DI)(BX*4)
+	ADDL $4, BX		// i += 4
+CV:	CMPL BX, BP
+	JLE CU
+
+	ADDL $4, BP
+	JMP CE
+CLoop:
+	MOVL (SI)(BX*4), AX
+	MOVL AX, (DI)(BX*4)
+	ADDL $1, BX
+CE:	CMPL BX, BP
+	JL CLoop
+	RET
+
+
+// func shl10VU(z, x []Word, s uint) (c Word)
+TEXT ·shl10VU(SB),NOSPLIT,$8-32
+	MOVL z_len+4(FP), BX	// i = n
+	SUBL $1, BX				// i--
+	JL X6b					// i < 0 (n <= 0)
+
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL s+24(FP), CX
+	TESTL CX, CX
+	JEQ X6c					// copy if s = 0
+
+	// d = 10**(_DW-s), m = 10**s
+	LEAL ·pow10tab(SB), BP
+	MOVL 0(BP)(CX*8), AX
+	MOVL AX, m-8(SP)
+	NEGL CX
+	MOVL (_DW*8)(BP)(CX*8), CX	// d
+
+	// r, l = x[n-1] / d
+	MOVL (SI)(BX*4), AX
+	MOVL $0, DX
+	DIVL CX
+	MOVL AX, c+28(FP)
+	MOVL DX, BP				// l
+	JMP E6
+
+L6:	// h, l = x[i-1] / d; z[i] = l'*m + h
+	MOVL -4(SI)(BX*4), AX
+	MOVL $0, DX
+	DIVL CX
+	MOVL AX, h-4(SP)
+	MOVL BP, AX
+	MOVL DX, BP
+	MULL m-8(SP)
+	ADDL h-4(SP), AX
+	MOVL AX, (DI)(BX*4)
+	SUBL $1, BX
+
+E6:	TESTL BX, BX
+	JG L6
+
+	MOVL BP, AX
+	MULL m-8(SP)
+	MOVL AX, (DI)
+	RET
+
+X6b:
+	MOVL $0, c+28(FP)
+	RET
+
+X6c:	// copy from high to low addresses
+	MOVL $0, c+28(FP)
+	CMPL SI, DI
+	JEQ X6d
+L6c:
+	MOVL (SI)(BX*4), AX
+	MOVL AX, (DI)(BX*4)
+	SUBL $1, BX
+	JGE L6c
+X6d:
+	RET
+
+
+// func shr10VU(z, x []Word, s uint) (c Word)
+TEXT ·shr10VU(SB),NOSPLIT,$8-32
+	MOVL z_len+4(FP), BP
+	TESTL BP, BP
+	JEQ X7b					// n == 0
+
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL s+24(FP), CX
+	TESTL CX, CX
+	JEQ X7c					// copy if s = 0
+
+	// d = 10**s, m = 10**(_DW-s)
+	LEAL ·pow10tab(SB), BX
+	MOVL 0(BX)(CX*8), AX
+	NEGL CX
+	MOVL (_DW*8)(BX)(CX*8), BX
+	MOVL BX, m-8(SP)
+	MOVL AX, CX				// d
+
+	// h, r = x[0] / d
+	MOVL (SI), AX
+	MOVL $0, DX
+	DIVL CX
+	MOVL AX, h-4(SP)
+	MOVL DX, AX
+	MULL m-8(SP)
+	MOVL AX, c+28(FP)		// r*m
+
+	MOVL $1, BX				// i = 1
+	JMP E7
+
+L7:	// h, l = x[i] / d; z[i-1] = h' + l*m
+	MOVL (SI)(BX*4), AX
+	MOVL $0, DX
+	DIVL CX
+	MOVL h-4(SP), DI
+	MOVL AX, h-4(SP)
+	MOVL DX, AX
+	MULL m-8(SP)
+	ADDL DI, AX
+	MOVL z+0(FP), DI
+	MOVL AX, -4(DI)(BX*4)
+	ADDL $1, BX
+
+E7:	CMPL BX, BP
+	JL L7
+
+	MOVL h-4(SP), AX
+	MOVL AX, -4(DI)(BP*4)
+	RET
+
+X7b:
+	MOVL $0, c+28(FP)
+	RET
+
+X7c:	// copy from low to high addresses
+	MOVL $0, c+28(FP)
+	CMPL SI, DI
+	JEQ X7d
+	MOVL $0, BX
+L7c:
+	MOVL (SI)(BX*4), AX
+	MOVL AX, (DI)(BX*4)
+	ADDL $1, BX
+	CMPL BX, BP
+	JL L7c
+X7d:
+	RET
+
+
+// func mulAdd10VWW(z, x []Word, y, r Word) (c Word)
+TEXT ·mulAdd10VWW(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL r+28(FP), CX	// c = r
+	MOVL $_DB, BP
+	MOVL $0, BX			// i = 0
+	JMP E8
+
+L8:	MOVL (SI)(BX*4), AX
+	MULL y+24(FP)
+	ADDL CX, AX
+	ADCL $0, DX
+	DIVL BP
+	MOVL AX, CX
+	MOVL DX, (DI)(BX*4)
+	ADDL $1, BX			// i++
+
+E8:	CMPL BX, z_len+4(FP)	// i < n
+	JL L8
+
+	MOVL CX, c+32(FP)
+	RET
+
+
+// func addMul10VVW(z, x []Word, y Word) (c Word)
+TEXT ·addMul10VVW(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL $0, CX			// c = 0
+	MOVL $_DB, BP
+	MOVL $0, BX			// i = 0
+	JMP E9
+
+L9:	MOVL (SI)(BX*4), AX
+	MULL y+24(FP)
+	ADDL (DI)(BX*4), AX
+	ADCL $0, DX
+	ADDL CX, AX
+	ADCL $0, DX
+	DIVL BP
+	MOVL AX, CX
+	MOVL DX, (DI)(BX*4)
+	ADDL $1, BX			// i++
+
+E9:	CMPL BX, z_len+4(FP)	// i < n
+	JL L9
+
+	MOVL CX, c+28(FP)
+	RET
+
+
+// func div10VWW(z, x []Word, y, xn Word) (r Word)
+TEXT ·div10VWW(SB),NOSPLIT,$0
+	MOVL z+0(FP), DI
+	MOVL x+12(FP), SI
+	MOVL y+24(FP), CX
+	MOVL xn+28(FP), DX	// r = xn
+	MOVL $_DB, BP
+	MOVL z_len+4(FP), BX	// i = z
+	JMP E10
+
+L10:
+	MOVL DX, AX
+	MULL BP				// DX:AX = r * _DB
+	ADDL (SI)(BX*4), AX
+	ADCL $0, DX
+	DIVL CX
+	MOVL AX, (DI)(BX*4)
+
+E10:
+	SUBL $1, BX			// i--
+	JGE L10				// i >= 0
+
+	MOVL DX, r+32(FP)
+	RET
@@ -0,0 +1,8 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// support_mulx selects the MULX versions of mulAdd10VWW and addMul10VVW.
+var support_mulx = x86HasBMI2
@@ -10,6 +10,42 @@
 #define _DMax 9999999999999999999
 #define _DW 19
 
+// DIV10X computes BX:AX / _DB with MULX, leaving the quotient in CX and the
+// remainder in DX. This is div10W with m' in R13. Trashes R12, R14.
+#define DIV10X \
+	MOVQ AX, CX; \
+	SARQ $63, CX;			/* _n1 */ \
+	MOVQ BX, DX; \
+	SUBQ CX, DX;			/* n1-_n1 */ \
+	MULXQ R13, R14, R12;	/* R12:R14 = m' * (n1-_n1) */ \
+	MOVQ $_DB, DX; \
+	ANDQ DX, CX;			/* d&_n1 */ \
+	ADDQ AX, CX;			/* nAdj */ \
+	ADDQ CX, R14; \
+	ADCQ BX, R12;			/* q1 + n1 + carry */ \
+	NOTQ R12;				/* t */ \
+	MULXQ R12, R14, CX;		/* CX:R14 = t * d */ \
+	ADDQ AX, R14; \
+	ADCQ BX, CX; \
+	SUBQ DX, CX;			/* CX:R14 = dr */ \
+	ANDQ CX, DX; \
+	ADDQ R14, DX;			/* r */ \
+	SUBQ R12, CX			/* q */
+
+// ADD10C adds the carry R11 to the remainder DX from DIV10X and sets R11 to
+// the carry out: q plus the decimal carry of the sum. Since the division does
+// not depend on R11, only this short sequence is on the critical path, and the
+// divisions of consecutive words can overlap. Trashes R12, R14.
+#define ADD10C \
+	MOVQ $_DB, R12; \
+	SUBQ R11, R12;			/* _DB - c */ \
+	SUBQ R12, DX;			/* r + c - _DB, CF set if r + c < _DB */ \
+	SBBQ R12, R12; \
+	MOVQ $_DB, R14; \
+	ANDQ R12, R14; \
+	ADDQ R14, DX;			/* r + c mod _DB */ \
+	LEAQ 1(CX)(R12*1), R11	/* q + (r + c >= _DB) */
+
 // This file provides fast assembly versions for the elementary
 // arithmetic operations on vectors implemented in arith.go.
 
@@ -665,6 +701,8 @@ X9c:
 
 // func mulAdd10VWW(z, x []Word, y, r Word) (c Word)
 TEXT ·mulAdd10VWW(SB),NOSPLIT,$0
+	CMPB ·support_mulx(SB), $1
+	JEQ mulx
 	MOVQ z+0(FP), R10
 	MOVQ x+24(FP), R8
 	MOVQ y+48(FP), R9
@@ -716,9 +754,36 @@ E10:
 	MOVQ R11, c+64(FP)
 	RET
 
+mulx:
+	MOVQ z+0(FP), R10
+	MOVQ x+24(FP), R8
+	MOVQ y+48(FP), R9
+	MOVQ r+56(FP), R11	 // c = r
+	MOVQ z_len+8(FP), DI // n
+	MOVQ $0, SI			 // i = 0
+	MOVQ $0xd83c94fb6d2ac34a, R13
+
+	CMPQ SI, DI
+	JGE E10X
+L10X:
+	MOVQ R9, DX
+	MULXQ 0(R8)(SI*8), AX, BX	// BX:AX = x[i] * y
+	DIV10X
+	ADD10C
+	MOVQ DX, 0(R10)(SI*8)
+
+	ADDQ $1, SI
+	CMPQ SI, DI
+	JL L10X
+E10X:
+	MOVQ R11, c+64(FP)
+	RET
+
 
-// func addMmul10VVW(z, x []Word, y Word) (c Word)
+// func addMul10VVW(z, x []Word, y Word) (c Word)
 TEXT ·addMul10VVW(SB),NOSPLIT,$0
+	CMPB ·support_mulx(SB), $1
+	JEQ mulx
 	MOVQ z+0(FP), R10
 	MOVQ x+24(FP), R8
 	MOVQ y+48(FP), R9
@@ -773,6 +838,33 @@ E11:
 	MOVQ R11, c+56(FP)
 	RET
 
+mulx:
+	MOVQ z+0(FP), R10
+	MOVQ x+24(FP), R8
+	MOVQ y+48(FP), R9
+	MOVQ z_len+8(FP), DI	// n
+	MOVQ $0, SI				// i = 0
+	XORQ R11, R11			// c = 0
+	MOVQ $0xd83c94fb6d2ac34a, R13
+
+	CMPQ SI, DI
+	JGE E11X
+L11X:
+	MOVQ R9, DX
+	MULXQ 0(R8)(SI*8), AX, BX	// BX:AX = x[i] * y
+	ADDQ 0(R10)(SI*8), AX
+	ADCQ $0, BX
+	DIV10X
+	ADD10C
+	MOVQ DX, 0(R10)(SI*8)
+
+	ADDQ $1, SI
+	CMPQ SI, DI
+	JL L11X
+E11X:
+	MOVQ R11, c+56(FP)
+	RET
+
 
 // func div10VWW(z, x []Word, y, xn Word) (r Word)
 TEXT ·div10VWW(SB),NOSPLIT,$0
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !decimal_pure_go,amd64
+// +build !decimal_pure_go,amd64 !decimal_pure_go,386
 
 package decimal
 
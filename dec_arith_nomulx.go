@@ -0,0 +1,10 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !amd64
+
+package decimal
+
+// There are no MULX kernels on this platform.
+var support_mulx = false
@@ -446,6 +446,40 @@ func TestDecFun10VWW(t *testing.T) {
 	}
 }
 
+// TestDecMulAdd10Kernels checks mulAdd10VWW and addMul10VVW against their
+// generic versions, with and without the MULX kernels if they are supported.
+func TestDecMulAdd10Kernels(t *testing.T) {
+	defer func(old bool) { support_mulx = old }(support_mulx)
+	for _, mulx := range []bool{false, support_mulx} {
+		support_mulx = mulx
+		for _, n := range []int{0, 1, 2, 3, 4, 7, 8, 9, 31, 100} {
+			for i := 0; i < 100; i++ {
+				x := rnd10V(n)
+				y, r := rnd10W(), rnd10W()
+				switch i {
+				case 0:
+					for j := range x {
+						x[j] = _DMax
+					}
+					y, r = _DMax, _DMax
+				case 1:
+					y = 0
+				}
+				z, zg := make([]Word, n), make([]Word, n)
+				c, cg := mulAdd10VWW(z, x, y, r), mulAdd10VWW_g(zg, x, y, r)
+				if c != cg || dec(z).cmp(zg) != 0 {
+					t.Fatalf("mulx=%v: mulAdd10VWW(%v, %d, %d) = %v, %d; want %v, %d", mulx, x, y, r, z, c, zg, cg)
+				}
+				copy(zg, z)
+				c, cg = addMul10VVW(z, x, y), addMul10VVW_g(zg, x, y)
+				if c != cg || dec(z).cmp(zg) != 0 {
+					t.Fatalf("mulx=%v: addMul10VVW(%v, %d) = %v, %d; want %v, %d", mulx, x, y, z, c, zg, cg)
+				}
+			}
+		}
+	}
+}
+
 var mul10WWTests = []struct {
 	x, y Word
 	q, r Word
@@ -3,32 +3,44 @@
 // license that can be found in the LICENSE file.
 
 // Calibration used to determine thresholds for using
-// different algorithms.  Ideally, this would be converted
-// to go generate to create thresholds.go
+// different algorithms.
 
-// This file prints execution times for the Mul benchmark
-// given different Karatsuba thresholds. The result may be
-// used to manually fine-tune the threshold constant. The
-// results are somewhat fragile; use repeated runs to get
-// a clear picture.
-
-// Calculates lower and upper thresholds for when basicSqr
-// is faster than standard multiplication.
+// This file measures execution times for the Mul, Sqr, Div and
+// conversion benchmarks given different thresholds and prints
+// the thresholds found. The results are somewhat fragile; use
+// repeated runs to get a clear picture.
 
 // Usage: go test -run=TestDecCalibrate -v -calibrate -cpu 1
 // Forcing a single logical CPU seems to yield more stable
 // benchmarks.
+//
+// With -calibrate.out=file, the thresholds found are also written
+// to file as the default thresholds for the current GOARCH. This
+// is what go generate does (see thresholds.go), which writes
+// thresholds_$GOARCH.go.
 
 package decimal
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"go/format"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
 
 var calibrate = flag.Bool("calibrate", false, "run calibration test")
+var calibrateOut = flag.String("calibrate.out", "", "write calibrated thresholds to `file`")
 
 const (
 	sqrModeMul       = "mul(x, x)"
@@ -41,21 +53,120 @@ func TestDecCalibrate(t *testing.T) {
 		return
 	}
 
-	computeKaratsubaThresholds()
+	// Thresholds are calibrated from the lowest to the highest algorithm:
+	// higher algorithms are disabled until they are calibrated, so that
+	// they do not interfere with the measurements of lower ones, and each
+	// threshold found is used for the next measurements. Thresholds that
+	// cannot be determined are left unchanged. Algorithms that are never
+	// faster in the measured range get a threshold of 1e9, which disables
+	// them.
+	def := SetThresholds(Thresholds{})
+	defer SetThresholds(def)
+	th := def
+	th.Toom3, th.Toom3Sqr, th.NTT, th.NTTSqr, th.DivNewton = 1e9, 1e9, 1e9, 1e9, 1e9
+	SetThresholds(th)
+	found := func(name string, p *int, v int) {
+		if v == 0 {
+			fmt.Printf("no %s found, keeping %d\n", name, *p)
+			return
+		}
+		fmt.Printf("found %s = %d\n", name, v)
+		*p = v
+		SetThresholds(th)
+	}
+
+	found("karatsubaThreshold", &th.Karatsuba, computeKaratsubaThresholds())
 
 	// compute basicSqrThreshold where overhead becomes negligible
-	minSqr := computeSqrThreshold(5, 20, 1, 3, sqrModeMul, sqrModeBasic)
+	found("basicSqrThreshold", &th.BasicSqr, computeSqrThreshold(5, 20, 1, 3, sqrModeMul, sqrModeBasic))
 	// compute karatsubaSqrThreshold where karatsuba is faster
-	maxSqr := computeSqrThreshold(30, 300, 10, 3, sqrModeBasic, sqrModeKaratsuba)
-	if minSqr != 0 {
-		fmt.Printf("found basicSqrThreshold = %d\n", minSqr)
-	} else {
-		fmt.Println("no basicSqrThreshold found")
+	found("karatsubaSqrThreshold", &th.KaratsubaSqr, computeSqrThreshold(30, 300, 10, 3, sqrModeBasic, sqrModeKaratsuba))
+
+	found("convThreshold", &th.Conv, computeConvThreshold())
+
+	found("toom3Threshold", &th.Toom3, computeToom3Threshold(false))
+	found("toom3SqrThreshold", &th.Toom3Sqr, computeToom3Threshold(true))
+
+	found("nttThreshold", &th.NTT, computeNTTThreshold(false))
+	found("nttSqrThreshold", &th.NTTSqr, computeNTTThreshold(true))
+
+	found("divRecursiveThreshold", &th.DivRecursive, computeDivRecursiveThreshold())
+	found("divNewtonThreshold", &th.DivNewton, computeDivNewtonThreshold())
+
+	if *calibrateOut != "" {
+		if err := writeThresholds(*calibrateOut, th); err != nil {
+			t.Fatal(err)
+		}
 	}
-	if maxSqr != 0 {
-		fmt.Printf("found karatsubaSqrThreshold = %d\n", maxSqr)
-	} else {
-		fmt.Println("no karatsubaSqrThreshold found")
+}
+
+// writeThresholds writes th to file as the default thresholds for the current
+// GOARCH, and updates the build constraints of thresholds_generic.go so that
+// it is used only for architectures without a thresholds_$GOARCH.go file.
+func writeThresholds(file string, th Thresholds) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by dec_calibrate_test.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package decimal\n\n")
+	fmt.Fprintf(&b, "// archThresholds holds the default thresholds for %s.\n", runtime.GOARCH)
+	fmt.Fprintf(&b, "var archThresholds = Thresholds{\n")
+	v := reflect.ValueOf(th)
+	for i := 0; i < v.NumField(); i++ {
+		fmt.Fprintf(&b, "%s: %d,\n", v.Type().Field(i).Name, v.Field(i).Int())
+	}
+	fmt.Fprintf(&b, "}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file, src, 0644); err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(file)
+	files, err := filepath.Glob(filepath.Join(dir, "thresholds_*.go"))
+	if err != nil {
+		return err
+	}
+	var tags []string
+	for _, f := range files {
+		arch := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "thresholds_"), ".go")
+		if arch != "generic" && arch != "test" {
+			tags = append(tags, "!"+arch)
+		}
+	}
+	sort.Strings(tags)
+	generic := filepath.Join(dir, "thresholds_generic.go")
+	src, err = ioutil.ReadFile(generic)
+	if err != nil {
+		return err
+	}
+	src = regexp.MustCompile(`(?m)^//go:build .*$`).ReplaceAll(src, []byte("//go:build "+strings.Join(tags, " && ")))
+	src = regexp.MustCompile(`(?m)^// \+build .*$`).ReplaceAll(src, []byte("// +build "+strings.Join(tags, ",")))
+	return ioutil.WriteFile(generic, src, 0644)
+}
+
+func TestWriteThresholds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decimal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	generic := filepath.Join(dir, "thresholds_generic.go")
+	for _, f := range []string{"thresholds_arm.go", generic} {
+		src := "//go:build !386\n// +build !386\n\npackage decimal\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.Base(f)), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeThresholds(filepath.Join(dir, "thresholds_mips.go"), archThresholds); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile(generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "//go:build !arm && !mips\n// +build !arm,!mips\n\npackage decimal\n"; string(src) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", src, want)
 	}
 }
 
@@ -72,7 +183,9 @@ func measureKaratsuba(th int) time.Duration {
 	return time.Duration(res.NsPerOp())
 }
 
-func computeKaratsubaThresholds() {
+// computeKaratsubaThresholds returns the Karatsuba threshold with the best
+// execution time.
+func computeKaratsubaThresholds() int {
 	fmt.Printf("Multiplication times for varying Karatsuba thresholds\n")
 	fmt.Printf("(run repeatedly for good results)\n")
 
@@ -84,9 +197,10 @@ func computeKaratsubaThresholds() {
 	th := 4
 	th1 := -1
 	th2 := -1
+	best, bestT := 0, Tb
 
 	var deltaOld time.Duration
-	for count := -1; count != 0 && th < 128; count-- {
+	for ; th < 128; th++ {
 		// determine Tk, the work load execution time using Karatsuba multiplication
 		Tk := measureKaratsuba(th)
 
@@ -108,15 +222,17 @@ func computeKaratsubaThresholds() {
 		}
 		deltaOld = delta
 
-		fmt.Println()
-
-		// trigger counter
-		if th1 >= 0 && th2 >= 0 && count < 0 {
-			count = 10 // this many extra measurements after we got both thresholds
+		if Tk < bestT {
+			best, bestT = th, Tk
+			fmt.Print("  best")
 		}
 
-		th++
+		fmt.Println()
 	}
+	if best == 0 {
+		return 1e9 // never faster
+	}
+	return best
 }
 
 func measureSqr(words, nruns int, mode string) time.Duration {
@@ -173,3 +289,173 @@ func computeSqrThreshold(from, to, step, nruns int, lower, upper string) int {
 	}
 	return threshold
 }
+
+// measureConv returns the time to convert a 1000 words dec to a big.Word slice
+// and back given conversion threshold th.
+func measureConv(th int) time.Duration {
+	th, decConvThreshold = decConvThreshold, th
+	res := testing.Benchmark(func(b *testing.B) {
+		x := rndDec(1000)
+		var z []big.Word
+		var y dec
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			z = decToNat(z, x)
+			y = y.setNat(z)
+		}
+	})
+	decConvThreshold = th
+	return time.Duration(res.NsPerOp())
+}
+
+func computeConvThreshold() int {
+	fmt.Printf("Conversion times for varying dec <-> big.Word conversion thresholds\n")
+	best, bestT := 0, time.Duration(0)
+	for th := 8; th <= 256; th += 8 {
+		T := measureConv(th)
+		fmt.Printf("th = %3d  T = %10s", th, T)
+		if best == 0 || T < bestT {
+			best, bestT = th, T
+			fmt.Print("  best")
+		}
+		fmt.Println()
+	}
+	return best
+}
+
+// measureToom3 returns the time to multiply (or square if sqr is set) two 1e4
+// words decs given Toom-3 threshold th.
+func measureToom3(th int, sqr bool) time.Duration {
+	p := &decToom3Threshold
+	if sqr {
+		p = &decToom3SqrThreshold
+	}
+	th, *p = *p, th
+	var res testing.BenchmarkResult
+	if sqr {
+		res = testing.Benchmark(func(b *testing.B) { benchmarkDecSqr(b, 1e4) })
+	} else {
+		res = testing.Benchmark(karatsubaLoad)
+	}
+	*p = th
+	return time.Duration(res.NsPerOp())
+}
+
+func computeToom3Threshold(sqr bool) int {
+	name := "multiplication"
+	if sqr {
+		name = "squaring"
+	}
+	fmt.Printf("Toom-3 %s times for varying thresholds\n", name)
+	T0 := measureToom3(1e9, sqr) // th == 1e9 => Toom-3 disabled
+	fmt.Printf("T0 = %10s\n", T0)
+	best, bestT := 0, T0
+	for th := 50; th <= 600; th += 25 {
+		T := measureToom3(th, sqr)
+		fmt.Printf("th = %4d  T = %10s  %4d%%", th, T, (T0-T)*100/T0)
+		if T < bestT {
+			best, bestT = th, T
+			fmt.Print("  best")
+		}
+		fmt.Println()
+	}
+	if best == 0 {
+		return 1e9 // never faster
+	}
+	return best
+}
+
+// measureNTT returns the time to multiply (or square if sqr is set) decs of the
+// given length, with NTT multiplication enabled or not.
+func measureNTT(words int, ntt, sqr bool) time.Duration {
+	p := &decNTTThreshold
+	if sqr {
+		p = &decNTTSqrThreshold
+	}
+	th := int(1e9)
+	if ntt {
+		th = words
+	}
+	th, *p = *p, th
+	var res testing.BenchmarkResult
+	if sqr {
+		res = testing.Benchmark(func(b *testing.B) { benchmarkDecSqr(b, words) })
+	} else {
+		res = testing.Benchmark(func(b *testing.B) { benchmarkDecMul(b, words) })
+	}
+	*p = th
+	return time.Duration(res.NsPerOp())
+}
+
+// computeNTTThreshold returns the smallest operand length for which NTT
+// multiplication is faster than Toom-3, for a geometric progression of lengths,
+// or 1e9 if there is none.
+func computeNTTThreshold(sqr bool) int {
+	name := "multiplication"
+	if sqr {
+		name = "squaring"
+	}
+	fmt.Printf("NTT vs. Toom-3 %s times for varying lengths\n", name)
+	for words := 500; words <= 100000; words = words * 5 / 4 {
+		Tt := measureNTT(words, false, sqr)
+		Tn := measureNTT(words, true, sqr)
+		fmt.Printf("words = %6d  Toom-3 = %10s  NTT = %10s  %4d%%\n", words, Tt, Tn, (Tt-Tn)*100/Tt)
+		if Tn < Tt {
+			return words
+		}
+	}
+	return 1e9 // never faster
+}
+
+// measureDivRecursive returns the time to divide a 2000 words dec by a 1000
+// words dec given recursive division threshold th.
+func measureDivRecursive(th int) time.Duration {
+	th, decDivRecursiveThreshold = decDivRecursiveThreshold, th
+	res := testing.Benchmark(func(b *testing.B) { benchmarkDecDiv(b, 2000, 1000) })
+	decDivRecursiveThreshold = th
+	return time.Duration(res.NsPerOp())
+}
+
+func computeDivRecursiveThreshold() int {
+	fmt.Printf("Division times for varying recursive division thresholds\n")
+	best, bestT := 0, time.Duration(0)
+	for th := 20; th <= 400; th += 20 {
+		T := measureDivRecursive(th)
+		fmt.Printf("th = %3d  T = %10s", th, T)
+		if best == 0 || T < bestT {
+			best, bestT = th, T
+			fmt.Print("  best")
+		}
+		fmt.Println()
+	}
+	return best
+}
+
+// measureDivNewton returns the time to divide a 2×words long dec by a words
+// long dec, using Newton's method or not.
+func measureDivNewton(words int, newton bool) time.Duration {
+	th := int(1e9)
+	if newton {
+		th = words
+	}
+	th, decDivNewtonThreshold = decDivNewtonThreshold, th
+	res := testing.Benchmark(func(b *testing.B) { benchmarkDecDiv(b, 2*words, words) })
+	decDivNewtonThreshold = th
+	return time.Duration(res.NsPerOp())
+}
+
+// computeDivNewtonThreshold returns the smallest divisor length for which
+// division with Newton's method is faster than recursive division, for a
+// geometric progression of lengths, or 1e9 if there is none.
+func computeDivNewtonThreshold() int {
+	fmt.Printf("Newton vs. recursive division times for varying lengths\n")
+	for words := 500; words <= 200000; words = words * 5 / 4 {
+		Tr := measureDivNewton(words, false)
+		Tn := measureDivNewton(words, true)
+		fmt.Printf("words = %6d  recursive = %10s  Newton = %10s  %4d%%\n", words, Tr, Tn, (Tr-Tn)*100/Tr)
+		if Tn < Tr {
+			return words
+		}
+	}
+	return 1e9 // never faster
+}
@@ -12,9 +12,7 @@ import (
 
 func (z dec) scan(r io.ByteScanner, base int, fracOk bool) (res dec, b, count int, err error) {
 	// reject invalid bases
-	baseOk := base == 0 ||
-		!fracOk && 2 <= base && base <= MaxBase ||
-		fracOk && (base == 2 || base == 8 || base == 10 || base == 16)
+	baseOk := base == 0 || 2 <= base && base <= MaxBase
 	if !baseOk {
 		panic(fmt.Sprintf("invalid number base %d", base))
 	}
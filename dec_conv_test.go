@@ -435,3 +435,76 @@ func TestDecStringPowers(t *testing.T) {
 		}
 	}
 }
+
+func TestDecNatConv(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 10, 39, 40, 41, 63, 64, 65, 100, 257, 1000} {
+		x := rndDec(n)
+		want := decToNatBasic(nil, x)
+		got := decToNat(nil, x)
+		if new(big.Int).SetBits(got).Cmp(new(big.Int).SetBits(want)) != 0 {
+			t.Fatalf("decToNat(%d words) mismatch", n)
+		}
+		y := dec(nil).setNat(got)
+		if y.cmp(x) != 0 {
+			t.Fatalf("setNat(decToNat(x)) != x for %d words", n)
+		}
+		if z := dec(nil).setNatBasic(normNat(got)); z.cmp(y) != 0 {
+			t.Fatalf("setNat(%d words) mismatch", n)
+		}
+	}
+	// conversions of powers of two and ten exercise carries across split
+	// points.
+	for _, n := range []uint{64 * 40, 64*64 - 1, 64 * 64, 64*100 + 1} {
+		i := new(big.Int).Lsh(big.NewInt(1), n)
+		i.Sub(i, big.NewInt(1))
+		x := dec(nil).setNat(i.Bits())
+		if s := string(x.utoa(10)); s != i.String() {
+			t.Fatalf("setNat(2**%d-1) = %s; want %s", n, s, i.String())
+		}
+		if got := new(big.Int).SetBits(decToNat(nil, x)); got.Cmp(i) != 0 {
+			t.Fatalf("decToNat(2**%d-1) = %s; want %s", n, got, i)
+		}
+	}
+}
+
+func benchmarkDecToNat(b *testing.B, nwords int) {
+	x := rndDec(nwords)
+	var z []big.Word
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z = decToNat(z, x)
+	}
+}
+
+func benchmarkDecSetNat(b *testing.B, nwords int) {
+	x := decToNat(nil, rndDec(nwords))
+	var z dec
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z = z.setNat(x)
+	}
+}
+
+var decConvBenchSizes = []int{10, 100, 1000, 10000, 100000}
+
+func BenchmarkDecToNat(b *testing.B) {
+	for _, n := range decConvBenchSizes {
+		if isRaceBuilder && n > 1e3 {
+			continue
+		}
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			benchmarkDecToNat(b, n)
+		})
+	}
+}
+
+func BenchmarkDecSetNat(b *testing.B) {
+	for _, n := range decConvBenchSizes {
+		if isRaceBuilder && n > 1e3 {
+			continue
+		}
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			benchmarkDecSetNat(b, n)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// This file implements the division of dec values by multiplication with the
+// reciprocal of the divisor, computed with Newton's method.
+//
+// Like sqrtInverse, the reciprocal is computed with a precision that doubles
+// at each iteration, so that the cost of the whole computation is a small
+// multiple of the cost of the last multiplication. The quotient obtained from
+// the reciprocal may be off by a few units; it is corrected with the
+// remainder, so that the result is exact.
+
+// Reciprocals of divisors shorter than decRecipThreshold are computed by long
+// division. The exact value matters little: the cost of decRecip is dominated
+// by the multiplications of its last Newton steps. It must be at least 4.
+var decRecipThreshold = 100
+
+// decRecip sets z to an approximation of _DB**(2n)/v, where n = len(v) >= 2
+// and v is normalized, and returns z. The result is within a few units of the
+// exact value. z must not alias v. Temporaries are taken from w.
+func decRecip(w *Workspace, z, v dec) dec {
+	n := len(v)
+	if n < decRecipThreshold {
+		// z = _DB**(2n) / v
+		up, rp := w.getDec(2*n+1), w.getDec(0)
+		u := *up
+		u.clear()
+		u[2*n] = 1
+		z, *rp = z.divLarge(w, *rp, u, v)
+		w.putDec(up)
+		w.putDec(rp)
+		return z
+	}
+
+	// Let vh be the l most significant words of v, and rh ≈ _DB**(2l)/vh. l is
+	// one word more than half of n, so that the error of rh stays small even
+	// when the most significant word of v is small.
+	// With s = n-l, r0 = rh×_DB**s is an approximation of r = _DB**(2n)/v
+	// with about l correct words. One Newton step for f(t) = 1/t - v/_DB**(2n)
+	// yields
+	//
+	//   r1 = r0 + r0(_DB**(2n) - v×r0)/_DB**(2n)
+	//      = rh×_DB**s + rh(_DB**(n+l) - v×rh)/_DB**(2l)
+	//
+	// with about 2l correct words.
+	l := n/2 + 1
+	s := n - l
+	rhp := w.getDec(0)
+	rh := decRecip(w, *rhp, v[s:])
+
+	// e = v×rh ≈ _DB**(n+l)
+	ep, tp := w.getDec(n+len(rh)), w.getDec(n+l+1)
+	e := (*ep).mulWith(w, v, rh)
+	t := *tp
+	t.clear()
+	t[n+l] = 1
+	c := e.cmp(t)
+	if c <= 0 {
+		e = e.sub(t, e)
+	} else {
+		e = e.sub(e, t)
+	}
+	// t = rh×|_DB**(n+l) - v×rh| / _DB**(2l). Since rh < _DB**(l+1), the l-1
+	// least significant words of e contribute less than a unit to t and are
+	// ignored.
+	if len(e) > l-1 {
+		t = t.mulWith(w, rh, e[l-1:])
+	} else {
+		t = t[:0]
+	}
+	*ep = e
+	w.putDec(ep)
+	tl := t[:0]
+	if len(t) > l+1 {
+		tl = t[l+1:]
+	}
+
+	z = z.make(len(rh) + s)
+	z[:s].clear()
+	copy(z[s:], rh)
+	if c <= 0 {
+		z = z.add(z, tl)
+	} else {
+		z = z.sub(z, tl)
+	}
+	*rhp, *tp = rh, t
+	w.putDec(rhp)
+	w.putDec(tp)
+	return z
+}
+
+// divNewton computes q = (u-r)/v with 0 <= r < v, using Newton's method to
+// compute the reciprocal of v. It uses z as storage for q, and z2 as storage
+// for r if possible. Temporaries are taken from w.
+//
+// Preconditions:
+//    len(v) >= 2
+//    len(u) >= len(v)
+func (z dec) divNewton(w *Workspace, z2, u, v dec) (q, r dec) {
+	n := len(v)
+	k := len(u) - n + 1 // length of the quotient, give or take a word
+
+	// The reciprocal of v is needed with k+2 words of precision, computed
+	// from the k+2 most significant words of v, or from v padded with zeros
+	// if it is shorter.
+	p := k + 2
+	ut, vt := u, v
+	var utp, vtp *dec
+	if n >= p {
+		ut, vt = u[n-p:], v[n-p:]
+	} else {
+		utp, vtp = w.getDec(len(u)+p-n), w.getDec(p)
+		ut, vt = *utp, *vtp
+		ut[:p-n].clear()
+		copy(ut[p-n:], u)
+		vt[:p-n].clear()
+		copy(vt[p-n:], v)
+	}
+	rvp := w.getDec(0)
+	rv := decRecip(w, *rvp, vt)
+
+	// q ≈ ut × rv / _DB**(2p). Since rv <= _DB**(p+1), only the p+1 most
+	// significant words of ut are needed for an error of less than a unit.
+	sh := 2 * p
+	if d := len(ut) - p - 1; d > 0 {
+		ut = ut[d:]
+		sh -= d
+	}
+	tp := w.getDec(len(ut) + len(rv))
+	t := (*tp).mulWith(w, ut, rv)
+	*rvp = rv
+	w.putDec(rvp)
+	if utp != nil {
+		w.putDec(utp)
+		w.putDec(vtp)
+	}
+	if alias(z, u) || alias(z, v) {
+		z = nil
+	}
+	if len(t) > sh {
+		q = z.set(t[sh:])
+	} else {
+		q = z[:0]
+	}
+
+	// r = u - q×v, adjusting q if needed.
+	t = t.mulWith(w, q, v)
+	for t.cmp(u) > 0 {
+		q = q.sub(q, decOne)
+		t = t.sub(t, v)
+	}
+	if alias(z2, u) || alias(z2, v) || alias(z2, q) {
+		z2 = nil
+	}
+	r = z2.sub(u, t)
+	*tp = t
+	w.putDec(tp)
+	for r.cmp(v) >= 0 {
+		q = q.add(q, decOne)
+		r = r.sub(r, v)
+	}
+	return q, r
+}
@@ -0,0 +1,299 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// This file implements the multiplication of dec values with a number
+// theoretic transform (NTT).
+//
+// The words of the operands are the coefficients of two polynomials whose
+// product, evaluated at _DB, is the product of the operands. The coefficients
+// of the product polynomial are computed exactly by cyclic convolution modulo
+// three primes p of the form c×2**40 + 1, so that NTTs of length up to 2**40
+// are possible, and reconstructed with the Chinese remainder theorem. A
+// coefficient is less than n×_DB**2 < 2**(log2(n)+128) for NTTs of length n,
+// while the product of the primes is larger than 2**188: there is no loss of
+// precision.
+//
+// Arithmetic modulo p uses the Montgomery representation with R = 2**64.
+
+// nttMod holds the constants for arithmetic modulo an NTT prime.
+type nttMod struct {
+	p    uint64 // prime modulus < 2**63
+	pinv uint64 // -1/p mod 2**64
+	r2   uint64 // R**2 mod p
+	g    uint64 // primitive root mod p
+}
+
+var nttMods = [3]nttMod{
+	newNTTMod(0x7ffffe0000000001, 7),
+	newNTTMod(0x7fffef0000000001, 5),
+	newNTTMod(0x7fffe90000000001, 7),
+}
+
+// nttMaxLog is the log2 of the maximum NTT length supported by nttMods.
+const nttMaxLog = 40
+
+func newNTTMod(p, g uint64) nttMod {
+	m := nttMod{p: p, g: g}
+	// Newton iteration for 1/p mod 2**64
+	inv := p
+	for i := 0; i < 6; i++ {
+		inv *= 2 - p*inv
+	}
+	m.pinv = -inv
+	// R mod p, then R**2 mod p by doubling
+	r := (^uint64(0))%p + 1
+	m.r2 = r
+	for i := 0; i < 64; i++ {
+		m.r2 = m.add(m.r2, m.r2)
+	}
+	return m
+}
+
+// mul returns a×b/R mod p. a×b must be less than p×R.
+func (m *nttMod) mul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	q := lo * m.pinv
+	h, l := bits.Mul64(q, m.p)
+	_, c := bits.Add64(lo, l, 0)
+	r := hi + h + c
+	if r >= m.p {
+		r -= m.p
+	}
+	return r
+}
+
+func (m *nttMod) add(a, b uint64) uint64 {
+	r := a + b
+	if r >= m.p {
+		r -= m.p
+	}
+	return r
+}
+
+func (m *nttMod) sub(a, b uint64) uint64 {
+	r := a - b
+	if a < b {
+		r += m.p
+	}
+	return r
+}
+
+// toMont returns a×R mod p for a < 2**64.
+func (m *nttMod) toMont(a uint64) uint64 {
+	return m.mul(a%m.p, m.r2)
+}
+
+// pow returns the Montgomery form of x**e, where x is in Montgomery form.
+func (m *nttMod) pow(x, e uint64) uint64 {
+	r := m.toMont(1)
+	for ; e != 0; e >>= 1 {
+		if e&1 != 0 {
+			r = m.mul(r, x)
+		}
+		x = m.mul(x, x)
+	}
+	return r
+}
+
+// roots sets w to the n/2 first powers of a primitive n-th root of unity (or
+// of its inverse if inv is set) in Montgomery form, and returns w.
+func (m *nttMod) roots(w []uint64, n int, inv bool) []uint64 {
+	e := (m.p - 1) / uint64(n)
+	if inv {
+		e = m.p - 1 - e
+	}
+	r := m.pow(m.toMont(m.g), e)
+	w = append(w[:0], m.toMont(1))
+	for i := 1; i < n/2; i++ {
+		w = append(w, m.mul(w[i-1], r))
+	}
+	return w
+}
+
+// forward computes the NTT of a with the Gentleman-Sande algorithm. The result
+// is in bit-reversed order. w must hold the roots for len(a).
+func (m *nttMod) forward(a, w []uint64) {
+	n := len(a)
+	for h, s := n/2, 1; h >= 1; h, s = h/2, s*2 {
+		for i := 0; i < n; i += 2 * h {
+			x, y := a[i:i+h], a[i+h:i+2*h]
+			for j := range x {
+				u, v := x[j], y[j]
+				x[j] = m.add(u, v)
+				y[j] = m.mul(m.sub(u, v), w[j*s])
+			}
+		}
+	}
+}
+
+// inverse computes the inverse NTT of a, in bit-reversed order, with the
+// Cooley-Tukey algorithm. The result is in natural order and not scaled by
+// 1/len(a). w must hold the inverse roots for len(a).
+func (m *nttMod) inverse(a, w []uint64) {
+	n := len(a)
+	for h, s := 1, n/2; h < n; h, s = h*2, s/2 {
+		for i := 0; i < n; i += 2 * h {
+			x, y := a[i:i+h], a[i+h:i+2*h]
+			for j := range x {
+				u, v := x[j], m.mul(y[j], w[j*s])
+				x[j] = m.add(u, v)
+				y[j] = m.sub(u, v)
+			}
+		}
+	}
+}
+
+// nttBuf holds the buffers used by decNTT.
+type nttBuf struct {
+	a, b, w [3][]uint64
+}
+
+var nttPool sync.Pool
+
+func getNTTBuf() *nttBuf {
+	if v := nttPool.Get(); v != nil {
+		return v.(*nttBuf)
+	}
+	return new(nttBuf)
+}
+
+func putNTTBuf(b *nttBuf) {
+	nttPool.Put(b)
+}
+
+// load sets a to the Montgomery form of the words of x, zero-extended to n
+// words, and returns a.
+func (m *nttMod) load(a []uint64, x dec, n int) []uint64 {
+	if cap(a) < n {
+		a = make([]uint64, n)
+	}
+	a = a[:n]
+	for i, w := range x {
+		a[i] = m.toMont(uint64(w))
+	}
+	for i := len(x); i < n; i++ {
+		a[i] = 0
+	}
+	return a
+}
+
+// decNTT multiplies x and y and leaves the result in z. len(z) must be >=
+// len(x)+len(y). The (non-normalized) result is placed in z[0 :
+// len(x)+len(y)].
+func decNTT(w *Workspace, z, x, y dec) {
+	sqr := len(x) == len(y) && &x[0] == &y[0]
+	lz := len(x) + len(y)
+	lg := bits.Len(uint(lz - 2)) // cyclic convolution length 2**lg >= lz-1
+	if lg > nttMaxLog {
+		panic("decimal: operands too large for NTT multiplication")
+	}
+	n := 1 << lg
+
+	buf := w.getNTTBuf()
+	if decParallel(len(y)) {
+		var tasks decTasks
+		tasks.run(func() { buf.convolve(0, x, y, n, sqr) })
+		tasks.run(func() { buf.convolve(1, x, y, n, sqr) })
+		buf.convolve(2, x, y, n, sqr)
+		tasks.wait()
+	} else {
+		for k := range nttMods {
+			buf.convolve(k, x, y, n, sqr)
+		}
+	}
+
+	nttCRT(z[:lz], buf.a[0][:lz-1], buf.a[1][:lz-1], buf.a[2][:lz-1])
+	w.putNTTBuf(buf)
+}
+
+// convolve sets buf.a[k] to the cyclic convolution of length n of x and y,
+// modulo the k-th NTT prime.
+func (buf *nttBuf) convolve(k int, x, y dec, n int, sqr bool) {
+	m := &nttMods[k]
+	w := buf.w[k]
+	a := m.load(buf.a[k], x, n)
+	w = m.roots(w, n, false)
+	m.forward(a, w)
+	b := a
+	if !sqr {
+		b = m.load(buf.b[k], y, n)
+		m.forward(b, w)
+		buf.b[k] = b
+	}
+	// pointwise product, scaled by 1/n; a[i] is set to the normal form
+	// of the convolution.
+	ninv := m.pow(m.toMont(uint64(n)), m.p-2)
+	for i := range a {
+		a[i] = m.mul(m.mul(a[i], b[i]), ninv)
+	}
+	w = m.roots(w, n, true)
+	m.inverse(a, w)
+	for i := range a {
+		a[i] = m.mul(a[i], 1)
+	}
+	buf.a[k], buf.w[k] = a, w
+}
+
+// CRT constants, in Montgomery form for the modulus they are used with.
+var (
+	nttInv01 = nttMods[1].toMont(nttInverse(nttMods[0].p, &nttMods[1])) // 1/p0 mod p1
+	nttInv02 = nttMods[2].toMont(nttInverse(nttMods[0].p, &nttMods[2])) // 1/p0 mod p2
+	nttInv12 = nttMods[2].toMont(nttInverse(nttMods[1].p, &nttMods[2])) // 1/p1 mod p2
+
+	nttP01hi, nttP01lo = bits.Mul64(nttMods[0].p, nttMods[1].p) // p0×p1
+)
+
+// nttInverse returns 1/x mod m.p in normal form.
+func nttInverse(x uint64, m *nttMod) uint64 {
+	return m.mul(m.pow(m.toMont(x), m.p-2), 1)
+}
+
+// nttCRT sets z to the sum of the coefficients c[i]×_DB**i, where c[i] is
+// given by its residues r0[i], r1[i] and r2[i] modulo the NTT primes, or 0 if
+// i >= len(r0).
+func nttCRT(z dec, r0, r1, r2 []uint64) {
+	m0, m1, m2 := &nttMods[0], &nttMods[1], &nttMods[2]
+	var c0, c1, c2 uint64 // carry, as a 192 bits integer
+	for i := range z {
+		// Garner's algorithm: c = v0 + v1×p0 + v2×p0×p1. The most significant
+		// word of z may hold only a carry.
+		var v0, v1, v2 uint64
+		if i < len(r0) {
+			v0 = r0[i]
+			v1 = m1.mul(m1.sub(r1[i], v0%m1.p), nttInv01)
+			v2 = m2.mul(m2.sub(r2[i], v0%m2.p), nttInv02)
+			v2 = m2.mul(m2.sub(v2, v1%m2.p), nttInv12)
+		}
+
+		// t = v0 + v1×p0 + v2×p0×p1 + carry
+		var t0, t1, t2, c uint64
+		t1, t0 = bits.Mul64(v1, m0.p)
+		t0, c = bits.Add64(t0, v0, 0)
+		t1, t2 = bits.Add64(t1, 0, c)
+		h, l := bits.Mul64(v2, nttP01lo)
+		t0, c = bits.Add64(t0, l, 0)
+		t1, c = bits.Add64(t1, h, c)
+		t2 += c
+		h, l = bits.Mul64(v2, nttP01hi)
+		t1, c = bits.Add64(t1, l, 0)
+		t2 += h + c
+		t0, c = bits.Add64(t0, c0, 0)
+		t1, c = bits.Add64(t1, c1, c)
+		t2 += c2 + c
+
+		// z[i] = t mod _DB, carry = t / _DB
+		var r uint64
+		c2, r = bits.Div64(0, t2, _DB)
+		c1, r = bits.Div64(r, t1, _DB)
+		c0, r = bits.Div64(r, t0, _DB)
+		z[i] = Word(r)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// This file implements the concurrent computation of the independent
+// sub-products of Karatsuba, Toom-3 and NTT multiplication. Divisions benefit
+// from it through the multiplications that they perform.
+
+// Sub-products of operands that are at least decParallelThreshold long are
+// computed concurrently, provided that the concurrency limit set with
+// SetConcurrency allows it.
+var decParallelThreshold = 128
+
+// decWorkers is the maximum number of goroutines started by multiplications
+// and decBusy the number of such goroutines currently running.
+var decWorkers, decBusy int32
+
+// SetConcurrency sets the maximum number of goroutines that a multiplication
+// or division may use, including the calling goroutine, and returns the
+// previous setting. The limit applies to all operations running concurrently:
+// it is the total number of additional goroutines that may be started by
+// the package, plus one. If n < 1, the setting is not changed. The default
+// is 1, which disables concurrency.
+//
+// Results do not depend on this setting.
+func SetConcurrency(n int) int {
+	old := int(atomic.LoadInt32(&decWorkers)) + 1
+	if n > 0 {
+		atomic.StoreInt32(&decWorkers, int32(n-1))
+	}
+	return old
+}
+
+// decParallel reports whether the sub-products of operands of length n should
+// be computed concurrently.
+func decParallel(n int) bool {
+	return n >= decParallelThreshold && atomic.LoadInt32(&decBusy) < atomic.LoadInt32(&decWorkers)
+}
+
+// decTasks runs functions concurrently within the concurrency limit.
+type decTasks struct {
+	wg sync.WaitGroup
+}
+
+// run calls f in a new goroutine if the concurrency limit allows it, or
+// directly otherwise.
+func (t *decTasks) run(f func()) {
+	for {
+		n := atomic.LoadInt32(&decBusy)
+		if n >= atomic.LoadInt32(&decWorkers) {
+			f()
+			return
+		}
+		if atomic.CompareAndSwapInt32(&decBusy, n, n+1) {
+			break
+		}
+	}
+	t.wg.Add(1)
+	go func() {
+		f()
+		atomic.AddInt32(&decBusy, -1)
+		t.wg.Done()
+	}()
+}
+
+// wait waits for all the functions started by run to return.
+func (t *decTasks) wait() {
+	t.wg.Wait()
+}
+
+// decKaratsubaParallel is like decKaratsuba, but computes the products x0*y0,
+// x1*y1 and xd*yd concurrently. Since the recursive calls of decKaratsuba use
+// z as scratch space, x1*y1 and xd*yd are computed in temporary buffers.
+func decKaratsubaParallel(w *Workspace, z, x, y dec) {
+	n := len(y)
+	n2 := n >> 1
+	x1, x0 := x[n2:], x[0:n2]
+	y1, y0 := y[n2:], y[0:n2]
+
+	tp := w.getDec(7 * n)
+	t := *tp
+	z2, p := t[:3*n], t[4*n:]
+
+	s := 1
+	xd := t[3*n : 3*n+n2]
+	if sub10VV(xd, x1, x0) != 0 {
+		s = -s
+		sub10VV(xd, x0, x1)
+	}
+	yd := t[3*n+n2 : 4*n]
+	if sub10VV(yd, y0, y1) != 0 {
+		s = -s
+		sub10VV(yd, y1, y0)
+	}
+
+	var tasks decTasks
+	tasks.run(func() { decKaratsuba(nil, z2, x1, y1) })
+	tasks.run(func() { decKaratsuba(nil, p, xd, yd) })
+	decKaratsuba(w, z, x0, y0)
+	tasks.wait()
+	copy(z[n:2*n], z2)
+
+	r := z[n*4:]
+	copy(r, z[:n*2])
+	decKaratsubaAdd(z[n2:], r, n)
+	decKaratsubaAdd(z[n2:], r[n:], n)
+	if s > 0 {
+		decKaratsubaAdd(z[n2:], p, n)
+	} else {
+		decKaratsubaSub(z[n2:], p, n)
+	}
+	w.putDec(tp)
+}
+
+// decKaratsubaSqrParallel is like decKaratsubaSqr, but computes the squares
+// of x0, x1 and xd concurrently.
+func decKaratsubaSqrParallel(w *Workspace, z, x dec) {
+	n := len(x)
+	n2 := n >> 1
+	x1, x0 := x[n2:], x[0:n2]
+
+	tp := w.getDec(7 * n)
+	t := *tp
+	z2, p := t[:3*n], t[4*n:]
+
+	xd := t[3*n : 3*n+n2]
+	if sub10VV(xd, x1, x0) != 0 {
+		sub10VV(xd, x0, x1)
+	}
+
+	var tasks decTasks
+	tasks.run(func() { decKaratsubaSqr(nil, z2, x1) })
+	tasks.run(func() { decKaratsubaSqr(nil, p, xd) })
+	decKaratsubaSqr(w, z, x0)
+	tasks.wait()
+	copy(z[n:2*n], z2)
+
+	r := z[n*4:]
+	copy(r, z[:n*2])
+	decKaratsubaAdd(z[n2:], r, n)
+	decKaratsubaAdd(z[n2:], r[n:], n)
+	decKaratsubaSub(z[n2:], p, n)
+	w.putDec(tp)
+}
+
+// decIntMuls sets z[i] = x[i]*y[i] for all i, concurrently. Only the product
+// computed by the calling goroutine uses temporaries from w.
+func decIntMuls(w *Workspace, z, x, y []*decInt) {
+	var tasks decTasks
+	last := len(z) - 1
+	for i := 0; i < last; i++ {
+		zi, xi, yi := z[i], x[i], y[i]
+		tasks.run(func() { zi.mul(nil, xi, yi) })
+	}
+	z[last].mul(w, x[last], y[last])
+	tasks.wait()
+}
+
+// decMulChunksParallel is like decMulChunks, but multiplies the chunks of x by
+// y concurrently.
+func decMulChunksParallel(z, x, y dec, mul func(w *Workspace, z, x, y dec)) {
+	m, n := len(x), len(y)
+	p := make([]dec, (m+n-1)/n)
+	var tasks decTasks
+	for i := range p {
+		xi := x[i*n:]
+		if len(xi) > n {
+			xi = xi[:n]
+		}
+		if xi = xi.norm(); len(xi) == 0 {
+			continue
+		}
+		pi := &p[i]
+		f := func() {
+			if len(xi) == n {
+				*pi = dec(nil).make(2 * n)
+				mul(nil, *pi, xi, y)
+				*pi = pi.norm()
+			} else {
+				*pi = dec(nil).mul(xi, y)
+			}
+		}
+		if i == len(p)-1 {
+			f()
+		} else {
+			tasks.run(f)
+		}
+	}
+	tasks.wait()
+	z = z[:m+n]
+	z.clear()
+	for i, pi := range p {
+		decAddAt(z, pi, i*n)
+	}
+}
@@ -192,6 +192,174 @@ func TestDecMulUnbalanced(t *testing.T) {
 	}
 }
 
+// TestDecToom3 checks Toom-3 multiplication and squaring against basic
+// multiplication, with low thresholds to exercise the recursion.
+func TestDecToom3(t *testing.T) {
+	defer func(m, s int) {
+		decToom3Threshold, decToom3SqrThreshold = m, s
+	}(decToom3Threshold, decToom3SqrThreshold)
+	decToom3Threshold, decToom3SqrThreshold = 9, 9
+
+	// operands with all nines and zero pieces
+	nines := func(n int) dec {
+		x := make(dec, n)
+		for i := range x {
+			x[i] = _DMax
+		}
+		return x
+	}
+	holes := func(n int) dec {
+		x := rndDec1(n)
+		for i := n / 3; i < 2*n/3; i++ {
+			x[i] = 0
+		}
+		return x
+	}
+
+	for _, m := range []int{9, 10, 11, 12, 30, 31, 100, 333, 1000} {
+		for _, n := range []int{9, 10, 17, m / 2, m - 1, m} {
+			if n < 9 || n > m {
+				continue
+			}
+			for _, xy := range [][2]dec{
+				{rndDec1(m), rndDec1(n)},
+				{nines(m), nines(n)},
+				{holes(m), holes(n)},
+				{nines(m), holes(n)},
+			} {
+				x, y := xy[0], xy[1]
+				want := make(dec, len(x)+len(y))
+				decBasicMul(want, x, y)
+				want = want.norm()
+				if got := dec(nil).mul(x, y); got.cmp(want) != 0 {
+					t.Fatalf("mul(%d words, %d words): got %v; want %v", len(x), len(y), got, want)
+				}
+				want = want.make(2 * len(x))
+				decBasicMul(want, x, x)
+				want = want.norm()
+				if got := dec(nil).sqr(x); got.cmp(want) != 0 {
+					t.Fatalf("sqr(%d words): got %v; want %v", len(x), got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestDecMulUnbalancedToom3 is like TestDecMulUnbalanced with operands long
+// enough to use Toom-3 multiplication.
+func TestDecMulUnbalancedToom3(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	x := rndDec(50000)
+	y := rndDec(decToom3Threshold + 10)
+	z := dec(nil).mul(x, y)
+	want := make(dec, len(x)+len(y))
+	decBasicMul(want, x, y)
+	if z.cmp(want.norm()) != 0 {
+		t.Fatalf("mul(%d words, %d words): wrong result", len(x), len(y))
+	}
+	allocSize := decAllocBytes(func() {
+		dec(nil).mul(x, y)
+	})
+	inputSize := uint64(len(x)+len(y)) * _S
+	if ratio := allocSize / uint64(inputSize); ratio > 10 {
+		t.Errorf("multiplication uses too much memory (%d > %d times the size of inputs)", allocSize, ratio)
+	}
+}
+
+func TestDecNTT(t *testing.T) {
+	defer func(m, s, tm, ts int) {
+		decNTTThreshold, decNTTSqrThreshold = m, s
+		decToom3Threshold, decToom3SqrThreshold = tm, ts
+	}(decNTTThreshold, decNTTSqrThreshold, decToom3Threshold, decToom3SqrThreshold)
+
+	nines := func(n int) dec {
+		x := make(dec, n)
+		for i := range x {
+			x[i] = _DMax
+		}
+		return x
+	}
+
+	// Karatsuba results
+	karatsuba := func(x, y dec) (dec, dec) {
+		decNTTThreshold, decNTTSqrThreshold = 1e9, 1e9
+		decToom3Threshold, decToom3SqrThreshold = 1e9, 1e9
+		return dec(nil).mul(x, y), dec(nil).sqr(x)
+	}
+
+	for _, m := range []int{1, 2, 3, 20, 64, 65, 129, 1000, 3000} {
+		for _, n := range []int{1, 2, 20, m / 3, m - 1, m} {
+			if n < 1 || n > m {
+				continue
+			}
+			for _, xy := range [][2]dec{
+				{rndDec1(m), rndDec1(n)},
+				{nines(m), nines(n)},
+				{nines(m), rndDec1(n)},
+			} {
+				x, y := xy[0], xy[1]
+				wantMul, wantSqr := karatsuba(x, y)
+
+				got := make(dec, len(x)+len(y)+1)
+				decNTT(nil, got, x, y)
+				if got = got.norm(); got.cmp(wantMul) != 0 {
+					t.Fatalf("decNTT(%d words, %d words): wrong result", len(x), len(y))
+				}
+
+				decNTTThreshold, decNTTSqrThreshold = 2, 2
+				decToom3Threshold, decToom3SqrThreshold = 9, 9
+				if got := dec(nil).mul(x, y); got.cmp(wantMul) != 0 {
+					t.Fatalf("mul(%d words, %d words): wrong result", len(x), len(y))
+				}
+				if got := dec(nil).sqr(x); got.cmp(wantSqr) != 0 {
+					t.Fatalf("sqr(%d words): wrong result", len(x))
+				}
+			}
+		}
+	}
+}
+
+// TestDecMulParallel checks that concurrent multiplication, squaring and
+// division give the same results as the sequential versions, for all
+// multiplication algorithms.
+func TestDecMulParallel(t *testing.T) {
+	defer func(m, s, tm, ts, p int) {
+		decNTTThreshold, decNTTSqrThreshold = m, s
+		decToom3Threshold, decToom3SqrThreshold = tm, ts
+		decParallelThreshold = p
+	}(decNTTThreshold, decNTTSqrThreshold, decToom3Threshold, decToom3SqrThreshold, decParallelThreshold)
+	defer SetConcurrency(SetConcurrency(1))
+	decParallelThreshold = 4
+
+	for _, th := range [][2]int{
+		{1e9, 1e9}, // Karatsuba
+		{9, 1e9},   // Toom-3
+		{9, 2},     // NTT
+	} {
+		decToom3Threshold, decToom3SqrThreshold = th[0], th[0]
+		decNTTThreshold, decNTTSqrThreshold = th[1], th[1]
+		for _, m := range []int{64, 129, 1000, 3000} {
+			for _, n := range []int{17, m / 3, m - 1, m} {
+				x, y := rndDec1(m), rndDec1(n)
+				SetConcurrency(1)
+				wantMul, wantSqr := dec(nil).mul(x, y), dec(nil).sqr(x)
+				u := dec(nil).add(wantMul, y[1:])
+				wantQ, wantR := dec(nil).div(nil, u, y)
+				SetConcurrency(8)
+				if got := dec(nil).mul(x, y); got.cmp(wantMul) != 0 {
+					t.Fatalf("mul(%d words, %d words): wrong result", len(x), len(y))
+				}
+				if got := dec(nil).sqr(x); got.cmp(wantSqr) != 0 {
+					t.Fatalf("sqr(%d words): wrong result", len(x))
+				}
+				if q, r := dec(nil).div(nil, u, y); q.cmp(wantQ) != 0 || r.cmp(wantR) != 0 {
+					t.Fatalf("div(%d words, %d words): wrong result", len(u), len(y))
+				}
+			}
+		}
+	}
+}
+
 // rndDec returns a random dec value >= 0 of (usually) n words in length.
 // In extremely unlikely cases it may be smaller than n words if the top-
 // most words are 0.
@@ -241,6 +409,20 @@ func BenchmarkDecMul(b *testing.B) {
 	}
 }
 
+// BenchmarkDecMulParallel is like BenchmarkDecMul with concurrency enabled
+// for all CPUs.
+func BenchmarkDecMulParallel(b *testing.B) {
+	defer SetConcurrency(SetConcurrency(runtime.GOMAXPROCS(0)))
+	for _, n := range decMulBenchSizes {
+		if isRaceBuilder && n > 1e3 {
+			continue
+		}
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			benchmarkDecMul(b, n)
+		})
+	}
+}
+
 func TestDecNLZ10(t *testing.T) {
 	var x Word = _DMax
 	for i := 0; i <= _DW; i++ {
@@ -726,6 +908,78 @@ func TestDecDiv(t *testing.T) {
 	}
 }
 
+func TestDecDivNewton(t *testing.T) {
+	defer func(th, rth int) { decDivNewtonThreshold, decRecipThreshold = th, rth }(decDivNewtonThreshold, decRecipThreshold)
+	decRecipThreshold = 4
+
+	nines := func(n int) dec {
+		x := make(dec, n)
+		for i := range x {
+			x[i] = _DMax
+		}
+		return x
+	}
+	pow := func(n int) dec {
+		x := make(dec, n)
+		x[n-1] = 1
+		return x
+	}
+
+	sizes := []int{2, 3, 7, 20, 64, 65, 200, 1000}
+	for _, i := range sizes {
+		for _, j := range sizes {
+			for _, ab := range [][2]dec{
+				{rndDec1(i), rndDec1(j)},
+				{nines(i), nines(j)},
+				{nines(i), pow(j)},
+				{pow(i), nines(j)},
+			} {
+				a, b := ab[0], ab[1]
+				for _, c := range []dec{nil, decOne, dec(nil).sub(b, decOne), rndDec(len(b) - 1)} {
+					// x = a*b+c
+					x := dec(nil).mul(a, b)
+					x = x.add(x, c)
+
+					decDivNewtonThreshold = 2
+					q, r := dec(nil).div(nil, x, b)
+					if q.cmp(a) != 0 || r.cmp(c) != 0 {
+						t.Fatalf("%d words / %d words: wrong quotient or remainder", len(x), len(b))
+					}
+					// decRecip within a few units
+					decDivNewtonThreshold = 1e9
+					want := dec(nil).make(2*len(b) + 1)
+					want.clear()
+					want[2*len(b)] = 1
+					want, _ = want.div(nil, want, b)
+					decDivNewtonThreshold = 2
+					got := decRecip(nil, nil, b)
+					if got.cmp(want) < 0 {
+						got, want = want, got
+					}
+					if d := got.sub(got, want); d.cmp(dec{100}) > 0 {
+						t.Fatalf("decRecip(%d words): error too large: %v", len(b), d)
+					}
+				}
+			}
+		}
+	}
+
+	// aliasing
+	decDivNewtonThreshold = 2
+	a, b := rndDec1(300), rndDec1(200)
+	x := dec(nil).mul(a, b)
+	x = x.add(x, decOne)
+	for _, f := range []func() (dec, dec){
+		func() (dec, dec) { y := x.set(x); return y.div(nil, y, b) },
+		func() (dec, dec) { y := dec(nil).set(b); return y.div(nil, x, y) },
+		func() (dec, dec) { y := x.set(x); return dec(nil).div(y, y, b) },
+	} {
+		if q, r := f(); q.cmp(a) != 0 || r.cmp(decOne) != 0 {
+			t.Fatal("wrong result with aliased arguments")
+		}
+	}
+}
+
 // TestGoIssue37499 triggers the edge case of divBasic where the inaccurate
 // estimate of the first word's quotient happens at the very beginning of the
 // loop. See https://github.com/golang/go/issues/37499
@@ -737,7 +991,7 @@ func TestGoIssue37499(t *testing.T) {
 	v := decFromString("923456789012345678912345678901234567891234567890123456790")
 
 	q := dec(nil).make(len(u) - len(v) + 1)
-	q.divBasic(u, v)
+	q.divBasic(nil, u, v)
 	q.norm()
 
 	if s := string(q.utoa(10)); s != "999999999999999999999999999999999999999999999999999999999" {
@@ -0,0 +1,212 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import "sync"
+
+// This file implements Toom-Cook 3-way multiplication (Toom-3) of dec values.
+
+// decInt is a signed dec used for the intermediate values of Toom-Cook
+// multiplication.
+type decInt struct {
+	abs dec
+	neg bool
+}
+
+// addSub sets z = x + y if sub is false, or x - y otherwise. z may alias x or
+// y.
+func (z *decInt) addSub(x, y *decInt, sub bool) {
+	yneg := y.neg != sub
+	if x.neg == yneg {
+		z.neg = x.neg
+		z.abs = z.abs.add(x.abs, y.abs)
+		return
+	}
+	if x.abs.cmp(y.abs) >= 0 {
+		z.neg = x.neg
+		z.abs = z.abs.sub(x.abs, y.abs)
+	} else {
+		z.neg = yneg
+		z.abs = z.abs.sub(y.abs, x.abs)
+	}
+	if len(z.abs) == 0 {
+		z.neg = false
+	}
+}
+
+// mul sets z = x*y, using temporaries from w. z must not alias x or y.
+func (z *decInt) mul(w *Workspace, x, y *decInt) {
+	if x == y {
+		z.abs = z.abs.sqrWith(w, x.abs)
+		z.neg = false
+		return
+	}
+	z.abs = z.abs.mulWith(w, x.abs, y.abs)
+	z.neg = len(z.abs) > 0 && x.neg != y.neg
+}
+
+// divW sets z = x/d. x must be a multiple of d.
+func (z *decInt) divW(x *decInt, d Word) {
+	z.abs, _ = z.abs.divW(x.abs, d)
+	z.neg = x.neg
+}
+
+// decToom3Buf holds the temporaries of decToom3.
+type decToom3Buf struct {
+	x, y           [3]decInt // operand pieces
+	p1, pm1, pm2   decInt    // x(1), x(-1), x(-2)
+	q1, qm1, qm2   decInt    // y(1), y(-1), y(-2)
+	r0, r1, rm1    decInt    // products
+	rm2, rinf, tmp decInt
+}
+
+var decToom3Pool sync.Pool
+
+func getDecToom3Buf() *decToom3Buf {
+	if v := decToom3Pool.Get(); v != nil {
+		return v.(*decToom3Buf)
+	}
+	return new(decToom3Buf)
+}
+
+func putDecToom3Buf(b *decToom3Buf) {
+	// do not keep references to the operands
+	b.x, b.y = [3]decInt{}, [3]decInt{}
+	decToom3Pool.Put(b)
+}
+
+// toom3Split sets the pieces p of x such that x = p[2]*b^2 + p[1]*b + p[0] with
+// b = _DB**k.
+func toom3Split(p *[3]decInt, x dec, k int) {
+	for i := range p {
+		lo, hi := i*k, (i+1)*k
+		if i == 2 || hi > len(x) {
+			hi = len(x)
+		}
+		if lo > len(x) {
+			lo = len(x)
+		}
+		p[i].abs = x[lo:hi].norm()
+		p[i].neg = false
+	}
+}
+
+// toom3Eval sets p1, pm1, pm2 to the values of the polynomial
+// x[2]*t^2 + x[1]*t + x[0] at t = 1, -1 and -2.
+func toom3Eval(p1, pm1, pm2 *decInt, x *[3]decInt) {
+	p1.addSub(&x[0], &x[2], false) // x0 + x2
+	pm1.addSub(p1, &x[1], true)    // x(-1) = x0 - x1 + x2
+	p1.addSub(p1, &x[1], false)    // x(1) = x0 + x1 + x2
+	pm2.addSub(pm1, &x[2], false)  // x0 - x1 + 2*x2
+	pm2.abs = pm2.abs.mulAddWW(pm2.abs, 2, 0)
+	pm2.addSub(pm2, &x[0], true) // x(-2) = x0 - 2*x1 + 4*x2
+}
+
+// decToom3 multiplies x and y and leaves the result in z. The operands should
+// have about the same length. len(z) must be >= len(x)+len(y). The
+// (non-normalized) result is placed in z[0 : len(x)+len(y)].
+//
+// The evaluation points are 0, 1, -1, -2 and ∞, with the interpolation
+// sequence of M. Bodrato and A. Zanoni, "Integer and Polynomial Multiplication:
+// Towards Optimal Toom-Cook Matrices".
+func decToom3(w *Workspace, z, x, y dec) {
+	sqr := len(x) == len(y) && &x[0] == &y[0]
+	k := (max(len(x), len(y)) + 2) / 3
+
+	b := w.getDecToom3Buf()
+	toom3Split(&b.x, x, k)
+	toom3Eval(&b.p1, &b.pm1, &b.pm2, &b.x)
+	q1, qm1, qm2, y0, y2 := &b.p1, &b.pm1, &b.pm2, &b.x[0], &b.x[2]
+	if !sqr {
+		toom3Split(&b.y, y, k)
+		toom3Eval(&b.q1, &b.qm1, &b.qm2, &b.y)
+		q1, qm1, qm2, y0, y2 = &b.q1, &b.qm1, &b.qm2, &b.y[0], &b.y[2]
+	}
+
+	// pointwise products
+	r0, r1, r2, r3, r4 := &b.r0, &b.r1, &b.rm1, &b.rm2, &b.rinf
+	if decParallel(k) {
+		decIntMuls(w,
+			[]*decInt{r0, r1, r2, r3, r4},
+			[]*decInt{&b.x[0], &b.p1, &b.pm1, &b.pm2, &b.x[2]},
+			[]*decInt{y0, q1, qm1, qm2, y2})
+	} else {
+		r0.mul(w, &b.x[0], y0) // r(0)
+		r1.mul(w, &b.p1, q1)   // r(1)
+		r2.mul(w, &b.pm1, qm1) // r(-1)
+		r3.mul(w, &b.pm2, qm2) // r(-2)
+		r4.mul(w, &b.x[2], y2) // r(∞)
+	}
+
+	// interpolation
+	r3.addSub(r3, r1, true) // r3 = (r(-2) - r(1))/3
+	r3.divW(r3, 3)
+	r1.addSub(r1, r2, true) // r1 = (r(1) - r(-1))/2
+	r1.divW(r1, 2)
+	r2.addSub(r2, r0, true) // r2 = r(-1) - r(0)
+	r3.addSub(r2, r3, true) // r3 = (r2 - r3)/2 + 2*r(∞)
+	r3.divW(r3, 2)
+	t := &b.tmp
+	t.abs = t.abs.mulAddWW(r4.abs, 2, 0)
+	t.neg = false
+	r3.addSub(r3, t, false)
+	r2.addSub(r2, r1, false) // r2 = r2 + r1 - r(∞)
+	r2.addSub(r2, r4, true)
+	r1.addSub(r1, r3, true) // r1 = r1 - r3
+
+	if debugDecimal && (r1.neg || r2.neg || r3.neg) {
+		panic("toom3: negative coefficient")
+	}
+
+	// recomposition
+	z = z[:len(x)+len(y)]
+	z.clear()
+	for i, r := range [...]*decInt{r0, r1, r2, r3, r4} {
+		decAddAt(z, r.abs, i*k)
+	}
+	w.putDecToom3Buf(b)
+}
+
+// decMulChunks multiplies x and y with m = len(x) >= n = len(y), and leaves the
+// result in z. len(z) must be >= m+n. The (non-normalized) result is placed in
+// z[0 : m+n]. x is split into chunks of length n, which are multiplied by y
+// with mul. Chunks that are shorter than n after normalization are multiplied
+// with dec.mul.
+//
+// mul must have the same signature and semantics as decToom3.
+func decMulChunks(w *Workspace, z, x, y dec, mul func(w *Workspace, z, x, y dec)) {
+	m, n := len(x), len(y)
+	if m == n {
+		mul(w, z, x, y)
+		return
+	}
+	if decParallel(n) {
+		decMulChunksParallel(z, x, y, mul)
+		return
+	}
+	z = z[:m+n]
+	z.clear()
+	tp := w.getDec(2 * n)
+	t := *tp
+	for i := 0; i < m; i += n {
+		xi := x[i:]
+		if len(xi) > n {
+			xi = xi[:n]
+		}
+		if xi = xi.norm(); len(xi) == 0 {
+			continue
+		}
+		if len(xi) == n {
+			t = t.make(2 * n)
+			mul(w, t, xi, y)
+			t = t.norm()
+		} else {
+			t = t.mulWith(w, xi, y)
+		}
+		decAddAt(z, t, i)
+	}
+	*tp = t
+	w.putDec(tp)
+}
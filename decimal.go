@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"math/rand"
 )
 
 const debugDecimal = false // enable for debugging
@@ -65,7 +66,9 @@ const DefaultDecimalPrec = 34
 // values, and each unique Decimal value requires its own unique *Decimal
 // pointer. To "copy" a Decimal value, an existing (or newly allocated) Decimal
 // must be set to a new value using the Decimal.Set method; shallow copies of
-// Decimals are not supported and may lead to errors.
+// Decimals are not supported and may lead to errors. In particular, after
+// y := *x, y and x share the same mantissa: setting one of them may change the
+// value of the other.
 type Decimal struct {
 	mant dec
 	exp  int32
@@ -76,6 +79,17 @@ type Decimal struct {
 	neg  bool
 }
 
+// setMant2 sets z.mant to the normalized two Words value hi×_DB + lo.
+func (z *Decimal) setMant2(hi, lo Word) {
+	if hi == 0 {
+		z.mant = z.mant.setWord(lo)
+		return
+	}
+	z.mant = z.mant.make(2)
+	z.mant[1], z.mant[0] = hi, lo
+	z.mant = z.mant.norm()
+}
+
 // NewDecimal allocates and returns a new Decimal set to x×10**exp, with
 // precision DefaultDecimalPrec and rounding mode ToNearestEven.
 //
@@ -128,6 +142,11 @@ func (x *Decimal) Acc() Accuracy {
 // result. Add panics with ErrNaN if x and y are infinities with opposite
 // signs. The value of z is undefined in that case.
 func (z *Decimal) Add(x, y *Decimal) *Decimal {
+	return z.add(nil, x, y)
+}
+
+// add is like Add, but takes its temporaries from w.
+func (z *Decimal) add(w *Workspace, x, y *Decimal) *Decimal {
 	if debugDecimal {
 		x.validate()
 		y.validate()
@@ -151,15 +170,15 @@ func (z *Decimal) Add(x, y *Decimal) *Decimal {
 		if x.neg == yneg {
 			// x + y == x + y
 			// (-x) + (-y) == -(x + y)
-			z.uadd(x, y)
+			z.uadd(w, x, y)
 		} else {
 			// x + (-y) == x - y == -(y - x)
 			// (-x) + y == y - x == -(x - y)
 			if x.ucmp(y) > 0 {
-				z.usub(x, y)
+				z.usub(w, x, y)
 			} else {
 				z.neg = !z.neg
-				z.usub(y, x)
+				z.usub(w, y, x)
 			}
 		}
 		if z.form == zero && z.mode == ToNegativeInf && z.acc == Exact {
@@ -200,7 +219,7 @@ func (z *Decimal) Add(x, y *Decimal) *Decimal {
 // z = x + y, ignoring signs of x and y for the addition
 // but using the sign of z for rounding the result.
 // x and y must have a non-empty mantissa and valid exponent.
-func (z *Decimal) uadd(x, y *Decimal) {
+func (z *Decimal) uadd(w *Workspace, x, y *Decimal) {
 	// Note: This implementation requires 2 shifts most of the
 	// time. It is also inefficient if exponents or precisions
 	// differ by wide margins. The following article describes
@@ -220,6 +239,22 @@ func (z *Decimal) uadd(x, y *Decimal) {
 	ex := int64(x.exp) - int64(len(x.mant))*_DW
 	ey := int64(y.exp) - int64(len(y.mant))*_DW
 
+	if len(x.mant) == 1 && len(y.mant) == 1 && ex-ey < _DW && ey-ex < _DW {
+		// single Word mantissae, the result fits in two Words.
+		xm, ym := x.mant[0], y.mant[0]
+		if ex < ey {
+			xm, ym = ym, xm
+			ex, ey = ey, ex
+		}
+		// x.mant has the largest exponent
+		hi, lo := mul10WW(xm, pow10(uint(ex-ey)))
+		var c Word
+		lo, c = add10WWW_g(lo, ym, 0)
+		z.setMant2(hi+c, lo)
+		z.setExpAndRound(ey+int64(len(z.mant))*_DW-dnorm(z.mant), 0)
+		return
+	}
+
 	// TODO(db47h) having a combined add-and-shift primitive
 	//             could make this code significantly faster
 	//             but this needs a version of shl that starts
@@ -228,8 +263,10 @@ func (z *Decimal) uadd(x, y *Decimal) {
 	switch {
 	case ex < ey:
 		if same(z.mant, x.mant) {
-			t := dec(nil).shl(y.mant, uint(ey-ex))
-			z.mant = z.mant.add(x.mant, t)
+			tp := w.getDec(0)
+			*tp = tp.shl(y.mant, uint(ey-ex))
+			z.mant = z.mant.add(x.mant, *tp)
+			w.putDec(tp)
 		} else {
 			z.mant = z.mant.shl(y.mant, uint(ey-ex))
 			z.mant = z.mant.add(x.mant, z.mant)
@@ -239,8 +276,10 @@ func (z *Decimal) uadd(x, y *Decimal) {
 		z.mant = z.mant.add(x.mant, y.mant)
 	case ex > ey:
 		if same(z.mant, y.mant) {
-			t := dec(nil).shl(x.mant, uint(ex-ey))
-			z.mant = z.mant.add(t, y.mant)
+			tp := w.getDec(0)
+			*tp = tp.shl(x.mant, uint(ex-ey))
+			z.mant = z.mant.add(*tp, y.mant)
+			w.putDec(tp)
 		} else {
 			z.mant = z.mant.shl(x.mant, uint(ex-ey))
 			z.mant = z.mant.add(z.mant, y.mant)
@@ -255,7 +294,7 @@ func (z *Decimal) uadd(x, y *Decimal) {
 // z = x - y for |x| > |y|, ignoring signs of x and y for the subtraction
 // but using the sign of z for rounding the result.
 // x and y must have a non-empty mantissa and valid exponent.
-func (z *Decimal) usub(x, y *Decimal) {
+func (z *Decimal) usub(w *Workspace, x, y *Decimal) {
 	// This code is symmetric to uadd.
 	// We have not factored the common code out because
 	// eventually uadd (and usub) should be optimized
@@ -268,11 +307,39 @@ func (z *Decimal) usub(x, y *Decimal) {
 	ex := int64(x.exp) - int64(len(x.mant))*_DW
 	ey := int64(y.exp) - int64(len(y.mant))*_DW
 
+	if len(x.mant) == 1 && len(y.mant) == 1 && ex-ey < _DW && ey-ex < _DW {
+		// single Word mantissae, |x| > |y| so that the result is positive
+		// and fits in two Words.
+		var x1, x0, y1, y0 Word
+		if ex < ey {
+			x0 = x.mant[0]
+			y1, y0 = mul10WW(y.mant[0], pow10(uint(ey-ex)))
+		} else {
+			x1, x0 = mul10WW(x.mant[0], pow10(uint(ex-ey)))
+			y0 = y.mant[0]
+			ex = ey
+		}
+		var c Word
+		x0, c = sub10WWW_g(x0, y0, 0)
+		x1, _ = sub10WWW_g(x1, y1, c)
+		z.setMant2(x1, x0)
+		if len(z.mant) == 0 {
+			z.acc = Exact
+			z.form = zero
+			z.neg = false
+			return
+		}
+		z.setExpAndRound(ex+int64(len(z.mant))*_DW-dnorm(z.mant), 0)
+		return
+	}
+
 	switch {
 	case ex < ey:
 		if same(z.mant, x.mant) {
-			t := dec(nil).shl(y.mant, uint(ey-ex))
-			z.mant = t.sub(x.mant, t)
+			tp := w.getDec(0)
+			*tp = tp.shl(y.mant, uint(ey-ex))
+			z.mant = z.mant.sub(x.mant, *tp)
+			w.putDec(tp)
 		} else {
 			z.mant = z.mant.shl(y.mant, uint(ey-ex))
 			z.mant = z.mant.sub(x.mant, z.mant)
@@ -282,8 +349,10 @@ func (z *Decimal) usub(x, y *Decimal) {
 		z.mant = z.mant.sub(x.mant, y.mant)
 	case ex > ey:
 		if same(z.mant, y.mant) {
-			t := dec(nil).shl(x.mant, uint(ex-ey))
-			z.mant = t.sub(t, y.mant)
+			tp := w.getDec(0)
+			*tp = tp.shl(x.mant, uint(ex-ey))
+			z.mant = z.mant.sub(*tp, y.mant)
+			w.putDec(tp)
 		} else {
 			z.mant = z.mant.shl(x.mant, uint(ex-ey))
 			z.mant = z.mant.sub(z.mant, y.mant)
@@ -376,6 +445,16 @@ func (x *Decimal) ucmp(y *Decimal) int {
 	}
 	// x.exp == y.exp
 
+	if len(x.mant) == 1 && len(y.mant) == 1 {
+		switch xm, ym := x.mant[0], y.mant[0]; {
+		case xm < ym:
+			return -1
+		case xm > ym:
+			return +1
+		}
+		return 0
+	}
+
 	// compare mantissas
 	i := len(x.mant)
 	j := len(y.mant)
@@ -421,64 +500,55 @@ func (z *Decimal) Copy(x *Decimal) *Decimal {
 	return z
 }
 
+// CopySign sets z to the (possibly rounded) value of x with the sign of y, and
+// returns z. Precision, rounding, and accuracy reporting are as for Set if x
+// and y have the same sign, and as for Neg otherwise: the rounding applies to
+// the result.
+func (z *Decimal) CopySign(x, y *Decimal) *Decimal {
+	if x.neg == y.neg {
+		return z.Set(x)
+	}
+	return z.Neg(x)
+}
+
+// Digits returns the number of significant digits of x: the number of digits
+// of the integer c such that x = c × 10**q for some integer q, with c not a
+// multiple of 10. It is equivalent to MinPrec. The result is 0 for |x| == 0 and
+// |x| == Inf.
+func (x *Decimal) Digits() uint {
+	return x.MinPrec()
+}
+
 // Float sets z to the (possibly rounded) value of x. If a non-nil *big.Float
 // argument z is provided, Float stores the result in z instead of allocating a
-// new big.Float.
+// new big.Float with x's rounding mode, or ToNearestEven if big.Float has no
+// equivalent rounding mode.
 // If z's precision is 0, it is changed to max(⌈x.Prec() * log2(10)⌉, 64).
+// The result is correctly rounded according to z's precision and rounding
+// mode, and z's accuracy reports the result error relative to x.
 func (x *Decimal) Float(z *big.Float) *big.Float {
 	if z == nil {
-		z = new(big.Float).SetMode(big.RoundingMode(x.mode))
+		z = new(big.Float)
+		if x.mode <= ToPositiveInf {
+			z.SetMode(big.RoundingMode(x.mode))
+		}
 	}
-	p := uint(z.Prec())
-	if p == 0 {
-		p = uint(max(int(math.Ceil(float64(x.prec)*log2_10)), 64))
+	if z.Prec() == 0 {
+		z.SetPrec(uint(max(int(math.Ceil(float64(x.prec)*log2_10)), 64)))
 	}
 
-	// clear z
-	z.SetPrec(0)
-
 	switch x.form {
 	case zero:
-		z.SetPrec(p)
-		if x.neg != z.Signbit() {
+		z.SetInt64(0)
+		if x.neg {
 			z.Neg(z)
 		}
 		return z
 	case inf:
-		return z.SetInf(x.neg).SetPrec(p)
+		return z.SetInf(x.neg)
 	}
 
-	// increase precision
-	z.SetPrec(p + 1)
-
-	// big.Float has no SetBits. Need to use a temp Int.
-	var i big.Int
-	i.SetBits(decToNat(nil, x.mant))
-
-	m := len(x.mant) * _DW
-	exp := int64(x.exp) - int64(m)
-	z = z.SetInt(&i)
-	if x.neg {
-		z.Neg(z)
-	}
-	// z = x·2**(m - x.exp)·5**(m - x.exp)
-
-	// normalize mantissa and apply 2 exponent
-	// done in two steps since SetMantExponent takes an int.
-	z.SetMantExp(z, -m)         // z = x·2**(-x.exp)·5**(m - x.exp)
-	z.SetMantExp(z, int(x.exp)) // z = x·5**(m - x.exp)
-
-	// now multiply/divide by 5**exp
-	if exp != 0 {
-		t := new(big.Float).SetPrec(uint(p))
-		if exp < 0 {
-			z.Quo(z, floatPow5(t, uint64(-exp)))
-		} else {
-			z.Mul(z, floatPow5(t, uint64(exp)))
-		}
-	}
-	// round
-	return z.SetPrec(p)
+	return x.float(z)
 }
 
 // Float32 returns the float32 value nearest to x. If x is too small to be
@@ -487,11 +557,11 @@ func (x *Decimal) Float(z *big.Float) *big.Float {
 // If x is too large to be represented by a float32 (|x| > math.MaxFloat32),
 // the result is (+Inf, Above) or (-Inf, Below), depending on the sign of x.
 func (x *Decimal) Float32() (float32, Accuracy) {
-	z := x.Float(new(big.Float).SetPrec(32))
+	z := x.floatOdd(24 + 2)
 	f, a := z.Float32()
-	// If big.Float -> float64 conversion is accurate, use Decimal->Float accuracy.
-	if a == big.Exact {
-		a = z.Acc()
+	if x.form == finite && (z.IsInf() || z.Sign() == 0) {
+		// out of big.Float range
+		a = big.Accuracy(makeAcc(z.IsInf() != x.neg))
 	}
 	return f, Accuracy(a)
 }
@@ -502,11 +572,11 @@ func (x *Decimal) Float32() (float32, Accuracy) {
 // If x is too large to be represented by a float64 (|x| > math.MaxFloat64),
 // the result is (+Inf, Above) or (-Inf, Below), depending on the sign of x.
 func (x *Decimal) Float64() (float64, Accuracy) {
-	z := x.Float(new(big.Float).SetPrec(64))
+	z := x.floatOdd(53 + 2)
 	f, a := z.Float64()
-	// If big.Float -> float64 conversion is accurate, use Decimal->Float accuracy.
-	if a == big.Exact {
-		a = z.Acc()
+	if x.form == finite && (z.IsInf() || z.Sign() == 0) {
+		// out of big.Float range
+		a = big.Accuracy(makeAcc(z.IsInf() != x.neg))
 	}
 	return f, Accuracy(a)
 }
@@ -664,6 +734,32 @@ func (x *Decimal) IsZero() bool {
 	return x.form == 0
 }
 
+// Logb sets z to the exponent of x in scientific notation, that is the integer
+// e such that 1 <= |x| × 10**-e < 10, and returns z. It implements the logB
+// operation of IEEE 754-2019 and is related to MantExp by
+// x.MantExp(nil) == e + 1. The result is rounded to z's precision with z's
+// rounding mode. If z's precision is 0, it is changed to DefaultDecimalPrec.
+//
+// Special cases are:
+//
+//	Logb(±0) = -Inf
+//	Logb(±Inf) = +Inf
+func (z *Decimal) Logb(x *Decimal) *Decimal {
+	if debugDecimal {
+		x.validate()
+	}
+	if z.prec == 0 {
+		z.prec = DefaultDecimalPrec
+	}
+	switch x.form {
+	case zero:
+		return z.SetInf(true)
+	case inf:
+		return z.SetInf(false)
+	}
+	return z.SetInt64(int64(x.exp) - 1)
+}
+
 // MantExp breaks x into its mantissa and exponent components
 // and returns the exponent. If a non-nil mant argument is
 // provided its value is set to the mantissa of x, with the
@@ -695,6 +791,54 @@ func (x *Decimal) MantExp(mant *Decimal) (exp int) {
 	return
 }
 
+// Max sets z to the (possibly rounded) greater of x and y, and returns z.
+// Unlike Cmp, Max considers +0 to be greater than -0: it implements the
+// maximum operation of IEEE 754-2019. Precision, rounding, and accuracy
+// reporting are as for Set.
+func (z *Decimal) Max(x, y *Decimal) *Decimal {
+	if x.TotalOrder(y) >= 0 {
+		return z.Set(x)
+	}
+	return z.Set(y)
+}
+
+// MaxMag sets z to the (possibly rounded) value of x or y with the greater
+// magnitude, or to Max(x, y) if |x| == |y|, and returns z. Precision, rounding,
+// and accuracy reporting are as for Set.
+func (z *Decimal) MaxMag(x, y *Decimal) *Decimal {
+	switch x.TotalOrderMag(y) {
+	case 1:
+		return z.Set(x)
+	case -1:
+		return z.Set(y)
+	}
+	return z.Max(x, y)
+}
+
+// Min sets z to the (possibly rounded) lesser of x and y, and returns z.
+// Unlike Cmp, Min considers -0 to be less than +0: it implements the minimum
+// operation of IEEE 754-2019. Precision, rounding, and accuracy reporting are
+// as for Set.
+func (z *Decimal) Min(x, y *Decimal) *Decimal {
+	if x.TotalOrder(y) <= 0 {
+		return z.Set(x)
+	}
+	return z.Set(y)
+}
+
+// MinMag sets z to the (possibly rounded) value of x or y with the lesser
+// magnitude, or to Min(x, y) if |x| == |y|, and returns z. Precision, rounding,
+// and accuracy reporting are as for Set.
+func (z *Decimal) MinMag(x, y *Decimal) *Decimal {
+	switch x.TotalOrderMag(y) {
+	case -1:
+		return z.Set(x)
+	case 1:
+		return z.Set(y)
+	}
+	return z.Min(x, y)
+}
+
 // MinPrec returns the minimum precision required to represent x exactly
 // (i.e., the smallest prec before x.SetPrec(prec) would start rounding x).
 // The result is 0 for |x| == 0 and |x| == Inf.
@@ -715,6 +859,11 @@ func (x *Decimal) Mode() RoundingMode {
 // Mul panics with ErrNaN if one operand is zero and the other
 // operand an infinity. The value of z is undefined in that case.
 func (z *Decimal) Mul(x, y *Decimal) *Decimal {
+	return z.mul(nil, x, y)
+}
+
+// mul is like Mul, but takes its temporaries from w.
+func (z *Decimal) mul(w *Workspace, x, y *Decimal) *Decimal {
 	if debugDecimal {
 		x.validate()
 		y.validate()
@@ -728,7 +877,7 @@ func (z *Decimal) Mul(x, y *Decimal) *Decimal {
 
 	if x.form == finite && y.form == finite {
 		// x * y (common case)
-		z.umul(x, y)
+		z.umul(w, x, y)
 		return z
 	}
 
@@ -792,7 +941,7 @@ func (z *Decimal) FMA(x, y, u *Decimal) *Decimal {
 		// prevent rounding in umul
 		prec := z0.prec
 		z0.prec = MaxPrec
-		z0.umul(x, y)
+		z0.umul(nil, x, y)
 		// restore precision without rounding
 		z0.prec = prec
 		return z.Add(z0, u)
@@ -822,10 +971,21 @@ func (z *Decimal) FMA(x, y, u *Decimal) *Decimal {
 }
 
 // Neg sets z to the (possibly rounded) value of x with its sign negated,
-// and returns z.
+// and returns z. The rounding applies to -x: with the ToNegativeInf mode for
+// instance, z is never greater than -x.
 func (z *Decimal) Neg(x *Decimal) *Decimal {
+	// round x with the mirrored directed rounding mode
+	mode := z.mode
+	switch mode {
+	case ToNegativeInf:
+		z.mode = ToPositiveInf
+	case ToPositiveInf:
+		z.mode = ToNegativeInf
+	}
 	z.Set(x)
+	z.mode = mode
 	z.neg = !z.neg
+	z.acc = -z.acc
 	return z
 }
 
@@ -840,6 +1000,11 @@ func (x *Decimal) Prec() uint {
 // Quo panics with ErrNaN if both operands are zero or infinities.
 // The value of z is undefined in that case.
 func (z *Decimal) Quo(x, y *Decimal) *Decimal {
+	return z.quo(nil, x, y)
+}
+
+// quo is like Quo, but takes its temporaries from w.
+func (z *Decimal) quo(w *Workspace, x, y *Decimal) *Decimal {
 	if debugDecimal {
 		x.validate()
 		y.validate()
@@ -853,7 +1018,7 @@ func (z *Decimal) Quo(x, y *Decimal) *Decimal {
 
 	if x.form == finite && y.form == finite {
 		// x / y (common case)
-		z.uquo(x, y)
+		z.uquo(w, x, y)
 		return z
 	}
 
@@ -883,11 +1048,33 @@ func (z *Decimal) Quo(x, y *Decimal) *Decimal {
 // z = x / y, ignoring signs of x and y for the division
 // but using the sign of z for rounding the result.
 // x and y must have a non-empty mantissa and valid exponent.
-func (z *Decimal) uquo(x, y *Decimal) {
+func (z *Decimal) uquo(w *Workspace, x, y *Decimal) {
 	if debugDecimal {
 		validateBinaryOperands(x, y)
 	}
 
+	if len(x.mant) == 1 && len(y.mant) == 1 && z.prec <= _DW {
+		// single Word mantissae, a two Words quotient has at least _DW+1
+		// digits: shift x by one Word if x.mant >= y.mant, by two otherwise.
+		xm, ym := x.mant[0], y.mant[0]
+		var q1, q0, r Word
+		d := 1
+		if xm >= ym {
+			q1, r = xm/ym, xm%ym
+		} else {
+			d = 2
+			q1, r = div10WW(xm, 0, ym)
+		}
+		q0, r = div10WW(r, 0, ym)
+		z.setMant2(q1, q0)
+		var sbit uint
+		if r != 0 {
+			sbit = 1
+		}
+		z.setExpAndRound(int64(x.exp)-int64(y.exp)-int64(d-len(z.mant))*_DW-dnorm(z.mant), sbit)
+		return
+	}
+
 	// mantissa length in words for desired result precision + 1
 	// (at least one extra bit so we get the rounding bit after
 	// the division)
@@ -895,9 +1082,12 @@ func (z *Decimal) uquo(x, y *Decimal) {
 
 	// compute adjusted x.mant such that we get enough result precision
 	xadj := x.mant
+	var xp *dec
 	if d := n - len(x.mant) + len(y.mant); d > 0 {
 		// d extra words needed => add d "0 digits" to x
-		xadj = make(dec, len(x.mant)+d)
+		xp = w.getDec(len(x.mant) + d)
+		xadj = *xp
+		xadj[:d].clear()
 		copy(xadj[d:], x.mant)
 	}
 	// TODO(db47h): If we have too many digits (d < 0), we should be able
@@ -909,8 +1099,11 @@ func (z *Decimal) uquo(x, y *Decimal) {
 	d := len(xadj) - len(y.mant)
 
 	// divide
+	rp := w.getDec(0)
 	var r dec
-	z.mant, r = z.mant.div(nil, xadj, y.mant)
+	m, tp := w.mantBuf(z, xadj, y.mant)
+	m, r = m.divWith(w, *rp, xadj, y.mant)
+	w.setMant(z, x, y, m, tp)
 	e := int64(x.exp) - int64(y.exp) - int64(d-len(z.mant))*_DW
 
 	// The result is long enough to include (at least) the rounding bit.
@@ -921,6 +1114,11 @@ func (z *Decimal) uquo(x, y *Decimal) {
 	if len(r) > 0 {
 		sbit = 1
 	}
+	*rp = r
+	w.putDec(rp)
+	if xp != nil {
+		w.putDec(xp)
+	}
 
 	z.setExpAndRound(e-dnorm(z.mant), sbit)
 }
@@ -978,6 +1176,38 @@ func (x *Decimal) Rat(z *big.Rat) (*big.Rat, Accuracy) {
 	panic("unreachable")
 }
 
+// Scaleb sets z to x × 10**n and returns z. It implements the scaleB operation
+// of IEEE 754-2019: the result is exact, unless x needs to be rounded to z's
+// precision or the result is out of range. Precision, rounding, and accuracy
+// reporting are as for Set, except that the result overflows to ±Inf if
+// |x × 10**n| >= 10**MaxExp, and underflows to ±0 if |x × 10**n| < 10**(MinExp-1).
+//
+// Unlike SetMantExp, which produces a result with x's precision, Scaleb rounds
+// the result to z's precision.
+//
+// On 32-bit platforms, n is limited to the range of an int32: for the smallest
+// values of x, with Logb(x) <= -2**31, -Logb(x) does not fit in n and
+// Scaleb(x, -Logb(x)) must be computed in two steps.
+func (z *Decimal) Scaleb(x *Decimal, n int) *Decimal {
+	z.Set(x)
+	if z.form != finite {
+		return z
+	}
+	switch exp := int64(z.exp) + int64(n); {
+	case exp < MinExp:
+		// underflow
+		z.acc = makeAcc(z.neg)
+		z.form = zero
+	case exp > MaxExp:
+		// overflow
+		z.acc = makeAcc(!z.neg)
+		z.form = inf
+	default:
+		z.exp = int32(exp)
+	}
+	return z
+}
+
 // Set sets z to the (possibly rounded) value of x and returns z.
 // If z's precision is 0, it is changed to the precision of x
 // before setting z (and rounding will have no effect).
@@ -1009,10 +1239,10 @@ func (z *Decimal) Set(x *Decimal) *Decimal {
 // SetFloat sets z to the (possibly rounded) value of x and returns z. If z's
 // precision is 0, it is changed to ⌈x.Prec() * Log10(2)⌉.
 //
-// Conversion is performed using z's precision and rounding mode. Caveat: as a
-// result this may lead to inconsistencies between the ouputs of x.Text and
-// z.Text. To preserve this property, the conversion should be done using x's
-// precision and rounding mode set to ToNearestEven:
+// Conversion is correctly rounded using z's precision and rounding mode.
+// Caveat: as a result this may lead to inconsistencies between the ouputs of
+// x.Text and z.Text. To preserve this property, the conversion should be done
+// using x's precision and rounding mode set to ToNearestEven:
 //
 //  p, m := z.Prec(), z.Mode()
 //  z.SetPrec(0).SetMode(ToNearestEven).SetFloat(x)
@@ -1023,43 +1253,25 @@ func (z *Decimal) SetFloat(x *big.Float) *Decimal {
 	}
 	z.acc = Exact
 	z.neg = x.Signbit()
-	if z.IsInf() {
+	if x.IsInf() {
 		z.form = inf
 		return z
 	}
+	if x.Sign() == 0 {
+		z.form = zero
+		return z
+	}
 
-	// TODO(db47h): the conversion is somewhat contrieved, but we don't have
-	// access to x's mantissa. The conversion algorithm is also naive. Cmparing
-	// string -> big.Float -> Decimal -> string will very likely fail since the
-	// Decimal is not rounded to the shortest possible representation of the
-	// input float.
+	// x = m × 2**exp2, with m an odd integer
 	f := new(big.Float).Copy(x)
-	// get int value of mantissa
 	exp2 := int64(f.MantExp(f))
 	fprec := f.MinPrec()
 	f.SetMantExp(f, int(fprec))
-	i, _ := f.Int(nil)
-	z.SetInt(i)
 	exp2 -= int64(fprec)
-	if exp2 != 0 {
-		// multiply / divide by 2**exp with increased precision
-		z.prec++
-		t := new(Decimal).SetPrec(uint(z.prec))
-		if exp2 < 0 {
-			if exp2 < MinExp {
-				// handle exponent overflow. Can only happen if exp2 < 0
-				// convert mantissa first, then apply exponent.
-				exp2 += int64(fprec)
-				z = z.Quo(z, t.pow2(uint64(fprec)))
-			}
-			z = z.Quo(z, t.pow2(uint64(-exp2)))
-		} else {
-			z = z.Mul(z, t.pow2(uint64(exp2)))
-		}
-		z.prec--
-	}
-	z.round(0)
-	return z
+	m, _ := f.Int(nil)
+	prec := uint32(math.Ceil(float64(m.BitLen()) * log10_2)) // off by 1 at most
+	z.mant = z.mant.make(int((prec + _DW - 1) / _DW)).setNat(m.Bits())
+	return z.setExp2(z.mant, exp2, 0)
 }
 
 // SetFloat64 sets z to the (possibly rounded) value of x and returns z. If z's
@@ -1085,29 +1297,13 @@ func (z *Decimal) SetFloat64(x float64) *Decimal {
 	}
 	// normalized x != 0
 
-	// TODO(db47h): The conversion algorithm is naive. Cmparing string ->
-	// float64 -> Decimal -> string will very likely fail since the Decimal is
-	// not rounded to the shortest possible representation of the input float
-	// when z.prec == 0.
+	// TODO(db47h): Cmparing string -> float64 -> Decimal -> string will very
+	// likely fail since the Decimal is not rounded to the shortest possible
+	// representation of the input float when z.prec == 0.
 
-	z.form = finite
 	fmant, exp2 := math.Frexp(x) // get normalized mantissa
-	exp2 -= 53
 	z.mant = z.mant.setUint64(1<<52 | (math.Float64bits(fmant) & (1<<52 - 1)))
-	z.exp = int32(len(z.mant))*_DW - int32(dnorm(z.mant))
-	if exp2 != 0 {
-		// multiply / divide by 2**exp with increased precision
-		z.prec++
-		t := new(Decimal).SetPrec(uint(z.prec))
-		if exp2 < 0 {
-			z = z.Quo(z, t.pow2(uint64(-exp2)))
-		} else {
-			z = z.Mul(z, t.pow2(uint64(exp2)))
-		}
-		z.prec--
-	}
-	z.round(0)
-	return z
+	return z.setExp2(z.mant, int64(exp2)-53, 0)
 }
 
 // SetInf sets z to the infinite Decimal -Inf if signbit is
@@ -1330,6 +1526,11 @@ func (x *Decimal) Signbit() bool {
 // Sub panics with ErrNaN if x and y are infinities with equal
 // signs. The value of z is undefined in that case.
 func (z *Decimal) Sub(x, y *Decimal) *Decimal {
+	return z.sub(nil, x, y)
+}
+
+// sub is like Sub, but takes its temporaries from w.
+func (z *Decimal) sub(w *Workspace, x, y *Decimal) *Decimal {
 	if debugDecimal {
 		x.validate()
 		y.validate()
@@ -1346,15 +1547,15 @@ func (z *Decimal) Sub(x, y *Decimal) *Decimal {
 		if x.neg != yneg {
 			// x - (-y) == x + y
 			// (-x) - y == -(x + y)
-			z.uadd(x, y)
+			z.uadd(w, x, y)
 		} else {
 			// x - y == x - y == -(y - x)
 			// (-x) - (-y) == y - x == -(x - y)
 			if x.ucmp(y) > 0 {
-				z.usub(x, y)
+				z.usub(w, x, y)
 			} else {
 				z.neg = !z.neg
-				z.usub(y, x)
+				z.usub(w, y, x)
 			}
 		}
 		if z.form == zero && z.mode == ToNegativeInf && z.acc == Exact {
@@ -1392,6 +1593,71 @@ func (z *Decimal) Sub(x, y *Decimal) *Decimal {
 	return z.Neg(y)
 }
 
+// TotalOrder compares x and y and returns:
+//
+//	-1 if x <  y
+//	 0 if x == y (incl. -Inf == -Inf, and +Inf == +Inf)
+//	+1 if x >  y
+//
+// TotalOrder differs from Cmp in that it considers -0 to be less than +0. It
+// implements the totalOrder predicate of IEEE 754-2019 (x <= y is
+// x.TotalOrder(y) <= 0). Since Decimals have a unique representation for each
+// value, TotalOrder is a total order on Decimal values: x.TotalOrder(y) == 0
+// if and only if x and y are identical, apart from their precision, rounding
+// mode and accuracy.
+func (x *Decimal) TotalOrder(y *Decimal) int {
+	if c := x.Cmp(y); c != 0 || x.form != zero {
+		return c
+	}
+	// x and y are ±0
+	switch {
+	case x.neg == y.neg:
+		return 0
+	case x.neg:
+		return -1
+	}
+	return +1
+}
+
+// TotalOrderMag compares the absolute values of x and y and returns -1, 0, or
+// +1 like TotalOrder(|x|, |y|). It implements the totalOrderMag predicate of
+// IEEE 754-2019.
+func (x *Decimal) TotalOrderMag(y *Decimal) int {
+	if debugDecimal {
+		x.validate()
+		y.validate()
+	}
+
+	mx := x.ord()
+	my := y.ord()
+	if mx < 0 {
+		mx = -mx
+	}
+	if my < 0 {
+		my = -my
+	}
+	switch {
+	case mx < my:
+		return -1
+	case mx > my:
+		return +1
+	}
+	if mx == 1 {
+		return x.ucmp(y)
+	}
+	return 0
+}
+
+// TrailingZeros returns the number of trailing zero digits in the mantissa of x
+// at x's precision, that is x.Prec() - x.Digits(). The result is 0 for |x| == 0
+// and |x| == Inf.
+func (x *Decimal) TrailingZeros() uint {
+	if x.form != finite {
+		return 0
+	}
+	return uint(x.prec) - x.Digits()
+}
+
 // Uint64 returns the unsigned integer resulting from truncating x
 // towards zero. If 0 <= x <= math.MaxUint64, the result is Exact
 // if x is an integer and Below otherwise.
@@ -1516,9 +1782,10 @@ func (z *Decimal) round(sbit uint) {
 	r := uint(digits - z.prec - 1) // rounding digit position r >= 0
 	rdigit := z.mant.digit(r)      // rounding digit
 
-	if sbit == 0 && (rdigit == 0 || z.mode == ToNearestEven) {
-		// The sticky bit is only needed for rounding ToNearestEven
-		// or when the rounding bit is zero. Avoid computation otherwise.
+	if sbit == 0 && (rdigit == 0 || z.mode == ToNearestEven || z.mode == ToNearestTowardZero) {
+		// The sticky bit is only needed for rounding ToNearestEven,
+		// ToNearestTowardZero or when the rounding bit is zero. Avoid
+		// computation otherwise.
 		sbit = z.mant.sticky(r)
 	}
 	sbit &= 1 // be safe and ensure it's a single bit
@@ -1553,6 +1820,13 @@ func (z *Decimal) round(sbit uint) {
 			inc = true
 		case ToPositiveInf:
 			inc = !z.neg
+		case ToNearestTowardZero:
+			inc = rdigit > 5 || (rdigit == 5 && sbit != 0)
+		case Round05Up:
+			d := z.mant.digit(ntz)
+			inc = d == 0 || d == 5
+		case ToOdd:
+			inc = z.mant.digit(ntz)&1 == 0
 		default:
 			panic("unreachable")
 		}
@@ -1582,6 +1856,80 @@ func (z *Decimal) round(sbit uint) {
 	}
 }
 
+// RoundInt sets z to the value of x rounded to an integer according to z's
+// rounding mode, and returns z. If z's precision is less than that of x, it is
+// changed to the precision of x, so that the integer is never rounded further.
+// z's accuracy reports the result error relative to x.
+func (z *Decimal) RoundInt(x *Decimal) *Decimal {
+	prec, mode := umax32(z.prec, x.prec), z.mode
+	z.Copy(x)
+	z.mode = mode
+	z.acc = Exact
+	if z.form == finite {
+		z.roundInt()
+	}
+	z.prec = prec
+	return z
+}
+
+// RoundStochastic sets z to the value of x rounded to z's precision using
+// stochastic rounding, and returns z. The result is rounded away from zero with
+// a probability equal to the discarded fraction of a unit in the last place,
+// and toward zero otherwise, so that its expected value is x. The rounding
+// decision compares the discarded digits of x with random digits drawn from
+// src, one at a time; the result is therefore deterministic for a given source
+// and seed. Exact values consume no random numbers.
+//
+// If z's precision is 0, it is changed to the precision of x before setting z
+// (and rounding will have no effect). z's rounding mode is left unchanged and
+// z's accuracy reports the result error relative to x.
+func (z *Decimal) RoundStochastic(x *Decimal, src rand.Source) *Decimal {
+	prec, mode := z.prec, z.mode
+	if prec == 0 {
+		prec = x.prec
+	}
+	z.Copy(x)
+	z.prec, z.mode = prec, mode
+	z.acc = Exact
+	if z.form != finite || uint32(len(z.mant))*_DW <= z.prec {
+		return z
+	}
+	r := uint(uint32(len(z.mant))*_DW - z.prec - 1) // rounding digit position
+	if z.mant.digit(r) == 0 && z.mant.sticky(r) == 0 {
+		// exact: let round truncate trailing zeros
+		z.round(0)
+		return z
+	}
+	// Compare the discarded fraction 0.d[r]d[r-1]...d[0] with a uniformly
+	// distributed random fraction 0.u[r]u[r-1]... and round away from zero iff
+	// the former is larger. If all digits up to d[0] are equal, the random
+	// fraction is larger or equal.
+	inc := false
+	for i := int(r); i >= 0; i-- {
+		if d, u := z.mant.digit(uint(i)), randDigit(src); d != u {
+			inc = d > u
+			break
+		}
+	}
+	z.mode = ToZero
+	if inc {
+		z.mode = AwayFromZero
+	}
+	z.round(0)
+	z.mode = mode
+	return z
+}
+
+// randDigit returns a uniformly distributed random decimal digit drawn from src.
+func randDigit(src rand.Source) uint {
+	const max = 1<<63 - (1<<63)%10
+	for {
+		if n := src.Int63(); n < max {
+			return uint(n % 10)
+		}
+	}
+}
+
 // dnorm normalizes mantissa m by shifting it to the left
 // such that the msd of the most-significant word (msw) is != 0.
 // It returns the shift amount. It assumes that len(m) != 0.
@@ -1603,17 +1951,19 @@ func dnorm(m dec) int64 {
 // z = x * y, ignoring signs of x and y for the multiplication
 // but using the sign of z for rounding the result.
 // x and y must have a non-empty mantissa and valid exponent.
-func (z *Decimal) umul(x, y *Decimal) {
+func (z *Decimal) umul(w *Workspace, x, y *Decimal) {
 	if debugDecimal {
 		validateBinaryOperands(x, y)
 	}
 
 	e := int64(x.exp) + int64(y.exp)
-	if x == y {
-		z.mant = z.mant.sqr(x.mant)
-	} else {
-		z.mant = z.mant.mul(x.mant, y.mant)
+	if len(x.mant) == 1 && len(y.mant) == 1 {
+		// single Word mantissae
+		z.setMant2(mul10WW(x.mant[0], y.mant[0]))
+		z.setExpAndRound(e-dnorm(z.mant), 0)
+		return
 	}
+	w.mulMant(z, x, y)
 	z.setExpAndRound(e-dnorm(z.mant), 0)
 }
 
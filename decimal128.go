@@ -0,0 +1,222 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// A Decimal128 represents a decimal floating-point number with a 34 digits
+// coefficient and an exponent in the range of the IEEE 754-2008 decimal128
+// format: finite non-zero values have a magnitude in the range [1e-6176,
+// 1e6145), with gradual underflow below 1e-6143. A Decimal128 may also be
+// zero (+0, -0) or infinite (+Inf, -Inf).
+//
+// Like Decimal64, a Decimal128 is a plain comparable value without pointers,
+// kept in canonical form, and its zero value is +0.
+type Decimal128 struct {
+	hi, lo uint64 // coefficient hi×1e19 + lo, without trailing zeros
+	exp    int32  // exponent of the least significant digit of the coefficient
+	form   form
+	neg    bool
+}
+
+// NewDecimal128 returns coef × 10**exp rounded ToNearestEven.
+func NewDecimal128(coef int64, exp int) Decimal128 {
+	var z fixed
+	z.setInt64(coef, exp)
+	decimal128Format.round(&z, false, decimal128Format.qmin, ToNearestEven)
+	return z.decimal128()
+}
+
+// ParseDecimal128 parses s like Decimal.Parse with base 10 and returns the
+// corresponding Decimal128 value, rounded with the given mode.
+func ParseDecimal128(s string, mode RoundingMode) (Decimal128, error) {
+	var z fixed
+	if err := decimal128Format.parse(&z, s, mode); err != nil {
+		return Decimal128{}, err
+	}
+	return z.decimal128(), nil
+}
+
+const pow10_19 = 1e19
+
+// unpack sets z to x.
+func (x Decimal128) unpack(z *fixed) {
+	if _W == 64 {
+		z.c = wide{}
+		z.c[0], z.c[1] = Word(x.lo), Word(x.hi)
+	} else {
+		var t wide
+		z.c.setUint64(x.hi)
+		z.c.shl(19)
+		t.setUint64(x.lo)
+		z.c.add(&t)
+	}
+	z.q = int64(x.exp)
+	z.form = x.form
+	z.neg = x.neg
+}
+
+func (x *fixed) decimal128() Decimal128 {
+	z := Decimal128{exp: int32(x.q), form: x.form, neg: x.neg}
+	if _W == 64 {
+		z.lo, z.hi = uint64(x.c[0]), uint64(x.c[1])
+	} else {
+		z.lo = x.c.uint64()
+		if x.c.digits() > 19 {
+			t := x.c
+			t.shr(19)
+			z.hi = t.uint64()
+			z.lo -= z.hi * pow10_19
+		}
+	}
+	return z
+}
+
+// Add returns x + y rounded with the given mode.
+func (x Decimal128) Add(y Decimal128, mode RoundingMode) Decimal128 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal128Format.add(&fx, &fy, false, mode)
+	return fx.decimal128()
+}
+
+// Sub returns x - y rounded with the given mode.
+func (x Decimal128) Sub(y Decimal128, mode RoundingMode) Decimal128 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal128Format.add(&fx, &fy, true, mode)
+	return fx.decimal128()
+}
+
+// Mul returns x × y rounded with the given mode.
+func (x Decimal128) Mul(y Decimal128, mode RoundingMode) Decimal128 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal128Format.mul(&fx, &fy, mode)
+	return fx.decimal128()
+}
+
+// Quo returns x / y rounded with the given mode.
+func (x Decimal128) Quo(y Decimal128, mode RoundingMode) Decimal128 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal128Format.quo(&fx, &fy, mode)
+	return fx.decimal128()
+}
+
+// Quantize returns x rounded with the given mode to a multiple of 10**exp.
+// Infinities and zeros are returned unchanged.
+func (x Decimal128) Quantize(exp int, mode RoundingMode) Decimal128 {
+	if x.form != finite || int(x.exp) >= exp {
+		return x
+	}
+	var z fixed
+	x.unpack(&z)
+	decimal128Format.round(&z, false, int64(exp), mode)
+	return z.decimal128()
+}
+
+// Cmp compares x and y and returns:
+//
+//	-1 if x <  y
+//	 0 if x == y (incl. -0 == 0, -Inf == -Inf, and +Inf == +Inf)
+//	+1 if x >  y
+//
+func (x Decimal128) Cmp(y Decimal128) int {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	return fx.cmp(&fy)
+}
+
+// Neg returns x with its sign negated.
+func (x Decimal128) Neg() Decimal128 {
+	x.neg = !x.neg
+	return x
+}
+
+// Abs returns |x|.
+func (x Decimal128) Abs() Decimal128 {
+	x.neg = false
+	return x
+}
+
+// Sign returns:
+//
+//	-1 if x <   0
+//	 0 if x is ±0
+//	+1 if x >   0
+//
+func (x Decimal128) Sign() int {
+	if x.form == zero {
+		return 0
+	}
+	if x.neg {
+		return -1
+	}
+	return 1
+}
+
+// Signbit reports whether x is negative or negative zero.
+func (x Decimal128) Signbit() bool {
+	return x.neg
+}
+
+// IsInf reports whether x is +Inf or -Inf.
+func (x Decimal128) IsInf() bool {
+	return x.form == inf
+}
+
+// IsZero reports whether x is +0 or -0.
+func (x Decimal128) IsZero() bool {
+	return x.form == zero
+}
+
+// Decimal64 returns x rounded to a Decimal64 with the given mode.
+func (x Decimal128) Decimal64(mode RoundingMode) Decimal64 {
+	var z fixed
+	x.unpack(&z)
+	decimal64Format.round(&z, false, decimal64Format.qmin, mode)
+	return z.decimal64()
+}
+
+// Decimal sets z to x, rounded to z's precision and rounding mode, and
+// returns z. If z is nil, a new Decimal is allocated. If z's precision is 0,
+// it is changed to 34 and the conversion is exact.
+func (x Decimal128) Decimal(z *Decimal) *Decimal {
+	if z == nil {
+		z = new(Decimal)
+	}
+	if z.prec == 0 {
+		z.prec = 34
+	}
+	var fx fixed
+	x.unpack(&fx)
+	fx.decimal(z)
+	return z
+}
+
+// Decimal128 returns the Decimal128 value nearest to x, rounded with x's
+// rounding mode, and the accuracy of the result. If x is too large to be
+// represented by a Decimal128, the result is ±Inf.
+func (x *Decimal) Decimal128() (Decimal128, Accuracy) {
+	var z fixed
+	acc := decimal128Format.setDecimal(&z, x, x.mode)
+	return z.decimal128(), acc
+}
+
+// Text converts x to a string like Decimal.Text. A precision of -1 uses the
+// smallest number of digits necessary to represent x exactly.
+func (x Decimal128) Text(format byte, prec int) string {
+	var d Decimal
+	return x.Decimal(&d).Text(format, prec)
+}
+
+// String formats x like x.Text('g', -1).
+func (x Decimal128) String() string {
+	return x.Text('g', -1)
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// A Decimal64 represents a decimal floating-point number with a 16 digits
+// coefficient and an exponent in the range of the IEEE 754-2008 decimal64
+// format: finite non-zero values have a magnitude in the range [1e-398,
+// 1e385), with gradual underflow below 1e-383. A Decimal64 may also be zero
+// (+0, -0) or infinite (+Inf, -Inf).
+//
+// Unlike Decimal, a Decimal64 is a plain value without pointers. The zero
+// value is +0. Decimal64 values are kept in canonical form: two Decimal64
+// values are equal with == if and only if they represent the same number with
+// the same sign (+0 != -0), so that they can be used as map keys.
+//
+// Operations that round take the rounding mode as argument. Results that
+// overflow are ±Inf and, like with Decimal, operations that would produce a
+// NaN panic with an ErrNaN.
+type Decimal64 struct {
+	coef uint64 // coefficient without trailing zeros
+	exp  int32  // exponent of the least significant digit of coef
+	form form
+	neg  bool
+}
+
+// NewDecimal64 returns coef × 10**exp rounded ToNearestEven.
+func NewDecimal64(coef int64, exp int) Decimal64 {
+	var z fixed
+	z.setInt64(coef, exp)
+	decimal64Format.round(&z, false, decimal64Format.qmin, ToNearestEven)
+	return z.decimal64()
+}
+
+// ParseDecimal64 parses s like Decimal.Parse with base 10 and returns the
+// corresponding Decimal64 value, rounded with the given mode.
+func ParseDecimal64(s string, mode RoundingMode) (Decimal64, error) {
+	var z fixed
+	if err := decimal64Format.parse(&z, s, mode); err != nil {
+		return Decimal64{}, err
+	}
+	return z.decimal64(), nil
+}
+
+// unpack sets z to x.
+func (x Decimal64) unpack(z *fixed) {
+	z.c.setUint64(x.coef)
+	z.q = int64(x.exp)
+	z.form = x.form
+	z.neg = x.neg
+}
+
+func (x *fixed) decimal64() Decimal64 {
+	return Decimal64{coef: x.c.uint64(), exp: int32(x.q), form: x.form, neg: x.neg}
+}
+
+// setInt64 sets z to coef × 10**exp, without rounding.
+func (z *fixed) setInt64(coef int64, exp int) {
+	u := uint64(coef)
+	if coef < 0 {
+		u = -u
+	}
+	*z = fixed{q: int64(exp), form: finite, neg: coef < 0}
+	z.c.setUint64(u)
+}
+
+// Add returns x + y rounded with the given mode.
+func (x Decimal64) Add(y Decimal64, mode RoundingMode) Decimal64 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal64Format.add(&fx, &fy, false, mode)
+	return fx.decimal64()
+}
+
+// Sub returns x - y rounded with the given mode.
+func (x Decimal64) Sub(y Decimal64, mode RoundingMode) Decimal64 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal64Format.add(&fx, &fy, true, mode)
+	return fx.decimal64()
+}
+
+// Mul returns x × y rounded with the given mode.
+func (x Decimal64) Mul(y Decimal64, mode RoundingMode) Decimal64 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal64Format.mul(&fx, &fy, mode)
+	return fx.decimal64()
+}
+
+// Quo returns x / y rounded with the given mode.
+func (x Decimal64) Quo(y Decimal64, mode RoundingMode) Decimal64 {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	decimal64Format.quo(&fx, &fy, mode)
+	return fx.decimal64()
+}
+
+// Quantize returns x rounded with the given mode to a multiple of 10**exp.
+// For instance, x.Quantize(-2, ToNearestEven) rounds x to two decimal places.
+// Infinities and zeros are returned unchanged.
+func (x Decimal64) Quantize(exp int, mode RoundingMode) Decimal64 {
+	if x.form != finite || int(x.exp) >= exp {
+		return x
+	}
+	var z fixed
+	x.unpack(&z)
+	decimal64Format.round(&z, false, int64(exp), mode)
+	return z.decimal64()
+}
+
+// Cmp compares x and y and returns:
+//
+//	-1 if x <  y
+//	 0 if x == y (incl. -0 == 0, -Inf == -Inf, and +Inf == +Inf)
+//	+1 if x >  y
+//
+func (x Decimal64) Cmp(y Decimal64) int {
+	var fx, fy fixed
+	x.unpack(&fx)
+	y.unpack(&fy)
+	return fx.cmp(&fy)
+}
+
+// Neg returns x with its sign negated.
+func (x Decimal64) Neg() Decimal64 {
+	x.neg = !x.neg
+	return x
+}
+
+// Abs returns |x|.
+func (x Decimal64) Abs() Decimal64 {
+	x.neg = false
+	return x
+}
+
+// Sign returns:
+//
+//	-1 if x <   0
+//	 0 if x is ±0
+//	+1 if x >   0
+//
+func (x Decimal64) Sign() int {
+	if x.form == zero {
+		return 0
+	}
+	if x.neg {
+		return -1
+	}
+	return 1
+}
+
+// Signbit reports whether x is negative or negative zero.
+func (x Decimal64) Signbit() bool {
+	return x.neg
+}
+
+// IsInf reports whether x is +Inf or -Inf.
+func (x Decimal64) IsInf() bool {
+	return x.form == inf
+}
+
+// IsZero reports whether x is +0 or -0.
+func (x Decimal64) IsZero() bool {
+	return x.form == zero
+}
+
+// Decimal128 returns x as a Decimal128. The conversion is exact.
+func (x Decimal64) Decimal128() Decimal128 {
+	var z fixed
+	x.unpack(&z)
+	return z.decimal128()
+}
+
+// Decimal sets z to x, rounded to z's precision and rounding mode, and
+// returns z. If z is nil, a new Decimal is allocated. If z's precision is 0,
+// it is changed to 16 and the conversion is exact.
+func (x Decimal64) Decimal(z *Decimal) *Decimal {
+	if z == nil {
+		z = new(Decimal)
+	}
+	if z.prec == 0 {
+		z.prec = 16
+	}
+	var fx fixed
+	x.unpack(&fx)
+	fx.decimal(z)
+	return z
+}
+
+// Decimal64 returns the Decimal64 value nearest to x, rounded with x's
+// rounding mode, and the accuracy of the result. If x is too large to be
+// represented by a Decimal64, the result is ±Inf.
+func (x *Decimal) Decimal64() (Decimal64, Accuracy) {
+	var z fixed
+	acc := decimal64Format.setDecimal(&z, x, x.mode)
+	return z.decimal64(), acc
+}
+
+// Text converts x to a string like Decimal.Text. A precision of -1 uses the
+// smallest number of digits necessary to represent x exactly.
+func (x Decimal64) Text(format byte, prec int) string {
+	var d Decimal
+	return x.Decimal(&d).Text(format, prec)
+}
+
+// String formats x like x.Text('g', -1).
+func (x Decimal64) String() string {
+	return x.Text('g', -1)
+}
@@ -7,6 +7,8 @@ package decimal
 import (
 	"fmt"
 	"io"
+	"math/big"
+	"math/bits"
 	"strings"
 )
 
@@ -69,35 +71,26 @@ func (z *Decimal) scan(r io.ByteScanner, base int) (f *Decimal, b int, err error
 	// The mantissa may have a radix point (fcount <= 0) and there
 	// may be a nonzero exponent exp. The radix point amounts to a
 	// division by b**(-fcount). An exponent means multiplication by
-	// ebase**exp. Finally, mantissa normalization (shift left) requires
-	// a correcting multiplication by 2**(-shiftcount). Multiplications
-	// are commutative, so we can apply them in any order as long as there
-	// is no loss of precision. We only have powers of 2 and 10, and
-	// we split powers of 10 into the product of the same powers of
-	// 2 and 5. This reduces the size of the multiplication factor
-	// needed for base-10 exponents.
-
-	// normalize mantissa and determine initial exponent contributions
-	exp2 := int64(0)
-	exp10 := int64(len(z.mant))*_DW - dnorm(z.mant)
+	// ebase**exp. If b is 10 or a power of two, the radix point amounts
+	// to a power of 10 or 2 that we can merge with the exponent, and the
+	// result is computed by setExp2 with a single rounding. For other
+	// bases, we need an actual division.
 
 	// determine binary or decimal exponent contribution of radix point
+	var exp2, exp10 int64
+	var frac uint64 // number of fractional digits in a base other than 10 or 2**n
 	if fcount < 0 {
 		// The mantissa has a radix point ddd.dddd; and
 		// -fcount is the number of digits to the right
 		// of '.'. Adjust relevant exponent accordingly.
 		d := int64(fcount)
-		switch b {
-		case 10:
-			exp10 += d
-		case 2:
-			exp2 += d
-		case 8:
-			exp2 += d * 3 // octal digits are 3 bits each
-		case 16:
-			exp2 += d * 4 // hexadecimal digits are 4 bits each
+		switch {
+		case b == 10:
+			exp10 = d
+		case b&(b-1) == 0:
+			exp2 = d * int64(bits.TrailingZeros(uint(b))) // log2(b) bits per digit
 		default:
-			panic("unexpected mantissa base")
+			frac = uint64(-d)
 		}
 		// fcount consumed - not needed anymore
 	}
@@ -113,63 +106,77 @@ func (z *Decimal) scan(r io.ByteScanner, base int) (f *Decimal, b int, err error
 	}
 	// exp consumed - not needed anymore
 
-	// apply 10**exp10
-	if MinExp <= exp10 && exp10 <= MaxExp {
-		z.prec = prec
-		z.form = finite
-		z.exp = int32(exp10)
-		f = z
-	} else {
-		err = fmt.Errorf("exponent overflow")
-		return
+	// z.mant is an integer; without binary exponent contribution, the
+	// exponent of the result is known.
+	if exp2 == 0 && frac == 0 {
+		e := exp10 + int64(len(z.mant))*_DW - int64(nlz10(z.mant[len(z.mant)-1]))
+		if e < MinExp || MaxExp < e {
+			err = fmt.Errorf("exponent overflow")
+			return
+		}
 	}
+	z.prec = prec
+	z.acc = Exact
+	f = z
 
-	if exp2 == 0 {
+	switch {
+	case frac > 0:
+		m := new(big.Int).SetBits(decToNat(nil, z.mant))
+		d := new(big.Int).Exp(big.NewInt(int64(b)), new(big.Int).SetUint64(frac), nil)
+		z.setFrac(m, d, exp2, exp10)
+	case exp2 != 0:
+		z.setExp2(z.mant, exp2, exp10)
+	default:
 		// no binary exponent contribution
-		z.round(0)
-		return
-	}
-	// exp2 != 0
-
-	// // apply 2**exp2
-	p := new(Decimal).SetPrec(z.Prec() + _DW) // use more bits for p -- TODO(db47h) what is the right number?
-	if exp2 < 0 {
-		z.Quo(z, p.pow2(uint64(-exp2)))
-	} else {
-		z.Mul(z, p.pow2(uint64(exp2)))
+		z.setExpAndRound(int64(len(z.mant))*_DW-dnorm(z.mant)+exp10, 0)
 	}
 
 	return
 }
 
-// pow2 sets z to 2**n and returns z.
-// n must not be negative.
-func (z *Decimal) pow2(n uint64) *Decimal {
-	const m = _DWb - 1 // maximum exponent such that 2**m < _BD
-	if n < _W {
-		return z.SetUint64(1 << n)
-	}
-	// n > m
-
-	z.SetUint64(1 << m)
-	n -= m
-
-	// use more bits for f than for z
-	// TODO(db47h) what is the right number?
-	f := new(Decimal).SetPrec(z.Prec() + _DW).SetUint64(2)
+// setFrac sets z to the (possibly rounded) value of m / d × 2**exp2 × 10**exp10
+// and returns z. Rounding is performed according to z's precision and rounding
+// mode. The sign of z must be set and m and d must be > 0.
+func (z *Decimal) setFrac(m, d *big.Int, exp2, exp10 int64) *Decimal {
+	g := new(big.Int).GCD(nil, nil, m, d)
+	m.Quo(m, g)
+	d.Quo(d, g)
 
-	for n > 0 {
-		if n&1 != 0 {
-			z.Mul(z, f)
-			if n == 1 {
-				break
-			}
+	// m/d has a finite decimal representation iff d = 2**a × 5**c, in which
+	// case m/d × 2**exp2 = m × 2**(exp2-a+c) × 10**-c.
+	a := d.TrailingZeroBits()
+	d.Rsh(d, a)
+	var c int64
+	for q, r := new(big.Int), new(big.Int); d.Cmp(big.NewInt(1)) > 0; c++ {
+		if q.QuoRem(d, big.NewInt(5), r); r.Sign() != 0 {
+			break
 		}
-		f.Mul(f, f)
-		n >>= 1
+		d, q = q, d
+	}
+	if d.Cmp(big.NewInt(1)) == 0 {
+		z.mant = z.mant.setNat(m.Bits())
+		return z.setExp2(z.mant, exp2-int64(a)+c, exp10-c)
 	}
 
-	return z
+	// m/d is not a finite decimal, hence neither exact nor a tie; restore
+	// the factors we removed from d and use Ziv's strategy.
+	d.Lsh(d.Mul(d, new(big.Int).Exp(big.NewInt(5), big.NewInt(c), nil)), a)
+	b, n, shift := Word(2), uint64(exp2), exp10
+	if exp2 < 0 {
+		b, n, shift = 5, uint64(-exp2), exp2+exp10
+	}
+	for w := uint(z.prec) + _DW; ; w *= 2 {
+		lo := new(Decimal).SetMode(ToZero).SetPrec(w).SetInt(m)
+		hi := new(Decimal).SetMode(AwayFromZero).SetPrec(w).SetInt(m)
+		lo.Quo(lo, new(Decimal).SetMode(AwayFromZero).SetPrec(w).SetInt(d))
+		hi.Quo(hi, new(Decimal).SetMode(ToZero).SetPrec(w).SetInt(d))
+		lo.neg, hi.neg = z.neg, z.neg
+		lo.setExpAndRound(lo.mulPow(lo, b, n)+shift, 0)
+		hi.setExpAndRound(hi.mulPow(hi, b, n)+shift, 0)
+		if z.setFromBounds(lo, hi) {
+			return z
+		}
+	}
 }
 
 // Parse parses s which must contain a text representation of a floating-point
@@ -199,8 +206,11 @@ func (z *Decimal) pow2(n uint64) *Decimal {
 //     digits    = digit { [ "_" ] digit } .
 //     digit     = "0" ... "9" | "a" ... "z" | "A" ... "Z" .
 //
-// The base argument must be 0, 2, 8, 10, or 16. Providing an invalid base
-// argument will lead to a run-time panic.
+// The base argument must be 0 or a value between 2 and MaxBase. Providing an
+// invalid base argument will lead to a run-time panic. For bases <= 36, lower
+// and upper case letters are considered the same: the letters 'a' to 'z' and
+// 'A' to 'Z' represent digit values 10 to 35. For bases > 36, the upper case
+// letters 'A' to 'Z' represent the digit values 36 to 61.
 //
 // For base 0, the number prefix determines the actual base: A prefix of ``0b''
 // or ``0B'' selects base 2, ``0o'' or ``0O'' selects base 8, and ``0x'' or
@@ -212,7 +222,12 @@ func (z *Decimal) pow2(n uint64) *Decimal {
 // instance, "0x1.fffffffffffffp1023" (using base 0) represents the maximum
 // float64 value. For hexadecimal mantissae, the exponent character must be one
 // of 'p' or 'P', if present (an "e" or "E" exponent indicator cannot be
-// distinguished from a mantissa digit).
+// distinguished from a mantissa digit). Likewise, there can be no "e" exponent
+// in bases > 14, and no exponent at all in bases > 25.
+//
+// Mantissae with a fractional part in a base other than 10 or a power of two
+// may not have a finite decimal representation. In all cases, the result is
+// correctly rounded.
 //
 // The returned *Decimal d is nil and the value of z is valid but not defined if
 // an error is reported.
@@ -0,0 +1,105 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/big"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestDecimalParseBase(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		base int
+		prec uint
+		want string
+		acc  Accuracy
+	}{
+		{"0.1", 3, 10, "0.3333333333", Below},
+		{"0.2", 3, 10, "0.6666666667", Above},
+		{"-0.1", 3, 10, "-0.3333333333", Above},
+		{"0.12", 3, 10, "0.5555555556", Above},
+		{"10.1", 3, 10, "3.333333333", Below},
+		{"0.1", 6, 10, "0.1666666667", Above},
+		{"0.3", 6, 10, "0.5", Exact},
+		{"0.1", 20, 10, "0.05", Exact},
+		{"0.1", 40, 10, "0.025", Exact},
+		{"0.1", 12, 10, "0.08333333333", Below},
+		{"0.09", 12, 10, "0.0625", Exact},
+		{"1.1p3", 3, 10, "10.66666667", Above},
+		{"1.1p-3", 3, 10, "0.1666666667", Above},
+		{"1.1e2", 3, 10, "133.3333333", Below},
+		{"z", 36, 10, "35", Exact},
+		{"Z", 36, 10, "35", Exact},
+		{"Z", 62, 10, "61", Exact},
+		{"0.V", 62, 10, "0.9193548387", Below},
+		{"1.8p1", 16, 10, "3", Exact},
+		{"1p-1074", 2, 20, "4.9406564584124654418e-324", Above},
+		{"1p-3321928092", 2, 10, "7.399164369e-1000000000", Above},
+	} {
+		x, _, err := ParseDecimal(test.s, test.base, test.prec, ToNearestEven)
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", test.s, test.base, err)
+			continue
+		}
+		want := makeDecimal(test.want)
+		if x.Cmp(want) != 0 || x.Acc() != test.acc {
+			t.Errorf("Parse(%q, %d) = %s (%s); want %s (%s)", test.s, test.base, x.Text('g', -1), x.Acc(), test.want, test.acc)
+		}
+	}
+}
+
+// TestDecimalParseBaseRat checks that Parse is correctly rounded by comparing
+// its result against a conversion through a big.Rat.
+func TestDecimalParseBaseRat(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	n := 2000
+	if testing.Short() {
+		n = 200
+	}
+	for i := 0; i < n; i++ {
+		base := 2 + r.Intn(MaxBase-1)
+		ip := make([]byte, r.Intn(20))
+		fp := make([]byte, r.Intn(20))
+		for j := range ip {
+			ip[j] = digits[r.Intn(base)]
+		}
+		for j := range fp {
+			fp[j] = digits[r.Intn(base)]
+		}
+		s := string(ip) + "." + string(fp)
+		if len(ip)+len(fp) == 0 {
+			s = "0"
+		}
+		m, _ := new(big.Int).SetString(string(ip)+string(fp)+"0", base)
+		m.Quo(m, big.NewInt(int64(base)))
+		q := new(big.Rat).SetFrac(m, new(big.Int).Exp(big.NewInt(int64(base)), big.NewInt(int64(len(fp))), nil))
+		if base <= 25 && r.Intn(2) == 0 {
+			e := r.Intn(400) - 200
+			s += "p" + strconv.Itoa(e)
+			p := new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(abs(e))))
+			if e < 0 {
+				q.Quo(q, p)
+			} else {
+				q.Mul(q, p)
+			}
+		}
+		for _, prec := range []uint{1, 5, 19, 34, 70} {
+			for _, mode := range floatConvModes {
+				x, _, err := ParseDecimal(s, base, prec, mode)
+				if err != nil {
+					t.Fatalf("Parse(%q, %d): %v", s, base, err)
+				}
+				want := new(Decimal).SetPrec(prec).SetMode(mode).SetRat(q)
+				if x.Cmp(want) != 0 || x.Acc() != want.Acc() {
+					t.Fatalf("Parse(%q, %d) (prec %d, %s) = %s (%s); want %s (%s)",
+						s, base, prec, mode, x.Text('g', -1), x.Acc(), want.Text('g', -1), want.Acc())
+				}
+			}
+		}
+	}
+}
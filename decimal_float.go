@@ -0,0 +1,303 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements correctly rounded conversions between Decimal and
+// big.Float values.
+//
+// A finite Decimal is an integer m scaled by 10**e = 2**e × 5**e, and a finite
+// big.Float is an integer m scaled by 2**e = 5**-e × 10**e. Conversion in
+// either direction thus amounts to multiplying m by a power of 5 or 2 in the
+// target representation, followed by a single rounding.
+//
+// When the exact result may be exactly representable (or a tie) in the target
+// precision, the power is small enough to be computed exactly and the result
+// is computed with a single correctly rounded operation. Otherwise the exact
+// result can be neither, and we use Ziv's strategy: compute a lower and upper
+// bound of the exact result using directed rounding at increasing working
+// precisions until both bounds round to the same value.
+
+package decimal
+
+import (
+	"math/big"
+)
+
+const (
+	log2_5  = log2_10 - 1
+	log10_5 = 1 - log10_2
+)
+
+// float sets z to the (possibly rounded) value of x and returns z. Rounding
+// is performed according to z's precision and rounding mode; z's precision
+// must be > 0 and x must be finite.
+func (x *Decimal) float(z *big.Float) *big.Float {
+	// 10**(x.exp-1) <= |x| < 10**x.exp. Catch values that would overflow or
+	// underflow anyway. Rounding can at most increment the binary exponent;
+	// leave some margin.
+	if float64(int64(x.exp)-1)*log2_10 > big.MaxExp+2 {
+		return floatOutOfRange(z, x.neg, true)
+	}
+	if float64(x.exp)*log2_10+2 < big.MinExp {
+		return floatOutOfRange(z, x.neg, false)
+	}
+	return x.floatExp(z, 0)
+}
+
+// floatExp sets z to the (possibly rounded) value of x × 2**shift and returns
+// z. Rounding is performed according to z's precision and rounding mode; z's
+// precision must be > 0 and x must be finite. The shift lets callers bring
+// values outside of big.Float's exponent range back into range.
+func (x *Decimal) floatExp(z *big.Float, shift int64) *big.Float {
+	// x = ±M × 10**e with M an integer
+	e := int64(x.exp) - int64(len(x.mant))*_DW
+	var mi big.Int
+	mi.SetBits(decToNat(nil, x.mant))
+	m := new(big.Float).SetInt(&mi) // exact
+	if x.neg {
+		m.Neg(m)
+	}
+	// x × 2**shift = m × 2**exp × 5**e with 0.5 <= |m| < 1
+	exp := shift + e + int64(m.MantExp(m))
+	n := uint64(e)
+	if e < 0 {
+		n = uint64(-e)
+	}
+
+	// If e >= 0, the odd part of the result has at least n×log2(5) bits.
+	// If e < 0, the result m/5**n is not a dyadic rational unless 5**n <= M.
+	// In both cases, if the result can be exact or a tie, 5**n is small
+	// enough to be computed exactly.
+	prec := z.Prec()
+	if e >= 0 && float64(n)*log2_5 <= float64(prec)+2 ||
+		e < 0 && float64(n)*log2_5 <= float64(mi.BitLen())+1 {
+		p5 := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(5), new(big.Int).SetUint64(n), nil))
+		exp5 := int64(p5.MantExp(p5))
+		// Split the scaling between both operands: neither overflows unless
+		// the result does.
+		if e < 0 {
+			exp -= exp5
+			floatScale(m, exp/2)
+			floatScale(p5, exp/2-exp)
+			return z.Quo(m, p5)
+		}
+		exp += exp5
+		floatScale(m, exp/2)
+		floatScale(p5, exp-exp/2)
+		return z.Mul(m, p5)
+	}
+
+	for w := prec + 64; ; w *= 2 {
+		p5lo := new(big.Float).SetPrec(w).SetMode(big.ToZero)
+		p5hi := new(big.Float).SetPrec(w).SetMode(big.AwayFromZero)
+		elo, ehi := floatPow5(p5lo, n), floatPow5(p5hi, n)
+		lo := new(big.Float).SetPrec(w).SetMode(big.ToZero)
+		hi := new(big.Float).SetPrec(w).SetMode(big.AwayFromZero)
+		if e < 0 {
+			lo.Quo(m, p5hi)
+			hi.Quo(m, p5lo)
+			floatScale(lo, exp-ehi)
+			floatScale(hi, exp-elo)
+		} else {
+			lo.Mul(m, p5lo)
+			hi.Mul(m, p5hi)
+			floatScale(lo, exp+elo)
+			floatScale(hi, exp+ehi)
+		}
+		if floatFromBounds(z, lo, hi) {
+			return z
+		}
+	}
+}
+
+// floatScale sets z to z × 2**exp and returns z. It differs from
+// z.SetMantExp(z, exp) in that exp may exceed the range of an int.
+func floatScale(z *big.Float, exp int64) *big.Float {
+	z.SetMantExp(z, int(exp/2))
+	return z.SetMantExp(z, int(exp-exp/2))
+}
+
+// floatFromBounds sets z to the rounded value of a number x such that
+// |lo| <= |x| <= |hi|, with lo and hi of the same sign as x, and reports
+// whether it could do so: this is only the case if both bounds round to the
+// same value. x must not be exactly representable (or a tie) in z's precision.
+func floatFromBounds(z, lo, hi *big.Float) bool {
+	if lo.IsInf() || hi.Sign() == 0 {
+		// |x| >= |lo| overflows or |x| <= |hi| underflows
+		floatOutOfRange(z, lo.Signbit(), lo.IsInf())
+		return true
+	}
+	rlo := new(big.Float).SetPrec(z.Prec()).SetMode(z.Mode()).Set(lo)
+	rhi := new(big.Float).SetPrec(z.Prec()).SetMode(z.Mode()).Set(hi)
+	if rlo.Cmp(rhi) != 0 {
+		return false
+	}
+	// accuracy of the results relative to |lo| and |hi|
+	alo, ahi := rlo.Acc(), rhi.Acc()
+	if lo.Signbit() {
+		alo, ahi = -alo, -ahi
+	}
+	// Since x is not representable, the rounded result r != x. z.Set rounds
+	// its argument to r and sets z's accuracy relative to it; pick the bound
+	// that lies on the same side of r as x.
+	switch {
+	case alo <= big.Exact:
+		// |r| <= |lo| <= |x| => |r| < |x| <= |hi|
+		z.Set(hi)
+	case ahi >= big.Exact:
+		// |r| >= |hi| >= |x| => |r| > |x| >= |lo|
+		z.Set(lo)
+	default:
+		return false
+	}
+	return true
+}
+
+// floatOutOfRange sets z to the result of an exponent overflow (±Inf) or
+// underflow (±0), with the same accuracy big.Float would report.
+func floatOutOfRange(z *big.Float, neg, overflow bool) *big.Float {
+	if overflow {
+		z.SetInt64(2) // 0.5 × 2**2
+	} else {
+		z.SetFloat64(0.25) // 0.5 × 2**-1
+	}
+	if neg {
+		z.Neg(z)
+	}
+	if overflow {
+		return z.SetMantExp(z, big.MaxExp)
+	}
+	return z.SetMantExp(z, big.MinExp)
+}
+
+// floatOdd returns x rounded to prec bits using round-to-odd: x is truncated
+// and, if the result is inexact, its least significant bit is set. A value
+// rounded to odd with at least two extra bits can be correctly rounded to its
+// final precision in any rounding mode without double rounding errors, which
+// is what we need for float32 and float64 conversions since denormals have a
+// variable precision.
+func (x *Decimal) floatOdd(prec uint) *big.Float {
+	z := x.Float(new(big.Float).SetPrec(prec).SetMode(big.ToZero))
+	if z.Acc() != big.Exact && !z.IsInf() && z.Sign() != 0 && z.MinPrec() < prec {
+		ulp := big.NewFloat(1)
+		if z.Signbit() {
+			ulp.Neg(ulp)
+		}
+		z.Add(z, ulp.SetMantExp(ulp, z.MantExp(nil)-int(prec))) // exact
+	}
+	return z
+}
+
+// setExp2 sets z to the (possibly rounded) value of m × 2**exp2 × 10**exp10 and
+// returns z.
+// Rounding is performed according to z's precision and rounding mode. The
+// sign of z must be set and m must be normalized and not zero. z.mant may
+// alias m.
+func (z *Decimal) setExp2(m dec, exp2, exp10 int64) *Decimal {
+	// m × 2**exp2 × 10**exp10 = m × b**n × 10**shift
+	b, n, shift := Word(2), uint64(exp2), exp10
+	logb := log10_2
+	if exp2 < 0 {
+		b, n, shift, logb = 5, uint64(-exp2), exp2+exp10, log10_5
+	}
+
+	// The largest power of 10 dividing m × b**n is at most 10**log2(m). If
+	// the result can be exact or a tie, that is if m × b**n might have less
+	// than z.prec + 2 significant digits, b**n is small enough to be computed
+	// exactly.
+	if (float64(n)-float64(m.digits())*log2_10)*logb <= float64(z.prec)+2 {
+		if n <= 2048 {
+			// multiply in place by powers of b that fit in a Word, so that
+			// small conversions like SetFloat64 do not allocate.
+			z.mant = z.mant.set(m)
+			for n > 0 {
+				p, i := b, uint64(1)
+				for i < n && p <= _DMax/b {
+					p *= b
+					i++
+				}
+				z.mant = z.mant.mulAddWW(z.mant, p, 0)
+				n -= i
+			}
+		} else {
+			z.mant = z.mant.mul(m, dec(nil).expWW(b, n))
+		}
+		z.setExpAndRound(int64(len(z.mant))*_DW-dnorm(z.mant)+shift, 0)
+		return z
+	}
+
+	x := &Decimal{mant: m, prec: uint32(len(m)) * _DW, form: finite, neg: z.neg}
+	x.exp = int32(int64(len(m))*_DW - dnorm(m))
+	for w := uint(z.prec) + _DW; ; w *= 2 {
+		lo := new(Decimal).SetMode(ToZero).SetPrec(w)
+		hi := new(Decimal).SetMode(AwayFromZero).SetPrec(w)
+		lo.setExpAndRound(lo.mulPow(x, b, n)+shift, 0)
+		hi.setExpAndRound(hi.mulPow(x, b, n)+shift, 0)
+		if z.setFromBounds(lo, hi) {
+			return z
+		}
+	}
+}
+
+// mulPow sets z to x × b**n × 10**-exp and returns exp, with 0.1 <= |z| < 1.
+// x must be finite and not zero. All intermediate results are rounded using
+// z's precision and rounding mode. If z's rounding mode is ToZero or
+// AwayFromZero, |z| is therefore a lower or upper bound of the exact result.
+// Since the decimal exponent is kept separately, mulPow does not overflow.
+func (z *Decimal) mulPow(x *Decimal, b Word, n uint64) (exp int64) {
+	p := new(Decimal).SetMode(z.mode).SetPrec(uint(z.prec)).SetUint64(uint64(b))
+	pexp := int64(p.exp) // b**(2**i) = p × 10**pexp
+	p.exp = 0
+	z.Set(x)
+	exp = int64(z.exp)
+	z.exp = 0
+	for {
+		if n&1 != 0 {
+			z.Mul(z, p)
+			exp += pexp + int64(z.exp)
+			z.exp = 0
+		}
+		if n >>= 1; n == 0 {
+			return exp
+		}
+		p.Mul(p, p)
+		pexp = 2*pexp + int64(p.exp)
+		p.exp = 0
+	}
+}
+
+// setFromBounds is like floatFromBounds, for Decimals. The sign of z must be
+// set.
+func (z *Decimal) setFromBounds(lo, hi *Decimal) bool {
+	if lo.form == inf || hi.form == zero {
+		// |x| >= |lo| overflows or |x| <= |hi| underflows
+		z.form = lo.form
+		z.acc = makeAcc(z.neg != (z.form == inf))
+		return true
+	}
+	rlo := new(Decimal).SetMode(z.mode).SetPrec(uint(z.prec)).Set(lo)
+	rhi := new(Decimal).SetMode(z.mode).SetPrec(uint(z.prec)).Set(hi)
+	if rlo.Cmp(rhi) != 0 {
+		return false
+	}
+	alo, ahi := rlo.acc, rhi.acc
+	if z.neg {
+		alo, ahi = -alo, -ahi
+	}
+	switch {
+	case alo <= Exact:
+		// |r| < |x|
+		z.acc = makeAcc(z.neg)
+	case ahi >= Exact:
+		// |r| > |x|
+		z.acc = makeAcc(!z.neg)
+	default:
+		return false
+	}
+	z.form = rlo.form
+	if z.form == finite {
+		z.mant = z.mant.set(rlo.mant)
+		z.exp = rlo.exp
+	}
+	return true
+}
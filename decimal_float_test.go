@@ -0,0 +1,217 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var floatConvModes = [...]RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf}
+
+// rndDecimal returns a random Decimal with precision prec, prec significant
+// digits and a decimal exponent in [-exp, exp].
+func rndDecimal(r *rand.Rand, prec uint, exp int) *Decimal {
+	b := make([]byte, prec)
+	for i := range b {
+		b[i] = byte('0' + r.Intn(10))
+	}
+	b[0] = byte('1' + r.Intn(9))
+	m, _ := new(big.Int).SetString(string(b), 10)
+	if r.Intn(2) == 0 {
+		m.Neg(m)
+	}
+	x := new(Decimal).SetPrec(prec).SetInt(m)
+	return x.SetMantExp(x, r.Intn(2*exp+1)-exp)
+}
+
+// rndFloat returns a random big.Float with up to prec bits and a binary
+// exponent in [-exp, exp].
+func rndFloat(r *rand.Rand, prec, exp int) *big.Float {
+	p := 1 + r.Intn(prec)
+	m := new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), uint(p)))
+	m.SetBit(m, p, 1)
+	if r.Intn(2) == 0 {
+		m.Neg(m)
+	}
+	f := new(big.Float).SetPrec(uint(p + 1)).SetInt(m)
+	return f.SetMantExp(f, r.Intn(2*exp+1)-exp)
+}
+
+// TestDecimalFloatRat checks that Decimal -> big.Float conversions are
+// correctly rounded by comparing them against an exact conversion through a
+// big.Rat.
+func TestDecimalFloatRat(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	n := 500
+	if testing.Short() {
+		n = 50
+	}
+	for i := 0; i < n; i++ {
+		x := rndDecimal(r, uint(1+r.Intn(60)), 400)
+		q, _ := x.Rat(nil)
+		for _, prec := range []uint{1, 2, 10, 24, 53, 64, 113, 200, 500} {
+			for _, mode := range floatConvModes {
+				want := new(big.Float).SetPrec(prec).SetMode(big.RoundingMode(mode)).SetRat(q)
+				got := x.Float(new(big.Float).SetPrec(prec).SetMode(big.RoundingMode(mode)))
+				if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+					t.Fatalf("%s.Float(prec %d, %s) = %s (%s); want %s (%s)",
+						x.Text('g', -1), prec, mode, got.Text('p', 0), got.Acc(), want.Text('p', 0), want.Acc())
+				}
+			}
+		}
+	}
+}
+
+// TestDecimalSetFloatRat checks that big.Float -> Decimal conversions are
+// correctly rounded by comparing them against an exact conversion through a
+// big.Rat.
+func TestDecimalSetFloatRat(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	n := 500
+	if testing.Short() {
+		n = 50
+	}
+	for i := 0; i < n; i++ {
+		f := rndFloat(r, 200, 1200)
+		q, _ := f.Rat(nil)
+		for _, prec := range []uint{1, 2, 9, 16, 19, 34, 50, 100, 300} {
+			for _, mode := range floatConvModes {
+				want := new(Decimal).SetPrec(prec).SetMode(mode).SetRat(q)
+				got := new(Decimal).SetPrec(prec).SetMode(mode).SetFloat(f)
+				if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+					t.Fatalf("SetFloat(%s) (prec %d, %s) = %s (%s); want %s (%s)",
+						f.Text('p', 0), prec, mode, got.Text('p', 0), got.Acc(), want.Text('p', 0), want.Acc())
+				}
+			}
+		}
+	}
+}
+
+// TestDecimalFloatTies checks conversions of values that are exactly
+// representable or ties in the target precision.
+func TestDecimalFloatTies(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		// f has exactly prec+1 bits: it is a tie at prec bits if its lsb is
+		// set.
+		prec := uint(1 + r.Intn(100))
+		f := rndFloat(r, 1, 300)
+		f.SetPrec(prec + 1)
+		m := new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), prec+1))
+		m.SetBit(m, int(prec), 1)
+		f.SetMantExp(f.SetInt(m), r.Intn(600)-300)
+		x := new(Decimal).SetPrec(1000).SetFloat(f)
+		if x.Acc() != Exact {
+			t.Fatalf("SetFloat(%s) is not exact", f.Text('p', 0))
+		}
+		q, _ := f.Rat(nil)
+		for _, p := range []uint{prec, prec + 1} {
+			for _, mode := range floatConvModes {
+				want := new(big.Float).SetPrec(p).SetMode(big.RoundingMode(mode)).SetRat(q)
+				got := x.Float(new(big.Float).SetPrec(p).SetMode(big.RoundingMode(mode)))
+				if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+					t.Fatalf("%s.Float(prec %d, %s) = %s (%s); want %s (%s)",
+						f.Text('p', 0), p, mode, got.Text('p', 0), got.Acc(), want.Text('p', 0), want.Acc())
+				}
+			}
+		}
+	}
+	// decimal ties
+	for i := 0; i < 200; i++ {
+		x := rndDecimal(r, uint(1+r.Intn(30)), 20)
+		prec := uint(x.MinPrec())
+		f := x.Float(new(big.Float).SetPrec(4000))
+		if f.Acc() != big.Exact {
+			continue // not a dyadic rational
+		}
+		q, _ := x.Rat(nil)
+		for _, p := range []uint{prec - 1, prec} {
+			if p == 0 {
+				continue
+			}
+			for _, mode := range floatConvModes {
+				want := new(Decimal).SetPrec(p).SetMode(mode).SetRat(q)
+				got := new(Decimal).SetPrec(p).SetMode(mode).SetFloat(f)
+				if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+					t.Fatalf("SetFloat(%s) (prec %d, %s) = %s (%s); want %s (%s)",
+						f.Text('p', 0), p, mode, got.Text('p', 0), got.Acc(), want.Text('p', 0), want.Acc())
+				}
+			}
+		}
+	}
+}
+
+// TestDecimalFloatHugeExp checks conversions of values whose exponents make an
+// exact conversion impractical.
+func TestDecimalFloatHugeExp(t *testing.T) {
+	for _, test := range []struct {
+		x   string
+		acc big.Accuracy
+		inf bool
+	}{
+		{"1e600000000", big.Below, false},
+		{"-1e600000000", big.Above, false},
+		{"1.5e-600000000", big.Below, false},
+		{"1e700000000", big.Above, true},
+		{"-1e700000000", big.Below, true},
+		{"1e-700000000", big.Below, false},
+		{"-1e-700000000", big.Above, false},
+	} {
+		x := makeDecimal(test.x)
+		lo := x.Float(new(big.Float).SetPrec(100).SetMode(big.ToNegativeInf))
+		hi := x.Float(new(big.Float).SetPrec(100).SetMode(big.ToPositiveInf))
+		got := x.Float(new(big.Float).SetPrec(100).SetMode(big.ToNearestEven))
+		if got.IsInf() != test.inf {
+			t.Errorf("%s.Float() = %s; want Inf == %v", test.x, got.Text('g', 10), test.inf)
+			continue
+		}
+		if got.Acc() != test.acc && (test.inf || got.Sign() == 0) {
+			t.Errorf("%s.Float() accuracy = %s; want %s", test.x, got.Acc(), test.acc)
+		}
+		if test.inf || got.Sign() == 0 {
+			continue
+		}
+		// lo and hi must be adjacent and bracket x
+		if lo.Acc() != big.Below || hi.Acc() != big.Above || lo.Cmp(hi) >= 0 {
+			t.Errorf("%s: bad bounds %s (%s), %s (%s)", test.x, lo.Text('g', 30), lo.Acc(), hi.Text('g', 30), hi.Acc())
+		}
+		if got.Cmp(lo) != 0 && got.Cmp(hi) != 0 {
+			t.Errorf("%s: %s not in {%s, %s}", test.x, got.Text('g', 30), lo.Text('g', 30), hi.Text('g', 30))
+		}
+		// round trip
+		y := new(Decimal).SetPrec(40).SetFloat(got)
+		if g2 := y.Float(new(big.Float).SetPrec(100)); g2.Cmp(got) != 0 {
+			t.Errorf("%s: round trip failed: got %s, want %s", test.x, g2.Text('g', 40), got.Text('g', 40))
+		}
+	}
+
+	// big.Float -> Decimal
+	for _, exp := range []int{1 << 30, -1 << 30, 1<<31 - 10, -1<<31 + 10} {
+		f := new(big.Float).SetMantExp(big.NewFloat(0.75), exp)
+		lo := new(Decimal).SetPrec(50).SetMode(ToNegativeInf).SetFloat(f)
+		hi := new(Decimal).SetPrec(50).SetMode(ToPositiveInf).SetFloat(f)
+		if lo.Acc() != Below || hi.Acc() != Above || lo.Cmp(hi) >= 0 {
+			t.Errorf("0.75p%d: bad bounds %s (%s), %s (%s)", exp, lo.Text('g', 50), lo.Acc(), hi.Text('g', 50), hi.Acc())
+		}
+		// lo and hi are adjacent
+		d := new(Decimal).Sub(hi, lo)
+		if d.MantExp(nil) != lo.MantExp(nil)-49 && d.MantExp(nil) != lo.MantExp(nil)-50 {
+			t.Errorf("0.75p%d: bounds not adjacent: %s, %s", exp, lo.Text('g', 50), hi.Text('g', 50))
+		}
+		if g := lo.Float(new(big.Float).SetPrec(2)); g.Cmp(f) != 0 {
+			t.Errorf("0.75p%d: round trip failed: got %s", exp, g.Text('p', 0))
+		}
+	}
+}
+
+func BenchmarkDecimalSetFloat(b *testing.B) {
+	f, _ := new(big.Float).SetPrec(200).SetString("1.2345678901234567890123456789e-300")
+	z := new(Decimal).SetPrec(50)
+	for i := 0; i < b.N; i++ {
+		z.SetFloat(f)
+	}
+}
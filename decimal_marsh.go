@@ -11,8 +11,13 @@ import (
 	"fmt"
 )
 
-// Gob codec version. Permits backward-compatible changes to the encoding.
-const decimalGobVersion byte = 1
+// Gob codec versions. Permits backward-compatible changes to the encoding.
+// Version 2 stores the rounding mode in an extra byte. It is only used for
+// rounding modes that do not fit in the 3 bits reserved by version 1.
+const (
+	decimalGobVersion  byte = 1
+	decimalGobVersion2 byte = 2
+)
 
 // GobEncode implements the gob.GobEncoder interface.
 // The Decimal value and all its attributes (precision,
@@ -39,19 +44,31 @@ func (x *Decimal) GobEncode() ([]byte, error) {
 		// len(x.mant) >= n
 		sz += 4 + n*_S // exp + mant
 	}
+	v := decimalGobVersion
+	if x.mode > 7 {
+		v = decimalGobVersion2
+		sz++
+	}
 	buf := make([]byte, sz)
 
-	buf[0] = decimalGobVersion
-	b := byte(x.mode&7)<<5 | byte((x.acc+1)&3)<<3 | byte(x.form&3)<<1
+	buf[0] = v
+	b := byte((x.acc+1)&3)<<3 | byte(x.form&3)<<1
 	if x.neg {
 		b |= 1
 	}
-	buf[1] = b
-	binary.BigEndian.PutUint32(buf[2:], x.prec)
+	p := buf[1:]
+	if v == decimalGobVersion2 {
+		p[0] = byte(x.mode)
+		p = p[1:]
+	} else {
+		b |= byte(x.mode&7) << 5
+	}
+	p[0] = b
+	binary.BigEndian.PutUint32(p[1:], x.prec)
 
 	if x.form == finite {
-		binary.BigEndian.PutUint32(buf[6:], uint32(x.exp))
-		x.mant[len(x.mant)-n:].bytes(buf[10:]) // cut off unused trailing words
+		binary.BigEndian.PutUint32(p[5:], uint32(x.exp))
+		x.mant[len(x.mant)-n:].bytes(p[9:]) // cut off unused trailing words
 	}
 
 	return buf, nil
@@ -68,23 +85,29 @@ func (z *Decimal) GobDecode(buf []byte) error {
 		return nil
 	}
 
-	if buf[0] != decimalGobVersion {
+	if buf[0] != decimalGobVersion && buf[0] != decimalGobVersion2 {
 		return fmt.Errorf("Decimal.GobDecode: encoding version %d not supported", buf[0])
 	}
 
 	oldPrec := z.prec
 	oldMode := z.mode
 
-	b := buf[1]
+	p := buf[1:]
+	b := p[0]
 	z.mode = RoundingMode((b >> 5) & 7)
+	if buf[0] == decimalGobVersion2 {
+		z.mode = RoundingMode(p[0])
+		p = p[1:]
+		b = p[0]
+	}
 	z.acc = Accuracy((b>>3)&3) - 1
 	z.form = form((b >> 1) & 3)
 	z.neg = b&1 != 0
-	z.prec = binary.BigEndian.Uint32(buf[2:])
+	z.prec = binary.BigEndian.Uint32(p[1:])
 
 	if z.form == finite {
-		z.exp = int32(binary.BigEndian.Uint32(buf[6:]))
-		z.mant = z.mant.setBytes(buf[10:])
+		z.exp = int32(binary.BigEndian.Uint32(p[5:]))
+		z.mant = z.mant.setBytes(p[9:])
 	}
 
 	if oldPrec != 0 {
@@ -33,7 +33,7 @@ func TestDecimalGobEncoding(t *testing.T) {
 	for _, test := range floatVals {
 		for _, sign := range []string{"", "+", "-"} {
 			for _, prec := range []uint{0, 1, 2, 10, 53, 64, 100, 1000} {
-				for _, mode := range []RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf} {
+				for _, mode := range []RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf, ToNearestTowardZero, Round05Up, ToOdd} {
 					medium.Reset() // empty buffer for each test case (in case of failures)
 					x := sign + test
 
@@ -137,3 +137,21 @@ func TestDecimalJSONEncoding(t *testing.T) {
 		}
 	}
 }
+
+// TestDecimalGobVersion checks that the version 1 encoding is used whenever
+// the rounding mode fits in it.
+func TestDecimalGobVersion(t *testing.T) {
+	for mode := ToNearestEven; mode <= ToOdd; mode++ {
+		b, err := new(Decimal).SetMode(mode).SetInt64(42).GobEncode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := decimalGobVersion
+		if mode > 7 {
+			want = decimalGobVersion2
+		}
+		if b[0] != want {
+			t.Errorf("%s: got version %d, want %d", mode, b[0], want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// This file implements IEEE-754 nextUp and nextDown for Decimals. Decimals have
+// no subnormal numbers: the finite positive Decimals with precision p range from
+// 0.1×10**MinExp to 0.99…9×10**MaxExp (p nines).
+
+// NextUp sets z to the smallest Decimal with z's precision that is greater than
+// x, and returns z. The result is -0 for the negative number closest to zero,
+// the smallest positive Decimal 0.1×10**MinExp for ±0, the largest negative
+// Decimal for -Inf and +Inf for +Inf or the largest positive Decimal. z's
+// accuracy is Above, or Exact if x is +Inf.
+//
+// If z's precision is 0, it is changed to x's precision, or to
+// DefaultDecimalPrec if x's precision is 0 as well.
+func (z *Decimal) NextUp(x *Decimal) *Decimal {
+	return z.next(x, false)
+}
+
+// NextDown sets z to the largest Decimal with z's precision that is less than
+// x, and returns z. NextDown is the mirror image of NextUp: z.NextDown(x) is
+// -(z.NextUp(-x)).
+func (z *Decimal) NextDown(x *Decimal) *Decimal {
+	return z.next(x, true)
+}
+
+// NextToward sets z to the Decimal with z's precision that is adjacent to x in
+// the direction of y, and returns z. If x == y, NextToward is like z.Set(y).
+// See NextUp for details.
+func (z *Decimal) NextToward(x, y *Decimal) *Decimal {
+	switch x.Cmp(y) {
+	case -1:
+		return z.NextUp(x)
+	case 1:
+		return z.NextDown(x)
+	}
+	return z.Set(y)
+}
+
+// Ulp sets z to the unit in the last place of x at x's precision: the value of
+// the least significant digit of x's mantissa, and returns z. The result is
+// always positive: +Inf if x is ±Inf and the smallest positive Decimal
+// 0.1×10**MinExp if x is ±0. If the ulp of x underflows, z is set to +0 and
+// its accuracy is Below.
+//
+// For finite x, the ulp of x is the distance between |x| and the next Decimal
+// of greater magnitude.
+//
+// If z's precision is 0, it is changed to x's precision, or to
+// DefaultDecimalPrec if x's precision is 0 as well.
+func (z *Decimal) Ulp(x *Decimal) *Decimal {
+	prec := z.prec
+	if prec == 0 {
+		prec = nextPrec(x)
+	}
+	z.prec = prec
+	z.acc = Exact
+	z.neg = false
+	switch x.form {
+	case inf:
+		z.form = inf
+		return z
+	case zero:
+		z.setTiny()
+		return z
+	}
+	exp := int64(x.exp) - int64(x.prec) + 1 // ulp is 0.1×10**exp
+	if exp < MinExp {
+		z.acc = Below
+		z.form = zero
+		return z
+	}
+	z.form = finite
+	z.mant = z.mant.setWord(_DB / 10)
+	z.exp = int32(exp)
+	return z
+}
+
+// nextPrec returns x's precision, or DefaultDecimalPrec if it is 0.
+func nextPrec(x *Decimal) uint32 {
+	if x.prec == 0 {
+		return DefaultDecimalPrec
+	}
+	return x.prec
+}
+
+// setTiny sets z's absolute value to the smallest positive Decimal.
+func (z *Decimal) setTiny() {
+	z.form = finite
+	z.mant = z.mant.setWord(_DB / 10)
+	z.exp = MinExp
+}
+
+// next sets z to the Decimal adjacent to x, toward -Inf if down is set, toward
+// +Inf otherwise.
+func (z *Decimal) next(x *Decimal, down bool) *Decimal {
+	prec, mode := z.prec, z.mode
+	if prec == 0 {
+		prec = nextPrec(x)
+	}
+	z.Copy(x)
+	z.prec, z.mode = prec, mode
+	z.acc = makeAcc(!down)
+
+	switch x.form {
+	case zero:
+		z.neg = down
+		z.setTiny()
+		return z
+	case inf:
+		if x.neg == down {
+			// +Inf up or -Inf down
+			z.acc = Exact
+			return z
+		}
+		// largest Decimal: p nines
+		z.form = finite
+		n := (z.prec + (_DW - 1)) / _DW
+		z.mant = z.mant.make(int(n))
+		z.setNines()
+		z.exp = MaxExp
+		return z
+	}
+
+	// Try rounding x toward the target first: if x has more than prec digits,
+	// the result is the adjacent Decimal.
+	if down {
+		z.mode = ToNegativeInf
+	} else {
+		z.mode = ToPositiveInf
+	}
+	z.round(0)
+	z.mode = mode
+	if z.acc != Exact {
+		// z.acc is Above (resp. Below) and z is the Decimal adjacent to x, or
+		// ±Inf on overflow.
+		return z
+	}
+	z.acc = makeAcc(!down)
+
+	// x fits in prec digits: add or subtract one ulp.
+	n := int((z.prec + (_DW - 1)) / _DW) // mantissa length in words
+	if m := len(z.mant); m < n {
+		// extend mantissa
+		z.mant = append(z.mant, make(dec, n-m)...)
+		copy(z.mant[n-m:], z.mant[:m])
+		for i := range z.mant[:n-m] {
+			z.mant[i] = 0
+		}
+	}
+	lsd := Word(pow10(uint(n*_DW) - uint(z.prec)))
+	if z.neg != down {
+		// decrease magnitude
+		sub10VW(z.mant, z.mant, lsd)
+		if z.mant[n-1] < _DB/10 {
+			// x was ±0.1×10**exp: the result is ±0.99…9×10**(exp-1).
+			if z.exp == MinExp {
+				z.form = zero
+				return z
+			}
+			z.setNines()
+			z.exp--
+		}
+		return z
+	}
+	// increase magnitude
+	if add10VW(z.mant, z.mant, lsd) != 0 {
+		// x was ±0.99…9×10**exp
+		if z.exp >= MaxExp {
+			z.form = inf
+			return z
+		}
+		z.exp++
+		z.mant[n-1] = _DB / 10
+	}
+	return z
+}
+
+// setNines sets the mantissa of z to all nines, up to z's precision.
+func (z *Decimal) setNines() {
+	for i := range z.mant {
+		z.mant[i] = _DB - 1
+	}
+	lsd := Word(pow10(uint(len(z.mant))*_DW - uint(z.prec)))
+	z.mant[0] -= z.mant[0] % lsd
+}
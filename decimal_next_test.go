@@ -0,0 +1,132 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// makeExp returns x × 10**exp with x's precision.
+func makeExp(x string, exp int) *Decimal {
+	d := makeDecimal(x)
+	return d.SetPrec(d.MinPrec()).SetMantExp(d, exp)
+}
+
+func TestDecimalNextUpDown(t *testing.T) {
+	var (
+		tiny    = makeExp("0.1", MinExp)
+		largest = makeExp("0.999", MaxExp)
+		inf     = makeDecimal("+Inf")
+	)
+	for _, test := range []struct {
+		x        *Decimal
+		prec     uint
+		up, down *Decimal
+	}{
+		{makeDecimal("1"), 3, makeDecimal("1.01"), makeDecimal("0.999")},
+		{makeDecimal("-1"), 3, makeDecimal("-0.999"), makeDecimal("-1.01")},
+		{makeDecimal("1.23"), 3, makeDecimal("1.24"), makeDecimal("1.22")},
+		{makeDecimal("9.99"), 3, makeDecimal("10"), makeDecimal("9.98")},
+		{makeDecimal("-9.99"), 3, makeDecimal("-9.98"), makeDecimal("-10")},
+		{makeDecimal("1.234"), 3, makeDecimal("1.24"), makeDecimal("1.23")},
+		{makeDecimal("-1.234"), 3, makeDecimal("-1.23"), makeDecimal("-1.24")},
+		{makeDecimal("9.995"), 3, makeDecimal("10"), makeDecimal("9.99")},
+		{makeDecimal("1"), 40, makeDecimal("1.000000000000000000000000000000000000001"), makeDecimal("0.9999999999999999999999999999999999999999")},
+		{makeDecimal("123456789012345678901234567890"), 30, makeDecimal("123456789012345678901234567891"), makeDecimal("123456789012345678901234567889")},
+		{makeDecimal("0"), 3, tiny, new(Decimal).Neg(tiny)},
+		{makeDecimal("-0"), 3, tiny, new(Decimal).Neg(tiny)},
+		{tiny, 3, makeExp("0.101", MinExp), makeDecimal("0")},
+		{new(Decimal).Neg(tiny), 3, makeDecimal("-0"), makeExp("-0.101", MinExp)},
+		{makeExp("0.1", MinExp+1), 3, makeExp("0.101", MinExp+1), makeExp("0.999", MinExp)},
+		{largest, 3, inf, makeExp("0.998", MaxExp)},
+		{new(Decimal).Neg(largest), 3, makeExp("-0.998", MaxExp), new(Decimal).Neg(inf)},
+		{makeExp("0.9995", MaxExp), 3, inf, largest},
+		{inf, 3, inf, largest},
+		{new(Decimal).Neg(inf), 3, new(Decimal).Neg(largest), new(Decimal).Neg(inf)},
+	} {
+		up := new(Decimal).SetPrec(test.prec).NextUp(test.x)
+		if up.Cmp(test.up) != 0 || up.Signbit() != test.up.Signbit() || up.Prec() != test.prec {
+			t.Errorf("NextUp(%s) = %s (prec %d); want %s", test.x, up, up.Prec(), test.up)
+		}
+		if wantAcc := Above; up.Acc() != wantAcc && !(test.x.IsInf() && test.x.Sign() > 0) {
+			t.Errorf("NextUp(%s) accuracy = %s; want %s", test.x, up.Acc(), wantAcc)
+		}
+		down := new(Decimal).SetPrec(test.prec).NextDown(test.x)
+		if down.Cmp(test.down) != 0 || down.Signbit() != test.down.Signbit() || down.Prec() != test.prec {
+			t.Errorf("NextDown(%s) = %s (prec %d); want %s", test.x, down, down.Prec(), test.down)
+		}
+		if wantAcc := Below; down.Acc() != wantAcc && !(test.x.IsInf() && test.x.Sign() < 0) {
+			t.Errorf("NextDown(%s) accuracy = %s; want %s", test.x, down.Acc(), wantAcc)
+		}
+		// NextToward
+		if z := new(Decimal).SetPrec(test.prec).NextToward(test.x, inf); z.Cmp(up) != 0 && !test.x.IsInf() {
+			t.Errorf("NextToward(%s, +Inf) = %s; want %s", test.x, z, up)
+		}
+		if z := new(Decimal).SetPrec(test.prec).NextToward(test.x, new(Decimal).Neg(inf)); z.Cmp(down) != 0 && !test.x.IsInf() {
+			t.Errorf("NextToward(%s, -Inf) = %s; want %s", test.x, z, down)
+		}
+	}
+
+	// z's precision 0 and aliasing
+	x := new(Decimal).SetPrec(5).SetInt64(1)
+	if z := new(Decimal).NextUp(x); z.Prec() != 5 || z.Cmp(makeDecimal("1.0001")) != 0 {
+		t.Errorf("NextUp(1) = %s (prec %d); want 1.0001 (prec 5)", z, z.Prec())
+	}
+	if z := new(Decimal).NextUp(new(Decimal)); z.Prec() != DefaultDecimalPrec || z.Cmp(tiny) != 0 {
+		t.Errorf("NextUp(0) = %s (prec %d)", z, z.Prec())
+	}
+	x.NextDown(x)
+	if x.Cmp(makeDecimal("0.99999")) != 0 {
+		t.Errorf("x.NextDown(x) = %s; want 0.99999", x)
+	}
+
+	// NextToward(x, x)
+	y := makeDecimal("-1.23456")
+	if z := new(Decimal).SetPrec(3).SetMode(ToZero).NextToward(y, y); z.Cmp(makeDecimal("-1.23")) != 0 {
+		t.Errorf("NextToward(y, y) = %s; want -1.23", z)
+	}
+}
+
+func TestDecimalUlp(t *testing.T) {
+	for _, test := range []struct {
+		x    *Decimal
+		want *Decimal
+		acc  Accuracy
+	}{
+		{new(Decimal).SetPrec(3).SetInt64(1), makeDecimal("0.01"), Exact},
+		{new(Decimal).SetPrec(3).SetFloat64(-0.0123), makeDecimal("0.0001"), Exact},
+		{new(Decimal).SetPrec(1).SetInt64(-5), makeDecimal("1"), Exact},
+		{new(Decimal).SetPrec(30).SetInt64(1e18), makeDecimal("1e-11"), Exact},
+		{makeDecimal("0"), makeExp("0.1", MinExp), Exact},
+		{makeDecimal("-Inf"), makeDecimal("+Inf"), Exact},
+		{makeExp("0.1", MinExp+2).SetPrec(3), makeExp("0.1", MinExp), Exact},
+		{makeExp("0.1", MinExp+1).SetPrec(3), makeDecimal("0"), Below},
+	} {
+		z := new(Decimal).Ulp(test.x)
+		if z.Cmp(test.want) != 0 || z.Signbit() || z.Acc() != test.acc {
+			t.Errorf("Ulp(%s) = %s (%s); want %s (%s)", test.x, z, z.Acc(), test.want, test.acc)
+		}
+	}
+
+	// NextDown(NextUp(x)) == x, and NextUp(x) - x == Ulp(x) for x > 0
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		prec := uint(1 + r.Intn(60))
+		x := new(Decimal).SetPrec(prec).SetInt64(r.Int63() - r.Int63())
+		x.SetMantExp(x, r.Intn(200)-100)
+		up := new(Decimal).NextUp(x)
+		if new(Decimal).NextDown(up).Cmp(x) != 0 {
+			t.Fatalf("NextDown(NextUp(%s)) != %s", x, x)
+		}
+		if x.Sign() < 0 {
+			continue
+		}
+		d := new(Decimal).SetPrec(prec+1).Sub(up, x)
+		if ulp := new(Decimal).Ulp(x); d.Cmp(ulp) != 0 {
+			t.Fatalf("NextUp(%s) - %s = %s; Ulp = %s", x, x, d, ulp)
+		}
+	}
+}
@@ -27,6 +27,11 @@ var (
 // The function panics if z < 0. The value of z is undefined in that
 // case.
 func (z *Decimal) Sqrt(x *Decimal) *Decimal {
+	return z.sqrt(nil, x)
+}
+
+// sqrt is like Sqrt, but takes its temporaries from w.
+func (z *Decimal) sqrt(w *Workspace, x *Decimal) *Decimal {
 	if debugDecimal {
 		x.validate()
 	}
@@ -73,7 +78,7 @@ func (z *Decimal) Sqrt(x *Decimal) *Decimal {
 	// very small precisions (<_DW/2).
 	//
 	// Solve 1/x² - z = 0 instead.
-	z.sqrtInverse(z)
+	z.sqrtInverse(w, z)
 
 	// restore precision and re-attach halved exponent
 	return z.SetMantExp(z, b/2)
@@ -82,7 +87,7 @@ func (z *Decimal) Sqrt(x *Decimal) *Decimal {
 // Compute √x (to z.prec precision) by solving
 //   1/t² - x = 0
 // for t (using Newton's method), and then inverting.
-func (z *Decimal) sqrtInverse(x *Decimal) {
+func (z *Decimal) sqrtInverse(w *Workspace, x *Decimal) {
 	if debugDecimal {
 		if oneHalf.acc != Exact {
 			panic(fmt.Sprintf("oneHalf is inexact (%v): %g", oneHalf.acc, oneHalf))
@@ -99,7 +104,8 @@ func (z *Decimal) sqrtInverse(x *Decimal) {
 	// Compute initial guess for 1/√x
 	// xf needs only be "close enough", use a fast Decimal->Float64 conversion
 	xf := float64(x.mant[len(x.mant)-1]/10) / float64(pow10(uint(_DW-1-x.exp)))
-	t := newDecimal(z.prec).SetFloat64(1 / math.Sqrt(xf))
+	t, u, v := w.sqrtTemps(z.prec)
+	t.SetFloat64(1 / math.Sqrt(xf))
 	// t.prec = min(_DW, 17)
 	if _W == 32 {
 		t.prec = _DW
@@ -112,24 +118,22 @@ func (z *Decimal) sqrtInverse(x *Decimal) {
 	//   g(t) = f(t)/f'(t) = -½t(1 - xt²)
 	// and the next guess is given by
 	//   t2 = t - g(t) = ½t(3 - xt²)
-	u := newDecimal(z.prec)
-	v := newDecimal(z.prec)
 	for prec := z.prec + 2; t.prec < prec; {
 		// be more conservative than big.Float in precision increase
 		// |√z - t| < 10**(-2*t.prec + 2) <= 10**-prec
 		t.prec = t.prec*2 - 2
 		u.prec = t.prec
 		v.prec = t.prec
-		u.Mul(t, t)       // u = t²
-		u.Mul(x, u)       //   = x.t²
-		v.Sub(three, u)   // v = 3 - x.t²
-		u.Mul(t, v)       // u = t(3 - x.t²)
-		t.Mul(u, oneHalf) // t = ½t(3 - x.t²)
+		u.mul(w, t, t)       // u = t²
+		u.mul(w, x, u)       //   = x.t²
+		v.sub(w, three, u)   // v = 3 - x.t²
+		u.mul(w, t, v)       // u = t(3 - x.t²)
+		t.mul(w, u, oneHalf) // t = ½t(3 - x.t²)
 	}
 	// t = 1/√x
 
 	// x/√x = √x
-	z.Mul(z, t)
+	z.mul(w, z, t)
 }
 
 // newDecimal returns a new *Decimal with space for twice the given
@@ -148,8 +148,10 @@ func TestDecimalZeroValue(t *testing.T) {
 	// TODO(db47h) test how precision is set for zero value results
 }
 
+// makeDecimal parses s with enough precision to represent the test values
+// with binary exponents exactly.
 func makeDecimal(s string) *Decimal {
-	x, _, err := ParseDecimal(s, 0, 350, ToNearestEven)
+	x, _, err := ParseDecimal(s, 0, 1000, ToNearestEven)
 	if err != nil {
 		panic(err)
 	}
@@ -334,6 +336,113 @@ func TestDecimalSetMantExp(t *testing.T) {
 	}
 }
 
+func TestDecimalLogb(t *testing.T) {
+	for _, test := range []struct {
+		x    string
+		want string
+	}{
+		{"0", "-Inf"},
+		{"-0", "-Inf"},
+		{"+Inf", "+Inf"},
+		{"-Inf", "+Inf"},
+		{"1", "0"},
+		{"9.99", "0"},
+		{"-10", "1"},
+		{"0.1", "-1"},
+		{"0.0999", "-2"},
+		{"1234.5", "3"},
+		{"1e2147483646", "2147483646"},
+		{"1e-2147483649", "-2147483649"},
+	} {
+		x := makeDecimal(test.x)
+		var z Decimal
+		z.Logb(x)
+		if want := makeDecimal(test.want); !alike(&z, want) || z.Acc() != Exact || z.Prec() != DefaultDecimalPrec {
+			t.Errorf("Logb(%s) = %s (%s, prec %d); want %s", test.x, &z, z.Acc(), z.Prec(), test.want)
+		}
+		if x.Sign() != 0 && !x.IsInf() {
+			// 1 <= |x| × 10**-Logb(x) < 10. -Logb(x) may overflow an int on
+			// 32-bit platforms: scale in two steps.
+			e, _ := z.Int64()
+			m := new(Decimal).Abs(x)
+			m.SetMantExp(m, -int(e/2))
+			m.SetMantExp(m, -int(e-e/2))
+			if m.Cmp(makeDecimal("1")) < 0 || m.Cmp(makeDecimal("10")) >= 0 {
+				t.Errorf("%s × 10**-Logb(%s) = %s", test.x, test.x, m)
+			}
+		}
+	}
+	// rounding
+	z := new(Decimal).SetPrec(2).SetMode(ToZero).Logb(makeDecimal("1e1234"))
+	if z.Cmp(makeDecimal("1200")) != 0 || z.Acc() != Below {
+		t.Errorf("Logb(1e1234) = %s (%s); want 1200 (Below)", z, z.Acc())
+	}
+}
+
+func TestDecimalScaleb(t *testing.T) {
+	for _, test := range []struct {
+		x    string
+		prec uint
+		n    int
+		want string
+		acc  Accuracy
+	}{
+		{"0", 0, 10, "0", Exact},
+		{"-0", 0, MaxExp, "-0", Exact},
+		{"+Inf", 0, MinExp, "+Inf", Exact},
+		{"-Inf", 0, -1234, "-Inf", Exact},
+		{"1.5", 0, 2, "150", Exact},
+		{"-1.5", 0, -3, "-0.0015", Exact},
+		{"123.456", 0, -2, "1.23456", Exact},
+		{"123.456", 4, 3, "123500", Above},
+		{"-123.456", 4, 3, "-123500", Below},
+		{"1", 0, MaxExp - 1, "1e2147483646", Exact},
+		{"10", 0, MaxExp - 1, "+Inf", Above},  // overflow
+		{"-10", 0, MaxExp - 1, "-Inf", Below}, // overflow
+		{"0.01", 0, MinExp, "+0", Below},      // underflow
+		{"-0.01", 0, MinExp, "-0", Above},     // underflow
+		{"1e-2147483649", 0, 0, "1e-2147483649", Exact},
+		{"1e-2147483649", 0, -1, "0", Below}, // underflow
+	} {
+		x := makeDecimal(test.x)
+		z := new(Decimal).SetPrec(test.prec).Scaleb(x, test.n)
+		if want := makeDecimal(test.want); !alike(z, want) || z.Acc() != test.acc {
+			t.Errorf("Scaleb(%s, %d) = %s (%s); want %s (%s)", test.x, test.n, z, z.Acc(), test.want, test.acc)
+		}
+		if test.prec == 0 && z.Prec() != x.Prec() {
+			t.Errorf("Scaleb(%s, %d): got prec %d; want %d", test.x, test.n, z.Prec(), x.Prec())
+		}
+		// aliasing
+		z = new(Decimal).SetPrec(test.prec).Set(x)
+		if z.Scaleb(z, test.n); test.acc == Exact && z.Cmp(makeDecimal(test.want)) != 0 {
+			t.Errorf("z = %s; z.Scaleb(z, %d) = %s; want %s", test.x, test.n, z, test.want)
+		}
+	}
+}
+
+func TestDecimalDigits(t *testing.T) {
+	for _, test := range []struct {
+		x      string
+		prec   uint
+		digits uint
+		tz     uint
+	}{
+		{"0", 10, 0, 0},
+		{"-Inf", 10, 0, 0},
+		{"1", 10, 1, 9},
+		{"-1200", 10, 2, 8},
+		{"0.00123", 3, 3, 0},
+		{"1.5", 40, 2, 38},
+		{"12345678901234567890123", 23, 23, 0},
+		{"1234567890123456789012300000", 40, 23, 17},
+	} {
+		x := new(Decimal).SetPrec(test.prec).Set(makeDecimal(test.x))
+		if d, tz := x.Digits(), x.TrailingZeros(); d != test.digits || tz != test.tz {
+			t.Errorf("%s (prec %d): Digits() = %d, TrailingZeros() = %d; want %d, %d", test.x, test.prec, d, tz, test.digits, test.tz)
+		}
+	}
+}
+
 func TestDecimalPredicates(t *testing.T) {
 	for _, test := range []struct {
 		x            string
@@ -385,6 +494,26 @@ func TestDecimalIsInt(t *testing.T) {
 	}
 }
 
+func absInt64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// roundUlp returns the value of the last of the first prec digits of x.
+func roundUlp(x int64, prec uint) int64 {
+	ulp := int64(1)
+	for n := absInt64(x) / 10; n > 0; n /= 10 {
+		if prec > 1 {
+			prec--
+		} else {
+			ulp *= 10
+		}
+	}
+	return ulp
+}
+
 func testDecimalRound(t *testing.T, x, r int64, prec uint, mode RoundingMode) {
 	// verify test data
 	var ok bool
@@ -407,9 +536,25 @@ func testDecimalRound(t *testing.T, x, r int64, prec uint, mode RoundingMode) {
 		ok = r <= x
 	case ToPositiveInf:
 		ok = r >= x
+	case ToNearestTowardZero:
+		// |r-x| is minimal, ties toward zero
+		d, ulp := absInt64(r-x), roundUlp(x, prec)
+		ok = 2*d < ulp || 2*d == ulp && absInt64(r) < absInt64(x)
+	case Round05Up:
+		// if inexact, r is x truncated, or x truncated and incremented if
+		// its last digit was 0 or 5: the last digit of r cannot be 0 or 5.
+		ulp := roundUlp(x, prec)
+		ok = r == x || absInt64(r-x) < ulp && (absInt64(r)/ulp)%5 != 0
+	case ToOdd:
+		// if inexact, the last digit of r is odd
+		ulp := roundUlp(x, prec)
+		ok = r == x || absInt64(r-x) < ulp && (absInt64(r)/ulp)%2 == 1
 	default:
 		panic("unreachable")
 	}
+	if r%roundUlp(x, prec) != 0 {
+		ok = false
+	}
 	if !ok {
 		t.Fatalf("incorrect test data for prec = %d, %s: x = %d, r = %d", prec, mode, x, r)
 	}
@@ -478,86 +623,89 @@ func fromBase10(s string) int64 {
 // TestDecimalRound tests basic rounding.
 func TestDecimalRound(t *testing.T) {
 	for _, test := range []struct {
-		prec                        uint
-		x, zero, neven, naway, away string // input, results rounded to prec bits
+		prec                                         uint
+		x, zero, neven, naway, away, ndown, r05, odd string // input, results rounded to prec digits
 	}{
-		{5, "5000", "5000", "5000", "5000", "5000"},
-		{5, "5005", "5005", "5005", "5005", "5005"},
-		{5, "5050", "5050", "5050", "5050", "5050"},
-		{5, "5055", "5055", "5055", "5055", "5055"},
-		{5, "5500", "5500", "5500", "5500", "5500"},
-		{5, "5505", "5505", "5505", "5505", "5505"},
-		{5, "5550", "5550", "5550", "5550", "5550"},
-		{5, "5555", "5555", "5555", "5555", "5555"},
-
-		{4, "5000", "5000", "5000", "5000", "5000"},
-		{4, "5005", "5005", "5005", "5005", "5005"},
-		{4, "5050", "5050", "5050", "5050", "5050"},
-		{4, "5055", "5055", "5055", "5055", "5055"},
-		{4, "5500", "5500", "5500", "5500", "5500"},
-		{4, "5505", "5505", "5505", "5505", "5505"},
-		{4, "5550", "5550", "5550", "5550", "5550"},
-		{4, "5555", "5555", "5555", "5555", "5555"},
-
-		{3, "5000", "5000", "5000", "5000", "5000"},
-		{3, "5005", "5000", "5000", "5010", "5010"},
-		{3, "5050", "5050", "5050", "5050", "5050"},
-		{3, "5055", "5050", "5060", "5060", "5060"},
-		{3, "5500", "5500", "5500", "5500", "5500"},
-		{3, "5505", "5500", "5500", "5510", "5510"},
-		{3, "5550", "5550", "5550", "5550", "5550"},
-		{3, "9995", "9990", "10000", "10000", "10000"},
-
-		{3, "5000005", "5000000", "5000000", "5000000", "5010000"},
-		{3, "5005005", "5000000", "5010000", "5010000", "5010000"},
-		{3, "5050005", "5050000", "5050000", "5050000", "5060000"},
-		{3, "5055005", "5050000", "5060000", "5060000", "5060000"},
-		{3, "5500005", "5500000", "5500000", "5500000", "5510000"},
-		{3, "5505005", "5500000", "5510000", "5510000", "5510000"},
-		{3, "9990005", "9990000", "9990000", "9990000", "10000000"},
-		{3, "9995005", "9990000", "10000000", "10000000", "10000000"},
-
-		{2, "5000", "5000", "5000", "5000", "5000"},
-		{2, "5005", "5000", "5000", "5000", "5100"},
-		{2, "5050", "5000", "5000", "5100", "5100"},
-		{2, "5055", "5000", "5100", "5100", "5100"},
-		{2, "5500", "5500", "5500", "5500", "5500"},
-		{2, "9905", "9900", "9900", "9900", "10000"},
-		{2, "9950", "9900", "10000", "10000", "10000"},
-		{2, "9955", "9900", "10000", "10000", "10000"},
-
-		{2, "5000005", "5000000", "5000000", "5000000", "5100000"},
-		{2, "5005005", "5000000", "5000000", "5000000", "5100000"},
-		{2, "5050005", "5000000", "5100000", "5100000", "5100000"},
-		{2, "5055005", "5000000", "5100000", "5100000", "5100000"},
-		{2, "9900005", "9900000", "9900000", "9900000", "10000000"},
-		{2, "9905005", "9900000", "9900000", "9900000", "10000000"},
-		{2, "9950005", "9900000", "10000000", "10000000", "10000000"},
-		{2, "9955005", "9900000", "10000000", "10000000", "10000000"},
-
-		{1, "9000", "9000", "9000", "9000", "9000"},
-		{1, "9005", "9000", "9000", "9000", "10000"},
-		{1, "9050", "9000", "9000", "9000", "10000"},
-		{1, "9055", "9000", "9000", "9000", "10000"},
-		{1, "9500", "9000", "10000", "10000", "10000"},
-		{1, "9505", "9000", "10000", "10000", "10000"},
-		{1, "9550", "9000", "10000", "10000", "10000"},
-		{1, "9555", "9000", "10000", "10000", "10000"},
-
-		{1, "9000005", "9000000", "9000000", "9000000", "10000000"},
-		{1, "9005005", "9000000", "9000000", "9000000", "10000000"},
-		{1, "9050005", "9000000", "9000000", "9000000", "10000000"},
-		{1, "9055005", "9000000", "9000000", "9000000", "10000000"},
-		{1, "9500005", "9000000", "10000000", "10000000", "10000000"},
-		{1, "9505005", "9000000", "10000000", "10000000", "10000000"},
-		{1, "9550005", "9000000", "10000000", "10000000", "10000000"},
-		{1, "9555005", "9000000", "10000000", "10000000", "10000000"},
+		{5, "5000", "5000", "5000", "5000", "5000", "5000", "5000", "5000"},
+		{5, "5005", "5005", "5005", "5005", "5005", "5005", "5005", "5005"},
+		{5, "5050", "5050", "5050", "5050", "5050", "5050", "5050", "5050"},
+		{5, "5055", "5055", "5055", "5055", "5055", "5055", "5055", "5055"},
+		{5, "5500", "5500", "5500", "5500", "5500", "5500", "5500", "5500"},
+		{5, "5505", "5505", "5505", "5505", "5505", "5505", "5505", "5505"},
+		{5, "5550", "5550", "5550", "5550", "5550", "5550", "5550", "5550"},
+		{5, "5555", "5555", "5555", "5555", "5555", "5555", "5555", "5555"},
+
+		{4, "5000", "5000", "5000", "5000", "5000", "5000", "5000", "5000"},
+		{4, "5005", "5005", "5005", "5005", "5005", "5005", "5005", "5005"},
+		{4, "5050", "5050", "5050", "5050", "5050", "5050", "5050", "5050"},
+		{4, "5055", "5055", "5055", "5055", "5055", "5055", "5055", "5055"},
+		{4, "5500", "5500", "5500", "5500", "5500", "5500", "5500", "5500"},
+		{4, "5505", "5505", "5505", "5505", "5505", "5505", "5505", "5505"},
+		{4, "5550", "5550", "5550", "5550", "5550", "5550", "5550", "5550"},
+		{4, "5555", "5555", "5555", "5555", "5555", "5555", "5555", "5555"},
+
+		{3, "5000", "5000", "5000", "5000", "5000", "5000", "5000", "5000"},
+		{3, "5005", "5000", "5000", "5010", "5010", "5000", "5010", "5010"},
+		{3, "5050", "5050", "5050", "5050", "5050", "5050", "5050", "5050"},
+		{3, "5055", "5050", "5060", "5060", "5060", "5050", "5060", "5050"},
+		{3, "5500", "5500", "5500", "5500", "5500", "5500", "5500", "5500"},
+		{3, "5505", "5500", "5500", "5510", "5510", "5500", "5510", "5510"},
+		{3, "5550", "5550", "5550", "5550", "5550", "5550", "5550", "5550"},
+		{3, "9995", "9990", "10000", "10000", "10000", "9990", "9990", "9990"},
+
+		{3, "5000005", "5000000", "5000000", "5000000", "5010000", "5000000", "5010000", "5010000"},
+		{3, "5005005", "5000000", "5010000", "5010000", "5010000", "5010000", "5010000", "5010000"},
+		{3, "5050005", "5050000", "5050000", "5050000", "5060000", "5050000", "5060000", "5050000"},
+		{3, "5055005", "5050000", "5060000", "5060000", "5060000", "5060000", "5060000", "5050000"},
+		{3, "5500005", "5500000", "5500000", "5500000", "5510000", "5500000", "5510000", "5510000"},
+		{3, "5505005", "5500000", "5510000", "5510000", "5510000", "5510000", "5510000", "5510000"},
+		{3, "9990005", "9990000", "9990000", "9990000", "10000000", "9990000", "9990000", "9990000"},
+		{3, "9995005", "9990000", "10000000", "10000000", "10000000", "10000000", "9990000", "9990000"},
+
+		{2, "5000", "5000", "5000", "5000", "5000", "5000", "5000", "5000"},
+		{2, "5005", "5000", "5000", "5000", "5100", "5000", "5100", "5100"},
+		{2, "5050", "5000", "5000", "5100", "5100", "5000", "5100", "5100"},
+		{2, "5055", "5000", "5100", "5100", "5100", "5100", "5100", "5100"},
+		{2, "5500", "5500", "5500", "5500", "5500", "5500", "5500", "5500"},
+		{2, "9905", "9900", "9900", "9900", "10000", "9900", "9900", "9900"},
+		{2, "9950", "9900", "10000", "10000", "10000", "9900", "9900", "9900"},
+		{2, "9955", "9900", "10000", "10000", "10000", "10000", "9900", "9900"},
+
+		{2, "5000005", "5000000", "5000000", "5000000", "5100000", "5000000", "5100000", "5100000"},
+		{2, "5005005", "5000000", "5000000", "5000000", "5100000", "5000000", "5100000", "5100000"},
+		{2, "5050005", "5000000", "5100000", "5100000", "5100000", "5100000", "5100000", "5100000"},
+		{2, "5055005", "5000000", "5100000", "5100000", "5100000", "5100000", "5100000", "5100000"},
+		{2, "9900005", "9900000", "9900000", "9900000", "10000000", "9900000", "9900000", "9900000"},
+		{2, "9905005", "9900000", "9900000", "9900000", "10000000", "9900000", "9900000", "9900000"},
+		{2, "9950005", "9900000", "10000000", "10000000", "10000000", "10000000", "9900000", "9900000"},
+		{2, "9955005", "9900000", "10000000", "10000000", "10000000", "10000000", "9900000", "9900000"},
+
+		{1, "9000", "9000", "9000", "9000", "9000", "9000", "9000", "9000"},
+		{1, "9005", "9000", "9000", "9000", "10000", "9000", "9000", "9000"},
+		{1, "9050", "9000", "9000", "9000", "10000", "9000", "9000", "9000"},
+		{1, "9055", "9000", "9000", "9000", "10000", "9000", "9000", "9000"},
+		{1, "9500", "9000", "10000", "10000", "10000", "9000", "9000", "9000"},
+		{1, "9505", "9000", "10000", "10000", "10000", "10000", "9000", "9000"},
+		{1, "9550", "9000", "10000", "10000", "10000", "10000", "9000", "9000"},
+		{1, "9555", "9000", "10000", "10000", "10000", "10000", "9000", "9000"},
+
+		{1, "9000005", "9000000", "9000000", "9000000", "10000000", "9000000", "9000000", "9000000"},
+		{1, "9005005", "9000000", "9000000", "9000000", "10000000", "9000000", "9000000", "9000000"},
+		{1, "9050005", "9000000", "9000000", "9000000", "10000000", "9000000", "9000000", "9000000"},
+		{1, "9055005", "9000000", "9000000", "9000000", "10000000", "9000000", "9000000", "9000000"},
+		{1, "9500005", "9000000", "10000000", "10000000", "10000000", "10000000", "9000000", "9000000"},
+		{1, "9505005", "9000000", "10000000", "10000000", "10000000", "10000000", "9000000", "9000000"},
+		{1, "9550005", "9000000", "10000000", "10000000", "10000000", "10000000", "9000000", "9000000"},
+		{1, "9555005", "9000000", "10000000", "10000000", "10000000", "10000000", "9000000", "9000000"},
 	} {
 		x := fromBase10(test.x)
 		z := fromBase10(test.zero)
 		e := fromBase10(test.neven)
 		n := fromBase10(test.naway)
 		a := fromBase10(test.away)
+		d := fromBase10(test.ndown)
+		f := fromBase10(test.r05)
+		o := fromBase10(test.odd)
 		prec := test.prec
 
 		testDecimalRound(t, x, z, prec, ToZero)
@@ -570,6 +718,121 @@ func TestDecimalRound(t *testing.T) {
 
 		testDecimalRound(t, -x, -a, prec, ToNegativeInf)
 		testDecimalRound(t, -x, -z, prec, ToPositiveInf)
+
+		testDecimalRound(t, x, d, prec, ToNearestTowardZero)
+		testDecimalRound(t, x, f, prec, Round05Up)
+		testDecimalRound(t, x, o, prec, ToOdd)
+		testDecimalRound(t, -x, -d, prec, ToNearestTowardZero)
+		testDecimalRound(t, -x, -f, prec, Round05Up)
+		testDecimalRound(t, -x, -o, prec, ToOdd)
+	}
+}
+
+func TestDecimalRoundInt(t *testing.T) {
+	for _, s := range []string{
+		"0", "0.4", "0.5", "0.6", "1.5", "2.5", "9.5", "99.5", "4.49", "5.01",
+		"0.0001", "123456789012345678901234567890.5", "1e25", "15e-1", "+Inf",
+	} {
+		for _, neg := range []bool{false, true} {
+			x, _ := new(Decimal).SetPrec(40).SetString(s)
+			if neg {
+				x.Neg(x)
+			}
+			for mode := ToNearestEven; mode <= ToOdd; mode++ {
+				want, _ := new(Decimal).SetPrec(40).SetString(x.SetMode(mode).Text('f', 0))
+				acc := makeAcc(want.Cmp(x) > 0)
+				if want.Cmp(x) == 0 {
+					acc = Exact
+				}
+				for _, prec := range [][2]uint{{0, 40}, {1, 40}, {100, 100}} {
+					z := new(Decimal).SetPrec(prec[0]).SetMode(mode)
+					if z.RoundInt(x); z.Cmp(want) != 0 || z.Acc() != acc || z.Prec() != prec[1] || z.Mode() != mode {
+						t.Errorf("prec %d, %s: RoundInt(%s) = %s (%s, prec %d); want %s (%s, prec %d)",
+							prec[0], mode, x, z, z.Acc(), z.Prec(), want, acc, prec[1])
+					}
+				}
+				// aliasing
+				if z := new(Decimal).Copy(x); z.RoundInt(z).Cmp(want) != 0 {
+					t.Errorf("%s: z = %s; z.RoundInt(z) = %s; want %s", mode, x, z, want)
+				}
+			}
+		}
+	}
+}
+
+func TestDecimalRoundStochastic(t *testing.T) {
+	// exact values and special values are unchanged and consume no random
+	// numbers
+	src := rand.NewSource(1)
+	for _, x := range []string{"0", "-0", "+Inf", "-Inf", "1.5", "-123000", "1e-100"} {
+		z := new(Decimal).SetPrec(4).RoundStochastic(makeDecimal(x), src)
+		if want := makeDecimal(x); z.Cmp(want) != 0 || z.Signbit() != want.Signbit() || z.Acc() != Exact {
+			t.Errorf("RoundStochastic(%s) = %s (%s); want %s (Exact)", x, z, z.Acc(), x)
+		}
+	}
+	if got, want := src.Int63(), rand.NewSource(1).Int63(); got != want {
+		t.Errorf("RoundStochastic consumed random numbers for exact values")
+	}
+
+	// prec 0 uses x's precision
+	x := new(Decimal).SetPrec(5).SetFloat64(1.2345)
+	if z := new(Decimal).RoundStochastic(x, src); z.Prec() != 5 || z.Cmp(x) != 0 {
+		t.Errorf("RoundStochastic(%s) = %s, prec %d", x, z, z.Prec())
+	}
+
+	for _, test := range []struct {
+		x        string
+		prec     uint
+		down, up string
+		p        float64 // probability of rounding up
+	}{
+		{"1.23", 2, "1.2", "1.3", 0.3},
+		{"-1.23", 2, "-1.2", "-1.3", 0.3},
+		{"1.2999", 2, "1.2", "1.3", 0.999},
+		{"9.95", 2, "9.9", "10", 0.5},
+		{"1.0000000000000000000000000001", 1, "1", "2", 0},
+		{"0.1234567890123456789012345678905", 30, "0.123456789012345678901234567890", "0.123456789012345678901234567891", 0.5},
+	} {
+		const n = 10000
+		up := 0
+		src := rand.NewSource(42)
+		down, upv := makeDecimal(test.down), makeDecimal(test.up)
+		for i := 0; i < n; i++ {
+			z := new(Decimal).SetPrec(test.prec).SetMode(ToNearestEven).RoundStochastic(makeDecimal(test.x), src)
+			switch {
+			case z.Cmp(down) == 0:
+				if z.Acc() != makeAcc(z.neg) {
+					t.Fatalf("RoundStochastic(%s, %d) = %s; got accuracy %s", test.x, test.prec, z, z.Acc())
+				}
+			case z.Cmp(upv) == 0:
+				if z.Acc() != makeAcc(!z.neg) {
+					t.Fatalf("RoundStochastic(%s, %d) = %s; got accuracy %s", test.x, test.prec, z, z.Acc())
+				}
+				up++
+			default:
+				t.Fatalf("RoundStochastic(%s, %d) = %s; want %s or %s", test.x, test.prec, z, test.down, test.up)
+			}
+			if z.Mode() != ToNearestEven || z.Prec() != test.prec {
+				t.Fatalf("RoundStochastic changed mode or precision")
+			}
+		}
+		// 5 standard deviations
+		if p := float64(up) / n; math.Abs(p-test.p) > 5*math.Sqrt(test.p*(1-test.p)/n)+1e-9 {
+			t.Errorf("RoundStochastic(%s, %d): rounded up with probability %g; want %g", test.x, test.prec, p, test.p)
+		}
+	}
+
+	// determinism
+	x = makeDecimal("3.14159265358979323846")
+	var s1, s2 []string
+	for _, s := range []*[]string{&s1, &s2} {
+		src := rand.NewSource(7)
+		for i := 0; i < 100; i++ {
+			*s = append(*s, new(Decimal).SetPrec(3).RoundStochastic(x, src).String())
+		}
+	}
+	if strings.Join(s1, " ") != strings.Join(s2, " ") {
+		t.Errorf("RoundStochastic is not deterministic for a given seed")
 	}
 }
 
@@ -967,8 +1230,7 @@ func TestDecimalFloat32(t *testing.T) {
 
 			x := makeDecimal(tx)
 			out, acc := x.Float32()
-			// TODO(db47h): need to check accuracy, somehow.
-			if !alike32(out, tout) /* || acc != tacc */ { // do not check accuracy
+			if !alike32(out, tout) || acc != tacc {
 				t.Errorf("%s: got %g (%#08x, %s); want %g (%#08x, %s)", tx, out, math.Float32bits(out), acc, test.out, math.Float32bits(test.out), tacc)
 			}
 
@@ -1075,8 +1337,7 @@ func TestDecimalFloat64(t *testing.T) {
 
 			x := makeDecimal(tx)
 			out, acc := x.Float64()
-			// TODO(db47h): need to check accuracy, somehow.
-			if !alike64(out, tout) /*|| acc != tacc */ {
+			if !alike64(out, tout) || acc != tacc {
 				t.Errorf("%s: got %g (%#016x, %s); want %g (%#016x, %s)", tx, out, math.Float64bits(out), acc, test.out, math.Float64bits(test.out), tacc)
 			}
 
@@ -1236,6 +1497,32 @@ func TestDecimalNeg(t *testing.T) {
 	}
 }
 
+func TestDecimalNegRounding(t *testing.T) {
+	x := makeDecimal("1.25")
+	for _, test := range []struct {
+		mode RoundingMode
+		want string
+		acc  Accuracy
+	}{
+		{ToNearestEven, "-1.2", Above},
+		{ToNearestAway, "-1.3", Below},
+		{ToZero, "-1.2", Above},
+		{AwayFromZero, "-1.3", Below},
+		{ToNegativeInf, "-1.3", Below},
+		{ToPositiveInf, "-1.2", Above},
+	} {
+		z := new(Decimal).SetPrec(2).SetMode(test.mode).Neg(x)
+		if z.Text('g', 10) != test.want || z.Acc() != test.acc || z.Mode() != test.mode {
+			t.Errorf("%s: got %s (%s, %s); want %s (%s)", test.mode, z.Text('g', 10), z.Acc(), z.Mode(), test.want, test.acc)
+		}
+		// Sub with a zero minuend
+		z.Sub(new(Decimal), x)
+		if z.Text('g', 10) != test.want || z.Acc() != test.acc {
+			t.Errorf("%s: 0 - %s = %s (%s); want %s (%s)", test.mode, x, z.Text('g', 10), z.Acc(), test.want, test.acc)
+		}
+	}
+}
+
 func TestDecimalInc(t *testing.T) {
 	const n = 10
 	for _, prec := range precList {
@@ -1593,6 +1880,162 @@ func TestDecimalQuo(t *testing.T) {
 	}
 }
 
+// TestDecimalQuoNewton checks that divisions computed with Newton's method are
+// rounded like divisions computed with long division.
+func TestDecimalQuoNewton(t *testing.T) {
+	defer func(th, rth int) { decDivNewtonThreshold, decRecipThreshold = th, rth }(decDivNewtonThreshold, decRecipThreshold)
+	decRecipThreshold = 4
+	r := rand.New(rand.NewSource(1))
+	rnd := func(digits int) *Decimal {
+		var m big.Int
+		m.Rand(r, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil))
+		m.Add(&m, big.NewInt(1))
+		return new(Decimal).SetPrec(uint(digits)).SetInt(&m)
+	}
+	modes := [...]RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf}
+	for i := 0; i < 200; i++ {
+		prec := uint(1 + r.Intn(2000))
+		x, y := rnd(1+r.Intn(2000)), rnd(1+r.Intn(2000))
+		if i&1 != 0 {
+			// exact quotient, or a tie
+			q := rnd(int(prec))
+			if i&2 != 0 {
+				q = new(Decimal).SetPrec(prec+1).SetMantExp(q, 1)
+				q.Add(q, NewDecimal(5, 0))
+			}
+			x = new(Decimal).SetPrec(q.Prec()+y.Prec()).Mul(q, y)
+		}
+		mode := modes[r.Intn(len(modes))]
+		decDivNewtonThreshold = 1e9
+		want := new(Decimal).SetPrec(prec).SetMode(mode).Quo(x, y)
+		decDivNewtonThreshold = 2
+		got := new(Decimal).SetPrec(prec).SetMode(mode).Quo(x, y)
+		if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+			t.Fatalf("%s / %s (prec %d, %s) = %s (%s); want %s (%s)", x, y, prec, mode, got, got.Acc(), want, want.Acc())
+		}
+	}
+}
+
+// TestDecimalMulNTT checks that products computed with NTT multiplication are
+// rounded like products computed with the other algorithms. The thresholds are
+// lowered so that the NTT also runs on platforms where it is disabled by
+// default, like 386.
+func TestDecimalMulNTT(t *testing.T) {
+	defer func(m, s int) { decNTTThreshold, decNTTSqrThreshold = m, s }(decNTTThreshold, decNTTSqrThreshold)
+	r := rand.New(rand.NewSource(1))
+	modes := [...]RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf}
+	for i := 0; i < 100; i++ {
+		prec := uint(1 + r.Intn(4000))
+		x, y := rndDecimal(r, uint(1+r.Intn(2000)), 0), rndDecimal(r, uint(1+r.Intn(2000)), 0)
+		if i&1 != 0 {
+			y = x
+		}
+		mode := modes[r.Intn(len(modes))]
+		decNTTThreshold, decNTTSqrThreshold = 1e9, 1e9
+		want := new(Decimal).SetPrec(prec).SetMode(mode).Mul(x, y)
+		decNTTThreshold, decNTTSqrThreshold = 2, 2
+		got := new(Decimal).SetPrec(prec).SetMode(mode).Mul(x, y)
+		if got.Cmp(want) != 0 || got.Acc() != want.Acc() {
+			t.Fatalf("%s * %s (prec %d, %s) = %s (%s); want %s (%s)", x, y, prec, mode, got, got.Acc(), want, want.Acc())
+		}
+	}
+}
+
+// TestDecimalSmall checks the single Word paths of Add, Sub, Mul, Quo and Cmp
+// against the general ones.
+func TestDecimalSmall(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	modes := [...]RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf}
+	rnd := func() *Decimal {
+		m := r.Int63n(int64(pow10(_DW-1))) * int64(r.Intn(9)+1)
+		if r.Intn(2) == 0 {
+			m = -m
+		}
+		return NewDecimal(m, r.Intn(2*_DW)-_DW).SetPrec(uint(1 + r.Intn(_DW)))
+	}
+	// wide returns x with a two Words mantissa.
+	wide := func(x *Decimal) *Decimal {
+		z := new(Decimal).Copy(x)
+		if z.form == finite {
+			z.mant = dec{0, x.mant[0]}
+		}
+		return z
+	}
+	for i := 0; i < 100000; i++ {
+		x, y := rnd(), rnd()
+		if r.Intn(8) == 0 {
+			y.Set(x)
+		}
+		if c, w := x.Cmp(y), wide(x).Cmp(wide(y)); c != w {
+			t.Fatalf("%s cmp %s = %d; want %d", x, y, c, w)
+		}
+		prec := uint(1 + r.Intn(_DW))
+		mode := modes[r.Intn(len(modes))]
+		for _, op := range []struct {
+			name string
+			f    func(z, x, y *Decimal) *Decimal
+		}{
+			{"+", (*Decimal).Add},
+			{"-", (*Decimal).Sub},
+			{"*", (*Decimal).Mul},
+			{"/", (*Decimal).Quo},
+		} {
+			if op.name == "/" && y.Sign() == 0 {
+				continue
+			}
+			got := op.f(new(Decimal).SetPrec(prec).SetMode(mode), x, y)
+			want := op.f(new(Decimal).SetPrec(prec).SetMode(mode), wide(x), wide(y))
+			if got.Cmp(want) != 0 || got.Acc() != want.Acc() || got.Signbit() != want.Signbit() {
+				t.Fatalf("%s %s %s (prec %d, %s) = %s (%s); want %s (%s)", x.Text('g', -1), op.name, y.Text('g', -1), prec, mode, got.Text('g', -1), got.Acc(), want.Text('g', -1), want.Acc())
+			}
+		}
+	}
+}
+
+func TestDecimalSmallAllocs(t *testing.T) {
+	x := NewDecimal(123456789, -5)
+	y := NewDecimal(98765432, 2)
+	z := new(Decimal).SetPrec(_DW)
+	for _, test := range []struct {
+		name string
+		f    func()
+	}{
+		{"Add", func() { z.Add(x, y) }},
+		{"Sub", func() { z.Sub(y, x) }},
+		{"Mul", func() { z.Mul(x, y) }},
+		{"Quo", func() { z.Quo(x, y) }},
+		{"Cmp", func() { x.Cmp(y) }},
+		{"aliased Add", func() { z.Add(z, y) }},
+		{"aliased Mul", func() { z.Mul(z, z) }},
+		{"aliased Quo", func() { z.Quo(y, z) }},
+		{"SetInt64", func() { z.SetInt64(-42) }},
+	} {
+		if allocs := testing.AllocsPerRun(100, test.f); allocs != 0 {
+			t.Errorf("%s: got %v allocs; want 0", test.name, allocs)
+		}
+	}
+	// only the mantissa of a new Decimal is allocated
+	for _, test := range []struct {
+		name string
+		f    func()
+	}{
+		{"NewDecimal", func() { NewDecimal(42, 0) }},
+		{"Set", func() { new(Decimal).Set(x) }},
+		{"local Add", func() {
+			var d Decimal
+			d.Add(x, y)
+		}},
+		{"local Mul", func() {
+			var d Decimal
+			d.Mul(x, x)
+		}},
+	} {
+		if allocs := testing.AllocsPerRun(100, test.f); allocs > 1 {
+			t.Errorf("%s: got %v allocs; want 1", test.name, allocs)
+		}
+	}
+}
+
 // var long = flag.Bool("long", false, "run very long tests")
 
 // // TODO(db47h): like similar tests, this test needs to be moved to a separate package and its results compared
@@ -1866,6 +2309,133 @@ func TestDecimalCmpSpecialValues(t *testing.T) {
 	}
 }
 
+// TestDecimalTotalOrderSpecialValues tests TotalOrder, TotalOrderMag, Min, Max,
+// MinMag, MaxMag and CopySign with the same values as
+// TestDecimalCmpSpecialValues. Since args is sorted in total order, the
+// expected results follow from the argument indices.
+func TestDecimalTotalOrderSpecialValues(t *testing.T) {
+	zero := 0.0
+	args := []float64{math.Inf(-1), -2.71828, -1, -zero, zero, 1, 2.71828, math.Inf(1)}
+	cmp := func(a, b float64) int {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return +1
+		}
+		return 0
+	}
+	same := func(z *Decimal, f float64) bool {
+		got, _ := z.Float64()
+		return got == f && math.Signbit(got) == math.Signbit(f)
+	}
+	xx := new(Decimal)
+	yy := new(Decimal)
+	for i, x := range args {
+		xx.SetFloat64(x)
+		for j, y := range args {
+			yy.SetFloat64(y)
+			if got, want := xx.TotalOrder(yy), cmp(float64(i), float64(j)); got != want {
+				t.Errorf("(%g).TotalOrder(%g) = %v; want %v", x, y, got, want)
+			}
+			mag := cmp(math.Abs(x), math.Abs(y))
+			if got := xx.TotalOrderMag(yy); got != mag {
+				t.Errorf("(%g).TotalOrderMag(%g) = %v; want %v", x, y, got, mag)
+			}
+
+			min, max := args[i], args[j]
+			if i > j {
+				min, max = max, min
+			}
+			minMag, maxMag := min, max
+			switch mag {
+			case -1:
+				minMag, maxMag = x, y
+			case 1:
+				minMag, maxMag = y, x
+			}
+			for _, test := range []struct {
+				name string
+				op   func(z, x, y *Decimal) *Decimal
+				want float64
+			}{
+				{"Min", (*Decimal).Min, min},
+				{"Max", (*Decimal).Max, max},
+				{"MinMag", (*Decimal).MinMag, minMag},
+				{"MaxMag", (*Decimal).MaxMag, maxMag},
+				{"CopySign", (*Decimal).CopySign, math.Copysign(x, y)},
+			} {
+				z := new(Decimal)
+				if got := test.op(z, xx, yy); got != z || !same(z, test.want) {
+					t.Errorf("%s(%g, %g) = %s; want %g", test.name, x, y, z, test.want)
+				}
+				// aliasing
+				z.SetFloat64(x)
+				if test.op(z, z, yy); !same(z, test.want) {
+					t.Errorf("z = %g; z.%s(z, %g) = %s; want %g", x, test.name, y, z, test.want)
+				}
+				z.SetFloat64(y)
+				if test.op(z, xx, z); !same(z, test.want) {
+					t.Errorf("z = %g; z.%s(%g, z) = %s; want %g", y, test.name, x, z, test.want)
+				}
+			}
+		}
+	}
+
+	// rounding
+	x := new(Decimal).SetPrec(5).SetFloat64(1.2345)
+	y := new(Decimal).SetPrec(5).SetFloat64(-1.2345)
+	if z := new(Decimal).SetPrec(3).Max(x, y); z.Cmp(makeDecimal("1.23")) != 0 || z.Acc() != Below {
+		t.Errorf("Max(%s, %s) = %s (%s); want 1.23 (Below)", x, y, z, z.Acc())
+	}
+	if z := new(Decimal).SetPrec(3).MaxMag(x, y); z.Cmp(makeDecimal("1.23")) != 0 || z.Acc() != Below {
+		t.Errorf("MaxMag(%s, %s) = %s (%s); want 1.23 (Below)", x, y, z, z.Acc())
+	}
+	for _, test := range []struct {
+		x, y *Decimal
+		mode RoundingMode
+		want string
+		acc  Accuracy
+	}{
+		{x, y, ToNearestEven, "-1.23", Above},
+		{x, y, ToZero, "-1.23", Above},
+		{x, y, AwayFromZero, "-1.24", Below},
+		{x, y, ToNegativeInf, "-1.24", Below},
+		{x, y, ToPositiveInf, "-1.23", Above},
+		{y, x, ToNegativeInf, "1.23", Below},
+		{y, x, ToPositiveInf, "1.24", Above},
+		{x, x, ToNegativeInf, "1.23", Below},
+		{x, x, ToPositiveInf, "1.24", Above},
+		{y, y, ToNegativeInf, "-1.24", Below},
+		{y, y, ToPositiveInf, "-1.23", Above},
+	} {
+		z := new(Decimal).SetPrec(3).SetMode(test.mode).CopySign(test.x, test.y)
+		if z.Text('g', 10) != test.want || z.Acc() != test.acc || z.Mode() != test.mode {
+			t.Errorf("%s: CopySign(%s, %s) = %s (%s, %s); want %s (%s)",
+				test.mode, test.x, test.y, z.Text('g', 10), z.Acc(), z.Mode(), test.want, test.acc)
+		}
+	}
+
+	// TotalOrder of finite values with equal sign and exponent
+	for _, test := range []struct {
+		x, y string
+		want int
+	}{
+		{"1.5", "1.25", 1},
+		{"-1.5", "-1.25", -1},
+		{"1.000000000000000000000000001", "1", 1},
+		{"-1", "-1.0000", 0},
+	} {
+		x, y := makeDecimal(test.x), makeDecimal(test.y)
+		if got := x.TotalOrder(y); got != test.want {
+			t.Errorf("(%s).TotalOrder(%s) = %d; want %d", test.x, test.y, got, test.want)
+		}
+		if got, want := x.TotalOrderMag(y), new(Decimal).Abs(x).Cmp(new(Decimal).Abs(y)); got != want {
+			t.Errorf("(%s).TotalOrderMag(%s) = %d; want %d", test.x, test.y, got, want)
+		}
+	}
+}
+
 func BenchmarkDecimalAdd(b *testing.B) {
 	x := new(Decimal)
 	y := new(Decimal)
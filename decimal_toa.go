@@ -10,6 +10,8 @@ package decimal
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 )
 
@@ -23,25 +25,32 @@ import (
 //  'G' like 'E' for large exponents, like 'f' otherwise
 //  'p' -0.dddde±dd, decimal mantissa, decimal exponent (non-standard)
 //  'b' -dddddde±dd, decimal mantissa, decimal exponent (non-standard)
+//  'x' -0x1.yyyyyp±dd, hexadecimal mantissa, binary exponent
+//  'X' -0X1.YYYYYP±dd, hexadecimal mantissa, binary exponent
 //
 // For non-standard formats, the mantissa is printed in normalized form:
 //
 //  'p' decimal mantissa in [0.1, 1), or 0
 //  'b' decimal integer mantissa using x.Prec() digits, or 0
 //
-// Note that the 'b' and 'p' formats differ from big.Float: an hexadecimal
-// representation does not make sense for decimals. These formats use a full
-// decimal representation instead.
+// Note that the 'b' and 'p' formats differ from big.Float: these formats use a
+// full decimal representation instead. The 'x' and 'X' formats are the same as
+// big.Float's: the hexadecimal mantissa is normalized to [1, 2), or 0.
 //
 // If format is a different character, Text returns a "%" followed by the
 // unrecognized format character.
 //
 // The precision prec controls the number of digits (excluding the exponent)
-// printed by the 'e', 'E', 'f', 'g', and 'G' formats. For 'e', 'E', and 'f', it
-// is the number of digits after the decimal point. For 'g' and 'G' it is the
-// total number of digits. A negative precision selects the smallest number of
-// decimal digits necessary to identify the value x uniquely using x.Prec()
-// mantissa digits. The prec value is ignored for the 'b' and 'p' formats.
+// printed by the 'e', 'E', 'f', 'g', 'G', 'x' and 'X' formats. For 'e', 'E',
+// 'f', 'x' and 'X', it is the number of digits after the decimal point. For 'g'
+// and 'G' it is the total number of digits. A negative precision selects the
+// smallest number of decimal digits necessary to identify the value x uniquely
+// using x.Prec() mantissa digits. For 'x' and 'X', it selects the smallest
+// number of hexadecimal digits such that parsing the result with x's precision
+// and rounding mode ToNearestEven yields x. The prec value is ignored for the
+// 'b' and 'p' formats.
+//
+// See TextBase for the formatting of x in bases other than 10 and 16.
 func (x *Decimal) Text(format byte, prec int) string {
 	return string(x.Append(nil, format, prec))
 }
@@ -78,6 +87,17 @@ func (x *Decimal) Append(buf []byte, fmt byte, prec int) []byte {
 		return x.fmtB(buf)
 	case 'p':
 		return x.fmtP(buf)
+	case 'x':
+		return x.fmtBase(append(buf, "0x"...), 16, 'p', prec)
+	case 'X':
+		i := len(buf) + 2
+		buf = x.fmtBase(append(buf, "0X"...), 16, 'P', prec)
+		for ; i < len(buf); i++ {
+			if 'a' <= buf[i] && buf[i] <= 'f' {
+				buf[i] -= 'a' - 'A'
+			}
+		}
+		return buf
 	}
 
 	// Algorithm:
@@ -114,7 +134,11 @@ func (x *Decimal) Append(buf []byte, fmt byte, prec int) []byte {
 			}
 			rnd = prec
 		}
-		if rnd < digits {
+		if rnd == 0 && fmt == 'f' && x.form == finite {
+			// |x| < 10**-prec: the result is either 0 or ±10**-prec
+			x = x.roundTiny(prec)
+			digits = int(x.MinPrec())
+		} else if rnd < digits {
 			x = new(Decimal).SetMode(x.mode).SetPrec(uint(rnd)).Set(x)
 			digits = int(x.MinPrec())
 		}
@@ -201,6 +225,14 @@ func (x *Decimal) bufSizeForFmt(fmt byte, prec int) int {
 	case 'p':
 		// -0.ddde±dd
 		sz += 2 + digits + 1 + expSz(exp)
+	case 'x', 'X':
+		// -0x1.yyyp±dd, with a binary exponent about 3.3 times larger
+		sz += 4 + 2 + expSz(exp) + 1
+		if prec < 0 {
+			sz += digits
+		} else {
+			sz += prec
+		}
 	default:
 		sz = prec
 	}
@@ -352,13 +384,237 @@ func (x *Decimal) fmtP(buf []byte) []byte {
 	return strconv.AppendInt(buf, int64(exp), 10)
 }
 
+// TextBase is like Text, but formats the mantissa of x in the given base. The
+// format is one of:
+//
+//  'e' -d.dddde±dd, mantissa in base, decimal exponent
+//  'E' -d.ddddE±dd, mantissa in base, decimal exponent
+//  'f' -ddddd.dddd, no exponent
+//  'p' -d.ddddp±dd, mantissa in base, binary exponent
+//  'P' -d.ddddP±dd, mantissa in base, binary exponent
+//
+// Note that unlike with Text, 'p' denotes a binary exponent. Exponents are
+// always written in decimal. The mantissa is normalized to [1, 10) for the 'e'
+// and 'E' formats, thus its integer part may have more than one digit in bases
+// < 10, and to [1, 2) for the 'p' and 'P' formats.
+//
+// The base must be between 2 and MaxBase. Digit values >= 10 are written with
+// the letters 'a' to 'z', then 'A' to 'Z'.
+//
+// The precision prec is the number of digits after the radix point; the
+// result is rounded according to x's rounding mode. A negative precision
+// selects the smallest number of digits necessary to represent x exactly if
+// base is a multiple of 10 and the format is 'e', 'E' or 'f'. Otherwise, it
+// selects the smallest number of digits such that parsing the result with x's
+// precision and rounding mode ToNearestEven yields x.
+//
+// Parse reads the result back as long as the exponent character is not a
+// valid digit in the given base.
+func (x *Decimal) TextBase(base int, format byte, prec int) string {
+	return string(x.AppendBase(nil, base, format, prec))
+}
+
+// AppendBase appends to buf the string form of the floating-point number x,
+// as generated by x.TextBase, and returns the extended buffer.
+func (x *Decimal) AppendBase(buf []byte, base int, fmt byte, prec int) []byte {
+	if base < 2 || base > MaxBase {
+		panic("invalid base")
+	}
+	switch fmt {
+	case 'e', 'E', 'f', 'p', 'P':
+	default:
+		return append(buf, '%', fmt)
+	}
+
+	// sign
+	if x.neg {
+		buf = append(buf, '-')
+	}
+
+	// Inf
+	if x.form == inf {
+		if !x.neg {
+			buf = append(buf, '+')
+		}
+		return append(buf, "Inf"...)
+	}
+
+	return x.fmtBase(buf, base, fmt, prec)
+}
+
+// fmtBase appends |x| formatted by AppendBase to buf.
+func (x *Decimal) fmtBase(buf []byte, base int, fmt byte, prec int) []byte {
+	var (
+		m    []byte
+		exp  int64
+		frac int
+	)
+	if x.form == zero {
+		m = []byte("0")
+		frac = max(prec, 0)
+	} else {
+		var n dec
+		n, exp, frac = x.digitsBase(Word(base), fmt, prec)
+		m = n.utoa(base)
+	}
+	if len(m) <= frac {
+		// pad with leading zeros
+		m = append(bytes.Repeat([]byte{'0'}, frac+1-len(m)), m...)
+	}
+	i := len(m) - frac
+	if prec < 0 {
+		// trim trailing zeros
+		for len(m) > i && m[len(m)-1] == '0' {
+			m = m[:len(m)-1]
+		}
+	}
+
+	buf = append(buf, m[:i]...)
+	if len(m) > i {
+		buf = append(buf, '.')
+		buf = append(buf, m[i:]...)
+	}
+	if fmt == 'f' {
+		return buf
+	}
+
+	// e±dd or p±dd
+	buf = append(buf, fmt)
+	ch := byte('+')
+	if exp < 0 {
+		ch = '-'
+		exp = -exp
+	}
+	buf = append(buf, ch)
+	if exp < 10 {
+		buf = append(buf, '0') // at least 2 exponent digits
+	}
+	return strconv.AppendInt(buf, exp, 10)
+}
+
+// digitsBase returns the integer n = |x| × b**frac × 10**-exp ('e' and 'E'
+// formats) or |x| × b**frac × 2**-exp ('p' and 'P' formats), rounded according
+// to x's rounding mode, where frac is the number of fractional digits for the
+// given format and precision, along with exp and frac. x must be finite and
+// not zero.
+func (x *Decimal) digitsBase(b Word, fmt byte, prec int) (n dec, exp int64, frac int) {
+	switch fmt {
+	case 'e', 'E':
+		exp = int64(x.exp) - 1
+	case 'p', 'P':
+		exp = x.ilogb2()
+	}
+
+	frac = prec
+	if prec < 0 {
+		if b%10 == 0 && fmt != 'p' && fmt != 'P' {
+			// x × 10**-exp has that many fractional decimal digits
+			frac = max(int(int64(x.MinPrec())-int64(x.exp)+exp), 0)
+		} else {
+			// With d significant digits, the distance between x and the
+			// result is less than b**(1-d) × |x|. This must be less than
+			// half an ulp of x, which is at least 10**-prec × |x| / 2.
+			logb := math.Log10(float64(b))
+			d := int(math.Ceil((float64(x.prec)+log10_2)/logb)) + 1
+			// number of digits of the integer part; err on the low side.
+			i := 1
+			if fmt == 'f' {
+				i = int(math.Floor(float64(x.exp-1)/logb)) + 1
+			}
+			frac = max(d-i, 0)
+		}
+	}
+
+	// x = ±m × 10**e with m an integer
+	p := dec(nil).expWW(b, uint64(frac))
+	m := dec(nil).mul(x.mant, p)
+	e := int64(x.exp) - int64(len(x.mant))*_DW
+	z := Decimal{mode: x.mode, form: finite, neg: x.neg}
+	var lim Word // upper bound of the normalized mantissa
+	switch fmt {
+	case 'e', 'E':
+		lim = 10
+		fallthrough
+	case 'f':
+		z.prec = uint32(len(m)) * _DW
+		z.mant = z.mant.set(m)
+		z.setExpAndRound(int64(len(m))*_DW-dnorm(z.mant)+e-exp, 0)
+		z.roundInt()
+	case 'p', 'P':
+		lim = 2
+		// the number of integer digits of the result is that of its
+		// truncated value.
+		z.prec = 1
+		z.mode = ToZero
+		z.setExp2(dec(nil).set(m), -exp, e)
+		z.prec = uint32(z.exp)
+		z.mode = x.mode
+		z.setExp2(m, -exp, e)
+	}
+	if z.form != finite {
+		// 'f' format, |x| rounded to 0
+		return nil, exp, frac
+	}
+	n = z.intMant()
+	if lim != 0 && n.cmp(dec(nil).mulAddWW(p, lim, 0)) >= 0 {
+		// Rounding overflowed into the next power of lim. At that scale, the
+		// mantissa rounds to 1.
+		return p, exp + 1, frac
+	}
+	return n, exp, frac
+}
+
+// roundInt rounds z to an integer according to z's rounding mode. z must be
+// finite.
+func (z *Decimal) roundInt() {
+	if z.exp > 0 {
+		if uint32(z.exp) < z.prec {
+			z.prec = uint32(z.exp)
+			z.round(0)
+		}
+		return
+	}
+	// |z| < 1. Adding ±10 preserves the parity of the integer part, and the
+	// position of the rounding digit.
+	ten := new(Decimal).SetInt64(10)
+	ten.neg = z.neg
+	t := new(Decimal).SetMode(z.mode).SetPrec(2)
+	t.Add(z, ten)
+	z.Sub(t, ten)
+	z.acc = t.acc // the subtraction is exact
+}
+
+// roundTiny returns x rounded to prec fractional digits according to x's
+// rounding mode. x must be finite and |x| < 10**-prec.
+func (x *Decimal) roundTiny(prec int) *Decimal {
+	z := new(Decimal).Copy(x)
+	// Only the rounding direction matters: bring |z| within [0.01, 1).
+	z.exp = int32(max(int(x.exp)+prec, -1))
+	z.roundInt()
+	if z.form == finite {
+		z.exp = int32(1 - prec)
+	}
+	z.neg = x.neg
+	return z
+}
+
+// ilogb2 returns floor(log2(|x|)). x must be finite and not zero.
+func (x *Decimal) ilogb2() int64 {
+	// 10**(x.exp-1) <= |x| < 10**x.exp, so that shifting |x| by -e brings it
+	// within a few binades of 1.
+	e := int64(float64(x.exp-1) * log2_10)
+	f := x.floatExp(new(big.Float).SetPrec(1).SetMode(big.ToZero), -e)
+	return e + int64(f.MantExp(nil)) - 1
+}
+
 // Format implements fmt.Formatter. It accepts the regular formats for
-// floating-point numbers 'e', 'E', 'f', 'F', 'g', and 'G', as well as 'b', 'p'
-// and 'v'. See (*Decimal).Text for the interpretation of 'b' and 'p'. The 'v'
-// format is handled like 'g'. Format also supports specification of the minimum
-// precision in digits, the output field width, as well as the format flags '+'
-// and ' ' for sign control, '0' for space or zero padding, and '-' for left or
-// right justification. See the fmt package for details.
+// floating-point numbers 'e', 'E', 'f', 'F', 'g', and 'G', as well as 'b', 'p',
+// 'x', 'X' and 'v'. See (*Decimal).Text for the interpretation of 'b', 'p',
+// 'x' and 'X'. The 'v' format is handled like 'g'. Format also supports
+// specification of the minimum precision in digits, the output field width,
+// as well as the format flags '+' and ' ' for sign control, '0' for space or
+// zero padding, and '-' for left or right justification. See the fmt package
+// for details.
 func (x *Decimal) Format(s fmt.State, format rune) {
 	prec, hasPrec := s.Precision()
 	if !hasPrec {
@@ -366,7 +622,7 @@ func (x *Decimal) Format(s fmt.State, format rune) {
 	}
 
 	switch format {
-	case 'e', 'E', 'f', 'b', 'p':
+	case 'e', 'E', 'f', 'b', 'p', 'x', 'X':
 		// nothing to do
 	case 'F':
 		// (*Decimal).Text doesn't support 'F'; handle like 'f'
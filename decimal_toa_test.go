@@ -0,0 +1,192 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDecimalTextBase(t *testing.T) {
+	for _, test := range []struct {
+		x      string
+		mode   RoundingMode
+		base   int
+		format byte
+		prec   int
+		want   string
+	}{
+		{"0", ToNearestEven, 2, 'f', -1, "0"},
+		{"0", ToNearestEven, 2, 'f', 3, "0.000"},
+		{"0", ToNearestEven, 16, 'p', -1, "0p+00"},
+		{"-0", ToNearestEven, 3, 'e', 2, "-0.00e+00"},
+		{"+Inf", ToNearestEven, 7, 'f', -1, "+Inf"},
+		{"-Inf", ToNearestEven, 7, 'e', -1, "-Inf"},
+		{"1", ToNearestEven, 2, 'f', -1, "1"},
+		{"0.5", ToNearestEven, 2, 'f', -1, "0.1"},
+		{"0.5", ToNearestEven, 3, 'e', -1, "12e-01"},
+		{"0.5", ToNearestEven, 36, 'f', -1, "0.i"},
+		{"-12345.678", ToNearestEven, 10, 'e', -1, "-1.2345678e+04"},
+		{"-12345.678", ToNearestEven, 10, 'e', 3, "-1.235e+04"},
+		{"-12345.678", ToZero, 10, 'e', 3, "-1.234e+04"},
+		{"12345.678", ToNearestEven, 20, 'e', -1, "1.4dgagj4e+04"},
+		{"12345.678", ToNearestEven, 2, 'f', 8, "11000000111001.10101110"},
+		{"12345.678", ToNearestEven, 16, 'p', 4, "1.81cdp+13"},
+		{"12345.678", ToZero, 16, 'P', 4, "1.81cdP+13"},
+		{"0.1", ToNearestEven, 16, 'p', -1, "1.99999999ap-04"},
+		{"0.1", ToNearestEven, 2, 'f', 4, "0.0010"},
+		{"0.1", AwayFromZero, 2, 'f', 4, "0.0010"},
+		{"0.1", ToZero, 2, 'f', 4, "0.0001"},
+		{"0.1", ToNearestEven, 2, 'f', 2, "0.00"},
+		{"0.1", AwayFromZero, 2, 'f', 2, "0.01"},
+		{"0.25", ToNearestEven, 2, 'f', 1, "0.0"},
+		{"0.75", ToNearestEven, 2, 'f', 1, "1.0"},
+		{"0.25", ToNearestAway, 2, 'f', 1, "0.1"},
+		{"-0.25", ToNegativeInf, 2, 'f', 1, "-0.1"},
+		{"-0.25", ToPositiveInf, 2, 'f', 1, "-0.0"},
+		{"3", ToNearestEven, 3, 'e', -1, "10e+00"},
+		{"3", ToNearestEven, 10, 'p', -1, "1.5p+01"},
+		{"1.999", ToNearestEven, 2, 'p', 2, "1.00p+01"},
+		{"9.96", ToNearestEven, 10, 'e', 1, "1.0e+01"},
+		{"1e100", ToNearestEven, 62, 'e', 0, "1e+100"},
+		{"1e1000000000", ToNearestEven, 16, 'p', 4, "1.d98cp+3321928094"},
+		{"1e-999999999", ToNearestEven, 16, 'p', 4, "1.59fcp-3321928092"},
+		{"1.5", ToNearestEven, 2, 'g', -1, "%g"},
+	} {
+		x := makeDecimal(test.x).SetMode(test.mode)
+		x.SetPrec(10)
+		if got := x.TextBase(test.base, test.format, test.prec); got != test.want {
+			t.Errorf("%s.TextBase(%d, %c, %d) (%s) = %s; want %s", test.x, test.base, test.format, test.prec, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestDecimalTextX(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	n := 200
+	if testing.Short() {
+		n = 20
+	}
+	for i := 0; i < n; i++ {
+		f := rndFloat(r, 200, 1000)
+		x := new(Decimal).SetPrec(1000).SetFloat(f)
+		if x.Acc() != Exact {
+			t.Fatalf("SetFloat(%s) not exact", f.Text('p', 0))
+		}
+		for _, mode := range floatConvModes {
+			x.SetMode(mode)
+			f.SetMode(big.RoundingMode(mode))
+			for _, prec := range []int{-1, 0, 1, 5, 13, 30, 60} {
+				want := f.Text('x', prec)
+				if got := x.Text('x', prec); got != want {
+					t.Fatalf("%s.Text('x', %d) (%s) = %s; want %s", f.Text('p', 0), prec, mode, got, want)
+				}
+				want = strings.ToUpper(want)
+				if got := x.Text('X', prec); got != want {
+					t.Fatalf("%s.Text('X', %d) (%s) = %s; want %s", f.Text('p', 0), prec, mode, got, want)
+				}
+			}
+		}
+	}
+	// %x
+	x := makeDecimal("-0.1")
+	if got, want := fmt.Sprintf("%x", x), "-0x1.99999ap-04"; got != want {
+		t.Errorf("%%x: got %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprintf("%.2X", x), "-0X1.9AP-04"; got != want {
+		t.Errorf("%%.2X: got %s, want %s", got, want)
+	}
+}
+
+// TestDecimalTextBaseRoundTrip checks that the result of TextBase with a
+// negative precision reads back as the original value.
+func TestDecimalTextBaseRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	n := 2000
+	if testing.Short() {
+		n = 200
+	}
+	for i := 0; i < n; i++ {
+		x := rndDecimal(r, uint(1+r.Intn(40)), 50)
+		x.SetMode(floatConvModes[r.Intn(len(floatConvModes))])
+		x.SetPrec(uint(1 + r.Intn(40)))
+		base := 2 + r.Intn(MaxBase-1)
+		var formats []byte
+		switch {
+		case base <= 14:
+			formats = []byte{'f', 'e', 'p'}
+		case base <= 25:
+			formats = []byte{'f', 'p'}
+		default:
+			formats = []byte{'f'}
+		}
+		for _, format := range formats {
+			s := x.TextBase(base, format, -1)
+			y, _, err := ParseDecimal(s, base, uint(x.Prec()), ToNearestEven)
+			if err != nil {
+				t.Fatalf("%s.TextBase(%d, %c, -1) = %s: %v", x, base, format, s, err)
+			}
+			if y.Cmp(x) != 0 {
+				t.Fatalf("%s.TextBase(%d, %c, -1) = %s reads back as %s", x.Text('g', -1), base, format, s, y.Text('g', -1))
+			}
+			if base%10 == 0 && format != 'p' && y.Acc() != Exact {
+				t.Fatalf("%s.TextBase(%d, %c, -1) = %s is not exact", x.Text('g', -1), base, format, s)
+			}
+		}
+		// 'x' format
+		s := x.Text('x', -1)
+		y, _, err := ParseDecimal(s, 0, uint(x.Prec()), ToNearestEven)
+		if err != nil || y.Cmp(x) != 0 {
+			t.Fatalf("%s.Text('x', -1) = %s reads back as %s (%v)", x.Text('g', -1), s, y, err)
+		}
+	}
+}
+
+// TestDecimalTextFTiny checks the rounding of values that are smaller than the
+// last requested digit in 'f' format.
+func TestDecimalTextFTiny(t *testing.T) {
+	for _, test := range []struct {
+		x    string
+		mode RoundingMode
+		prec int
+		want string
+	}{
+		{"0.6", ToNearestEven, 0, "1"},
+		{"0.5", ToNearestEven, 0, "0"},
+		{"0.5", ToNearestAway, 0, "1"},
+		{"-0.4", ToNearestEven, 0, "-0"},
+		{"-0.4", ToNegativeInf, 0, "-1"},
+		{"0.06", ToNearestEven, 1, "0.1"},
+		{"0.0006", ToNearestEven, 1, "0.0"},
+		{"0.0006", AwayFromZero, 1, "0.1"},
+		{"0.0006", ToPositiveInf, 3, "0.001"},
+		{"-7e-100", AwayFromZero, 2, "-0.01"},
+		{"-7e-100", ToZero, 2, "-0.00"},
+		{"-7e-100", ToPositiveInf, 2, "-0.00"},
+		{"0.9", ToZero, 0, "0"},
+		{"0.9", ToNegativeInf, 0, "0"},
+		{"0.009", ToNearestEven, 2, "0.01"},
+		{"0.005", ToNearestEven, 2, "0.00"},
+		{"0.005", ToNearestAway, 2, "0.01"},
+		{"0.0051", ToNearestEven, 2, "0.01"},
+		{"-0.005", ToNearestAway, 2, "-0.01"},
+		{"0.01", ToZero, 2, "0.01"}, // not tiny
+	} {
+		x := makeDecimal(test.x).SetMode(test.mode)
+		if got := x.Text('f', test.prec); got != test.want {
+			t.Errorf("%s.Text('f', %d) (%s) = %s; want %s", test.x, test.prec, test.mode, got, test.want)
+		}
+		if got := fmt.Sprintf("%.*f", test.prec, x); got != test.want {
+			t.Errorf("Sprintf(%%.%df, %s) (%s) = %s; want %s", test.prec, test.x, test.mode, got, test.want)
+		}
+		// x must not be modified
+		if got := x.Text('g', -1); got != makeDecimal(test.x).Text('g', -1) {
+			t.Errorf("%s.Text('f', %d) (%s) modified x to %s", test.x, test.prec, test.mode, got)
+		}
+	}
+}
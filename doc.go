@@ -76,6 +76,14 @@ better controlled. Instead of having to allocate new memory for each result, an
 operation can reuse the space allocated for the result value, and overwrite that
 value with the new result in the process.)
 
+Temporary storage needed by operations is taken from pools shared by all
+goroutines. Latency sensitive code can instead perform Add, Sub, Mul, Quo and
+Sqrt with the methods of a Workspace, which take all temporaries from
+caller-owned memory:
+
+    var w decimal.Workspace
+    w.Mul(z, x, y) // z = x*y, no allocation once w has grown
+
 Notational convention: Incoming method parameters (including the receiver) are
 named consistently in the API to clarify their use. Incoming operands are
 usually named x, y, a, b, and so on, but never z. A parameter specifying the
@@ -0,0 +1,589 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// This file implements the arithmetic shared by the fixed-size Decimal64 and
+// Decimal128 types.
+//
+// Coefficients are unpacked into a wide value: a small array of Words in base
+// _DB, large enough for the exact product of two Decimal128 coefficients or
+// for a Decimal128 dividend scaled for division. Operations on wide values
+// only use the scalar primitives from dec_arith, so that they never allocate.
+
+// fixedWords is the length of a wide value: 2×34+1 digits for the scaled
+// dividend of a Decimal128 division, plus one Word for the normalization step
+// of the division.
+const fixedWords = (2*34+1+_DW-1)/_DW + 1
+
+// A wide is an unsigned integer in base _DB, least significant Word first.
+type wide [fixedWords]Word
+
+func (z *wide) setUint64(x uint64) {
+	*z = wide{}
+	for i := 0; x != 0; i++ {
+		z[i] = Word(x % _DB)
+		x /= _DB
+	}
+}
+
+// uint64 returns z as a uint64. The result is undefined if z doesn't fit.
+func (z *wide) uint64() (x uint64) {
+	for i := len(z) - 1; i >= 0; i-- {
+		x = x*_DB + uint64(z[i])
+	}
+	return x
+}
+
+// top returns the index of the most significant non-zero Word of z, or -1 if
+// z is zero.
+func (z *wide) top() int {
+	i := len(z) - 1
+	for i >= 0 && z[i] == 0 {
+		i--
+	}
+	return i
+}
+
+func (z *wide) isZero() bool {
+	return z.top() < 0
+}
+
+// digits returns the number of decimal digits of z.
+func (z *wide) digits() uint {
+	i := z.top()
+	if i < 0 {
+		return 0
+	}
+	return uint(i)*_DW + decDigits(uint(z[i]))
+}
+
+func (z *wide) cmp(y *wide) int {
+	for i := len(z) - 1; i >= 0; i-- {
+		if z[i] != y[i] {
+			if z[i] < y[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// z = z + y. The sum must fit.
+func (z *wide) add(y *wide) {
+	var c Word
+	for i := range z {
+		z[i], c = add10WWW_g(z[i], y[i], c)
+	}
+}
+
+// z = z - y, with z >= y.
+func (z *wide) sub(y *wide) {
+	var b Word
+	for i := range z {
+		z[i], b = sub10WWW_g(z[i], y[i], b)
+	}
+}
+
+func (z *wide) addWord(y Word) {
+	for i := 0; y != 0 && i < len(z); i++ {
+		z[i], y = add10WWW_g(z[i], y, 0)
+	}
+}
+
+// z = z - y, with z >= y.
+func (z *wide) subWord(y Word) {
+	for i := 0; y != 0 && i < len(z); i++ {
+		z[i], y = sub10WWW_g(z[i], y, 0)
+	}
+}
+
+// mulAddWord sets z = z×y + r and returns the carry.
+func (z *wide) mulAddWord(y, r Word) Word {
+	for i := range z {
+		r, z[i] = mulAdd10WWW_g(z[i], y, r)
+	}
+	return r
+}
+
+// divWord sets z = z/y and returns the remainder.
+func (z *wide) divWord(y Word) (r Word) {
+	for i := len(z) - 1; i >= 0; i-- {
+		z[i], r = div10WW(r, z[i], y)
+	}
+	return r
+}
+
+// shl sets z = z × 10**s. The result must fit.
+func (z *wide) shl(s uint) {
+	n := int(s / _DW)
+	if n >= len(z) {
+		*z = wide{}
+		return
+	}
+	if n > 0 {
+		copy(z[n:], z[:])
+		for i := 0; i < n; i++ {
+			z[i] = 0
+		}
+	}
+	if s %= _DW; s != 0 {
+		z.mulAddWord(pow10(s), 0)
+	}
+}
+
+// shr sets z = z / 10**s, truncated, and reports whether any of the discarded
+// digits was non-zero.
+func (z *wide) shr(s uint) (sticky bool) {
+	n := int(s / _DW)
+	if n >= len(z) {
+		sticky = !z.isZero()
+		*z = wide{}
+		return sticky
+	}
+	if n > 0 {
+		for _, w := range z[:n] {
+			if w != 0 {
+				sticky = true
+			}
+		}
+		copy(z[:], z[n:])
+		for i := len(z) - n; i < len(z); i++ {
+			z[i] = 0
+		}
+	}
+	if s %= _DW; s != 0 && z.divWord(pow10(s)) != 0 {
+		sticky = true
+	}
+	return sticky
+}
+
+// trim removes the trailing zero digits of z != 0 and returns their number.
+func (z *wide) trim() uint {
+	var s uint
+	i := 0
+	for z[i] == 0 {
+		i++
+		s += _DW
+	}
+	s += trailingZeroDigits(uint(z[i]))
+	z.shr(s)
+	return s
+}
+
+// z = x × y. The product must fit.
+func (z *wide) mul(x, y *wide) {
+	var t wide
+	nx, ny := x.top()+1, y.top()+1
+	for j := 0; j < ny; j++ {
+		d := y[j]
+		if d == 0 {
+			continue
+		}
+		var c Word
+		for i := 0; i < nx && i+j < len(t); i++ {
+			hi, lo := mulAdd10WWW_g(x[i], d, c)
+			t[i+j], c = add10WWW_g(t[i+j], lo, 0)
+			c += hi
+		}
+		if k := nx + j; k < len(t) {
+			t[k] = c
+		}
+	}
+	*z = t
+}
+
+// div sets z = u/v, truncated, and reports whether the remainder is non-zero.
+// v must not be zero and the most significant Word of u must be zero.
+func (z *wide) div(u, v *wide) (sticky bool) {
+	n := v.top() + 1
+	if n == 1 {
+		*z = *u
+		return z.divWord(v[0]) != 0
+	}
+	m := u.top() + 1 - n
+	if m < 0 {
+		sticky = !u.isZero()
+		*z = wide{}
+		return sticky
+	}
+
+	// Knuth's Algorithm D, like dec.divBasic. Normalize u and v such that the
+	// most significant Word of v is >= _DB/2.
+	d := _DB / (v[n-1] + 1)
+	un, vn := *u, *v
+	un.mulAddWord(d, 0)
+	vn.mulAddWord(d, 0)
+	vn1, vn2 := vn[n-1], vn[n-2]
+
+	var q wide
+	for j := m; j >= 0; j-- {
+		qhat := Word(_DMax)
+		if ujn := un[j+n]; ujn != vn1 {
+			var rhat Word
+			qhat, rhat = div10WW(ujn, un[j+n-1], vn1)
+			// x1 | x2 = q̂v_{n-2}
+			x1, x2 := mul10WW(qhat, vn2)
+			// test if q̂v_{n-2} > br̂ + u_{j+n-2}
+			for greaterThan(x1, x2, rhat, un[j+n-2]) {
+				qhat--
+				prevRhat := rhat
+				rhat += vn1
+				// v[n-1] >= 0, so this tests for overflow.
+				if rhat < prevRhat {
+					break
+				}
+				x1, x2 = mul10WW(qhat, vn2)
+			}
+		}
+
+		// u = u - q̂v × _DB**j. If the subtraction borrows, q̂ was one too
+		// large: add v back.
+		var c, b Word
+		for i := 0; i < n; i++ {
+			var lo Word
+			c, lo = mulAdd10WWW_g(qhat, vn[i], c)
+			un[j+i], b = sub10WWW_g(un[j+i], lo, b)
+		}
+		un[j+n], b = sub10WWW_g(un[j+n], c, b)
+		if b != 0 {
+			qhat--
+			c = 0
+			for i := 0; i < n; i++ {
+				un[j+i], c = add10WWW_g(un[j+i], vn[i], c)
+			}
+			un[j+n], _ = add10WWW_g(un[j+n], 0, c)
+		}
+		q[j] = qhat
+	}
+	*z = q
+
+	for _, w := range un[:n] {
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fixedFormat describes a fixed-size decimal format.
+type fixedFormat struct {
+	prec uint  // number of digits of the coefficient
+	qmin int64 // minimum exponent of the least significant digit
+	emax int64 // maximum exponent of the most significant digit
+}
+
+var (
+	decimal64Format  = fixedFormat{16, -398, 384}
+	decimal128Format = fixedFormat{34, -6176, 6144}
+)
+
+// A fixed is an unpacked Decimal64 or Decimal128 value (-1)**neg × c × 10**q.
+// If form is zero or inf, c and q are zero.
+type fixed struct {
+	c    wide
+	q    int64
+	form form
+	neg  bool
+}
+
+// setZero sets z to ±0 with the given sign.
+func (z *fixed) setZero(neg bool) {
+	*z = fixed{neg: neg}
+}
+
+// setInf sets z to ±Inf with the given sign.
+func (z *fixed) setInf(neg bool) {
+	*z = fixed{form: inf, neg: neg}
+}
+
+// round rounds z to f.prec digits and an exponent of at least max(minq,
+// f.qmin), strips trailing zeros from the coefficient, and returns the
+// accuracy of the result. If sticky is set, the exact value is slightly
+// larger in magnitude than z; the caller must then ensure that z has more than
+// f.prec digits or an exponent below minq.
+func (f *fixedFormat) round(z *fixed, sticky bool, minq int64, mode RoundingMode) Accuracy {
+	if z.form != finite {
+		return Exact
+	}
+	if minq < f.qmin {
+		minq = f.qmin
+	}
+	acc := Exact
+	k := int64(z.c.digits()) - int64(f.prec)
+	if d := minq - z.q; d > k {
+		k = d
+	}
+	if k > 0 {
+		if z.c.shr(uint(k - 1)) {
+			sticky = true
+		}
+		rdigit := z.c.divWord(10)
+		if rdigit != 0 || sticky {
+			lsd := z.c[0] % 10
+			inc := false
+			switch mode {
+			case ToNegativeInf:
+				inc = z.neg
+			case ToZero:
+				// nothing to do
+			case ToNearestEven:
+				inc = rdigit > 5 || (rdigit == 5 && (sticky || lsd&1 != 0))
+			case ToNearestAway:
+				inc = rdigit >= 5
+			case AwayFromZero:
+				inc = true
+			case ToPositiveInf:
+				inc = !z.neg
+			case ToNearestTowardZero:
+				inc = rdigit > 5 || (rdigit == 5 && sticky)
+			case Round05Up:
+				inc = lsd == 0 || lsd == 5
+			case ToOdd:
+				inc = lsd&1 == 0
+			default:
+				panic("unreachable")
+			}
+			acc = makeAcc(inc != z.neg)
+			if inc {
+				z.c.addWord(1)
+				if z.c.digits() > f.prec {
+					z.c.divWord(10)
+					k++
+				}
+			}
+		}
+		z.q += k
+	}
+
+	if z.c.isZero() {
+		z.setZero(z.neg)
+		return acc
+	}
+	z.q += int64(z.c.trim())
+	if z.q+int64(z.c.digits())-1 > f.emax {
+		z.setInf(z.neg)
+		return makeAcc(!z.neg)
+	}
+	return acc
+}
+
+// add sets z = z + y, or z - y if sub is set, rounded with the given mode.
+func (f *fixedFormat) add(z, y *fixed, sub bool, mode RoundingMode) {
+	y.neg = y.neg != sub
+	if z.form != finite || y.form != finite {
+		switch {
+		case z.form == inf && y.form == inf && z.neg != y.neg:
+			if sub {
+				panic(ErrNaN{"subtraction of infinities with equal signs"})
+			}
+			panic(ErrNaN{"addition of infinities with opposite signs"})
+		case z.form == inf:
+		case y.form == inf:
+			*z = *y
+		case z.form == zero && y.form == zero:
+			if z.neg != y.neg {
+				z.neg = mode == ToNegativeInf
+			}
+		case z.form == zero:
+			*z = *y
+		}
+		return
+	}
+
+	// Make z the operand with the largest exponent, then shift its
+	// coefficient left by up to f.prec+2 digits. If that is not enough to
+	// align the operands, y is smaller than the rounding digit of the result
+	// and is shifted right, its discarded digits only recorded as sticky.
+	if z.q < y.q {
+		*z, *y = *y, *z
+	}
+	d := z.q - y.q
+	s := int64(f.prec) + 2 - int64(z.c.digits())
+	if s > d {
+		s = d
+	}
+	z.c.shl(uint(s))
+	z.q -= s
+	sticky := y.c.shr(uint(d - s))
+
+	switch {
+	case z.neg == y.neg:
+		z.c.add(&y.c)
+	case sticky:
+		// z - (y + ε) = (z - y - 1) + (1 - ε), with 0 < ε < 1
+		z.c.sub(&y.c)
+		z.c.subWord(1)
+	default:
+		switch z.c.cmp(&y.c) {
+		case 0:
+			z.setZero(mode == ToNegativeInf)
+			return
+		case -1:
+			y.c.sub(&z.c)
+			z.c, z.neg = y.c, y.neg
+		default:
+			z.c.sub(&y.c)
+		}
+	}
+	f.round(z, sticky, f.qmin, mode)
+}
+
+// mul sets z = z × y, rounded with the given mode.
+func (f *fixedFormat) mul(z, y *fixed, mode RoundingMode) {
+	neg := z.neg != y.neg
+	if z.form == finite && y.form == finite {
+		z.c.mul(&z.c, &y.c)
+		z.q += y.q
+		z.neg = neg
+		f.round(z, false, f.qmin, mode)
+		return
+	}
+	if z.form == zero && y.form == inf || z.form == inf && y.form == zero {
+		panic(ErrNaN{"multiplication of zero with infinity"})
+	}
+	if z.form == inf || y.form == inf {
+		z.setInf(neg)
+	} else {
+		z.setZero(neg)
+	}
+}
+
+// quo sets z = z / y, rounded with the given mode.
+func (f *fixedFormat) quo(z, y *fixed, mode RoundingMode) {
+	neg := z.neg != y.neg
+	if z.form == finite && y.form == finite {
+		// Scale z so that the quotient has at least f.prec+1 digits.
+		k := int64(f.prec) + 1 + int64(y.c.digits()) - int64(z.c.digits())
+		z.c.shl(uint(k))
+		z.q -= y.q + k
+		z.neg = neg
+		sticky := z.c.div(&z.c, &y.c)
+		f.round(z, sticky, f.qmin, mode)
+		return
+	}
+	if z.form == y.form {
+		panic(ErrNaN{"division of zero by zero or infinity by infinity"})
+	}
+	if z.form == zero || y.form == inf {
+		z.setZero(neg)
+	} else {
+		z.setInf(neg)
+	}
+}
+
+// ord classifies x and returns:
+//
+//	-2 if x == -Inf
+//	-1 if x < 0
+//	 0 if x == 0 (signed or unsigned)
+//	+1 if x > 0
+//	+2 if x == +Inf
+//
+func (x *fixed) ord() int {
+	var m int
+	switch x.form {
+	case finite:
+		m = 1
+	case zero:
+		return 0
+	case inf:
+		m = 2
+	}
+	if x.neg {
+		m = -m
+	}
+	return m
+}
+
+func (x *fixed) cmp(y *fixed) int {
+	mx := x.ord()
+	my := y.ord()
+	switch {
+	case mx < my:
+		return -1
+	case mx > my:
+		return +1
+	}
+	// mx == my
+	switch mx {
+	case -1:
+		return y.ucmp(x)
+	case +1:
+		return x.ucmp(y)
+	}
+	return 0
+}
+
+// ucmp compares the absolute values of the finite values x and y.
+func (x *fixed) ucmp(y *fixed) int {
+	nx, ny := int64(x.c.digits()), int64(y.c.digits())
+	if ex, ey := x.q+nx, y.q+ny; ex != ey {
+		if ex < ey {
+			return -1
+		}
+		return 1
+	}
+	a, b := x.c, y.c
+	if nx < ny {
+		a.shl(uint(ny - nx))
+	} else {
+		b.shl(uint(nx - ny))
+	}
+	return a.cmp(&b)
+}
+
+// setDecimal sets z to x rounded to f with the given mode, and returns the
+// accuracy of the result.
+func (f *fixedFormat) setDecimal(z *fixed, x *Decimal, mode RoundingMode) Accuracy {
+	*z = fixed{form: x.form, neg: x.neg}
+	if x.form != finite {
+		return Exact
+	}
+	// f.prec+1 digits of x are enough for rounding; the Words below them are
+	// only needed for the sticky bit. The most significant Word of x.mant
+	// always has _DW digits.
+	m := x.mant
+	sticky := false
+	if n := int((f.prec + _DW) / _DW); len(m) > n {
+		for _, w := range m[:len(m)-n] {
+			if w != 0 {
+				sticky = true
+				break
+			}
+		}
+		m = m[len(m)-n:]
+	}
+	copy(z.c[:], m)
+	z.q = int64(x.exp) - int64(len(m))*_DW
+	return f.round(z, sticky, f.qmin, mode)
+}
+
+// parse sets z to the value of s, parsed like Decimal.Parse with base 10 and
+// rounded to f with the given mode. s is first rounded to odd with two more
+// digits than f.prec: the final rounding to f.prec digits, or fewer for
+// subnormal values, is then free of double rounding errors.
+func (f *fixedFormat) parse(z *fixed, s string, mode RoundingMode) error {
+	var d Decimal
+	if _, _, err := d.SetMode(ToOdd).SetPrec(f.prec+2).Parse(s, 10); err != nil {
+		return err
+	}
+	f.setDecimal(z, &d, mode)
+	return nil
+}
+
+// decimal sets z to x, rounded to z's precision and mode.
+func (x *fixed) decimal(z *Decimal) {
+	z.acc = Exact
+	z.form = x.form
+	z.neg = x.neg
+	if x.form != finite {
+		return
+	}
+	n := x.c.top() + 1
+	z.mant = z.mant.make(n)
+	copy(z.mant, x.c[:n])
+	z.setExpAndRound(x.q+int64(n)*_DW-dnorm(z.mant), 0)
+}
@@ -0,0 +1,436 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// randFixedOperand returns a random decimal string with at most prec digits.
+func randFixedOperand(r *rand.Rand, prec int) string {
+	var sb strings.Builder
+	if r.Intn(2) == 0 {
+		sb.WriteByte('-')
+	}
+	n := 1 + r.Intn(prec)
+	switch r.Intn(4) {
+	case 0:
+		sb.WriteString(strings.Repeat("9", n))
+	case 1:
+		sb.WriteByte('1')
+		sb.WriteString(strings.Repeat("0", n-1))
+	default:
+		sb.WriteByte(byte('1' + r.Intn(9)))
+		for i := 1; i < n; i++ {
+			sb.WriteByte(byte('0' + r.Intn(10)))
+		}
+	}
+	sb.WriteByte('e')
+	e := r.Intn(40) - 20
+	if r.Intn(8) == 0 {
+		e = r.Intn(200) - 100
+	}
+	sb.WriteString(strconv.Itoa(e))
+	return sb.String()
+}
+
+// fixedOp computes x op y with a fixed-size type. It returns the result as a
+// Decimal, and whether the result is in canonical form.
+type fixedOp func(op byte, x, y string, mode RoundingMode) (*Decimal, bool)
+
+func decimal64Op(op byte, x, y string, mode RoundingMode) (*Decimal, bool) {
+	a, _ := ParseDecimal64(x, ToNearestEven)
+	b, _ := ParseDecimal64(y, ToNearestEven)
+	var z Decimal64
+	switch op {
+	case '+':
+		z = a.Add(b, mode)
+	case '-':
+		z = a.Sub(b, mode)
+	case '*':
+		z = a.Mul(b, mode)
+	case '/':
+		z = a.Quo(b, mode)
+	}
+	d := z.Decimal(nil)
+	c, _ := d.Decimal64()
+	return d, c == z
+}
+
+func decimal128Op(op byte, x, y string, mode RoundingMode) (*Decimal, bool) {
+	a, _ := ParseDecimal128(x, ToNearestEven)
+	b, _ := ParseDecimal128(y, ToNearestEven)
+	var z Decimal128
+	switch op {
+	case '+':
+		z = a.Add(b, mode)
+	case '-':
+		z = a.Sub(b, mode)
+	case '*':
+		z = a.Mul(b, mode)
+	case '/':
+		z = a.Quo(b, mode)
+	}
+	d := z.Decimal(nil)
+	c, _ := d.Decimal128()
+	return d, c == z
+}
+
+func testFixedArith(t *testing.T, prec int, f fixedOp) {
+	r := rand.New(rand.NewSource(int64(prec)))
+	n := 20000
+	if testing.Short() {
+		n = 2000
+	}
+	for i := 0; i < n; i++ {
+		xs, ys := randFixedOperand(r, prec), randFixedOperand(r, prec)
+		x, _ := new(Decimal).SetString(xs)
+		y, _ := new(Decimal).SetString(ys)
+		for mode := ToNearestEven; mode <= ToOdd; mode++ {
+			for _, op := range []byte("+-*/") {
+				want := new(Decimal).SetPrec(uint(prec)).SetMode(mode)
+				switch op {
+				case '+':
+					want.Add(x, y)
+				case '-':
+					want.Sub(x, y)
+				case '*':
+					want.Mul(x, y)
+				case '/':
+					want.Quo(x, y)
+				}
+				got, canonical := f(op, xs, ys, mode)
+				if got.Cmp(want) != 0 || got.Signbit() != want.Signbit() {
+					t.Fatalf("%s %c %s (%s) = %s, want %s", xs, op, ys, mode, got.Text('e', -1), want.Text('e', -1))
+				}
+				if !canonical {
+					t.Fatalf("%s %c %s (%s) = %s: result not in canonical form", xs, op, ys, mode, got.Text('e', -1))
+				}
+			}
+		}
+	}
+}
+
+func TestDecimal64Arith(t *testing.T) {
+	testFixedArith(t, 16, decimal64Op)
+}
+
+func TestDecimal128Arith(t *testing.T) {
+	testFixedArith(t, 34, decimal128Op)
+}
+
+func TestDecimal64Range(t *testing.T) {
+	for _, test := range []struct {
+		x, y string
+		op   byte
+		mode RoundingMode
+		want string
+	}{
+		// overflow
+		{"9.999999999999999e384", "1e369", '+', ToNearestEven, "+Inf"},
+		{"9.999999999999999e384", "1e368", '+', ToNearestEven, "9.999999999999999e+384"},
+		{"-1e200", "1e200", '*', ToZero, "-Inf"},
+		{"1e384", "0.1", '/', ToNearestEven, "+Inf"},
+		// gradual underflow
+		{"1e-383", "10", '/', ToNearestEven, "1e-384"},
+		{"1.234567890123456e-383", "1000", '/', ToNearestEven, "1.234567890123e-386"},
+		{"1e-398", "2", '/', ToNearestEven, "0"},
+		{"-1e-398", "2", '/', ToNearestEven, "-0"},
+		{"3e-398", "2", '/', ToNearestEven, "2e-398"},
+		{"1e-398", "2", '/', AwayFromZero, "1e-398"},
+		{"1e-398", "1e-10", '*', ToPositiveInf, "1e-398"},
+		// zeros
+		{"0", "-0", '+', ToNearestEven, "0"},
+		{"0", "-0", '+', ToNegativeInf, "-0"},
+		{"-0", "-0", '+', ToNearestEven, "-0"},
+		{"1.5", "1.5", '-', ToNearestEven, "0"},
+		{"1.5", "1.5", '-', ToNegativeInf, "-0"},
+		{"0", "-3", '*', ToNearestEven, "-0"},
+		{"-0", "7", '/', ToNearestEven, "-0"},
+		// infinities
+		{"Inf", "-1e300", '+', ToNearestEven, "+Inf"},
+		{"1", "-Inf", '-', ToNearestEven, "+Inf"},
+		{"-2", "0", '/', ToNearestEven, "-Inf"},
+		{"2", "-Inf", '/', ToNearestEven, "-0"},
+		{"-Inf", "-Inf", '*', ToNearestEven, "+Inf"},
+	} {
+		got, _ := decimal64Op(test.op, test.x, test.y, test.mode)
+		if s := got.Text('g', -1); s != test.want {
+			t.Errorf("%s %c %s (%s) = %s, want %s", test.x, test.op, test.y, test.mode, s, test.want)
+		}
+	}
+}
+
+func TestDecimal128Range(t *testing.T) {
+	for _, test := range []struct {
+		x, y string
+		op   byte
+		mode RoundingMode
+		want string
+	}{
+		{"9.999999999999999999999999999999999e6144", "1e6111", '+', ToNearestEven, "+Inf"},
+		{"9.999999999999999999999999999999999e6144", "1e6110", '+', ToNearestEven, "9.999999999999999999999999999999999e+6144"},
+		{"1e-6143", "1e-5", '*', ToNearestEven, "1e-6148"},
+		{"1e-6176", "2", '/', ToNearestEven, "0"},
+		{"1e-6176", "-1.5", '/', ToNearestEven, "-1e-6176"},
+		{"1", "3", '/', ToNearestEven, "0.3333333333333333333333333333333333"},
+		{"2", "3", '/', ToZero, "0.6666666666666666666666666666666666"},
+		{"2", "3", '/', ToNearestEven, "0.6666666666666666666666666666666667"},
+	} {
+		got, _ := decimal128Op(test.op, test.x, test.y, test.mode)
+		if s := got.Text('g', -1); s != test.want {
+			t.Errorf("%s %c %s (%s) = %s, want %s", test.x, test.op, test.y, test.mode, s, test.want)
+		}
+	}
+}
+
+func TestDecimal64NaN(t *testing.T) {
+	inf, _ := ParseDecimal64("Inf", ToNearestEven)
+	var zero Decimal64
+	for _, test := range []struct {
+		name string
+		f    func()
+	}{
+		{"Inf - Inf", func() { inf.Sub(inf, ToNearestEven) }},
+		{"Inf + -Inf", func() { inf.Add(inf.Neg(), ToNearestEven) }},
+		{"0 * Inf", func() { zero.Mul(inf, ToNearestEven) }},
+		{"0 / 0", func() { zero.Quo(zero, ToNearestEven) }},
+		{"Inf / Inf", func() { inf.Quo(inf, ToNearestEven) }},
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(ErrNaN); !ok {
+					t.Errorf("%s: expected ErrNaN panic", test.name)
+				}
+			}()
+			test.f()
+		}()
+	}
+}
+
+func TestDecimal64Quantize(t *testing.T) {
+	for _, test := range []struct {
+		x    string
+		exp  int
+		mode RoundingMode
+		want string
+	}{
+		{"1.2345", -2, ToNearestEven, "1.23"},
+		{"1.235", -2, ToNearestEven, "1.24"},
+		{"1.245", -2, ToNearestEven, "1.24"},
+		{"1.245", -2, ToNearestAway, "1.25"},
+		{"-1.241", -2, ToNegativeInf, "-1.25"},
+		{"-1.249", -2, ToZero, "-1.24"},
+		{"9.999", -2, ToNearestEven, "10"},
+		{"0.004", -2, ToNearestEven, "0"},
+		{"-0.004", -2, ToNearestEven, "-0"},
+		{"0.004", -2, ToPositiveInf, "0.01"},
+		{"1234.5", 2, ToNearestEven, "1200"},
+		{"1250", 2, ToNearestEven, "1200"},
+		{"1200", -2, ToNearestEven, "1200"},
+		{"Inf", 0, ToNearestEven, "+Inf"},
+	} {
+		x, _ := ParseDecimal64(test.x, ToNearestEven)
+		if s := x.Quantize(test.exp, test.mode).String(); s != test.want {
+			t.Errorf("%s.Quantize(%d, %s) = %s, want %s", test.x, test.exp, test.mode, s, test.want)
+		}
+		y, _ := ParseDecimal128(test.x, ToNearestEven)
+		if s := y.Quantize(test.exp, test.mode).String(); s != test.want {
+			t.Errorf("Decimal128 %s.Quantize(%d, %s) = %s, want %s", test.x, test.exp, test.mode, s, test.want)
+		}
+	}
+}
+
+func TestDecimal64Conv(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		mode RoundingMode
+		d64  string
+		d128 string
+	}{
+		{"0", ToNearestEven, "0", "0"},
+		{"-0", ToNearestEven, "-0", "-0"},
+		{"-Inf", ToNearestEven, "-Inf", "-Inf"},
+		{"123.4500", ToNearestEven, "123.45", "123.45"},
+		{"1e-398", ToNearestEven, "1e-398", "1e-398"},
+		{"1e-399", ToNearestEven, "0", "1e-399"},
+		{"1e385", ToNearestEven, "+Inf", "1e+385"},
+		{"12345678901234567890123456789012345678", ToNearestEven, "1.234567890123457e+37", "1.234567890123456789012345678901235e+37"},
+		{"12345678901234567890123456789012345678", ToZero, "1.234567890123456e+37", "1.234567890123456789012345678901234e+37"},
+		{"0.1000000000000000000000000000000000000000001", ToPositiveInf, "0.1000000000000001", "0.1000000000000000000000000000000001"},
+	} {
+		x, err := ParseDecimal64(test.s, test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := x.String(); s != test.d64 {
+			t.Errorf("ParseDecimal64(%s, %s) = %s, want %s", test.s, test.mode, s, test.d64)
+		}
+		y, err := ParseDecimal128(test.s, test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := y.String(); s != test.d128 {
+			t.Errorf("ParseDecimal128(%s, %s) = %s, want %s", test.s, test.mode, s, test.d128)
+		}
+		if z := y.Decimal64(test.mode); z != x {
+			t.Errorf("Decimal128(%s).Decimal64(%s) = %s, want %s", test.s, test.mode, z, x)
+		}
+		if z := x.Decimal128().Decimal64(ToNearestEven); z != x {
+			t.Errorf("Decimal64(%s).Decimal128().Decimal64() = %s, want %s", test.s, z, x)
+		}
+	}
+
+	if _, err := ParseDecimal64("1.2.3", ToNearestEven); err == nil {
+		t.Error("ParseDecimal64(1.2.3): expected error")
+	}
+	if s := NewDecimal64(-12345, -2).Text('f', 3); s != "-123.450" {
+		t.Errorf("NewDecimal64(-12345, -2).Text('f', 3) = %s, want -123.450", s)
+	}
+	if s := NewDecimal128(-9223372036854775808, 3).String(); s != "-9.223372036854775808e+21" {
+		t.Errorf("NewDecimal128(MinInt64, 3) = %s, want -9.223372036854775808e+21", s)
+	}
+	if x, acc := NewDecimal(12345678901234567, 0).Decimal64(); x.String() != "1.234567890123457e+16" || acc != Above {
+		t.Errorf("NewDecimal(12345678901234567, 0).Decimal64() = %s, %s, want 1.234567890123457e+16, Above", x, acc)
+	}
+	if d := NewDecimal64(1, -1).Decimal(new(Decimal).SetPrec(100)); d.Prec() != 100 || d.String() != "0.1" {
+		t.Errorf("Decimal64.Decimal: got %s with prec %d, want 0.1 with prec 100", d, d.Prec())
+	}
+}
+
+// TestParseDecimalDoubleRounding checks that ParseDecimal64 and
+// ParseDecimal128 round their input only once.
+func TestParseDecimalDoubleRounding(t *testing.T) {
+	// a tie at 17 digits, followed by a nonzero digit past the 34th
+	s := "1.0000000000000005" + strings.Repeat("0", 70) + "1"
+	for _, test := range []struct {
+		mode RoundingMode
+		want string
+	}{
+		{ToNearestEven, "1.000000000000001"},
+		{ToNearestTowardZero, "1.000000000000001"},
+		{ToZero, "1"},
+	} {
+		if x, _ := ParseDecimal64(s, test.mode); x.String() != test.want {
+			t.Errorf("ParseDecimal64(%s, %s) = %s, want %s", s, test.mode, x, test.want)
+		}
+	}
+	// the same for a subnormal Decimal128, rounded to fewer digits
+	s = "5" + strings.Repeat("0", 40) + "1e-6218"
+	if x, _ := ParseDecimal128(s, ToNearestEven); x.String() != "1e-6176" {
+		t.Errorf("ParseDecimal128(%s) = %s, want 1e-6176", s, x)
+	}
+	if x, _ := ParseDecimal128(s, ToZero); x.String() != "0" {
+		t.Errorf("ParseDecimal128(%s, ToZero) = %s, want 0", s, x)
+	}
+}
+
+func TestDecimal64Cmp(t *testing.T) {
+	vals := []string{"-Inf", "-1e300", "-1.5", "-1e-398", "0", "1e-398", "1.4999999999999999", "1.5", "15e-1", "1.5000000000000001", "2", "1e384", "Inf"}
+	for i, xs := range vals {
+		x, _ := ParseDecimal64(xs, ToNearestEven)
+		x128, _ := ParseDecimal128(xs, ToNearestEven)
+		for j, ys := range vals {
+			y, _ := ParseDecimal64(ys, ToNearestEven)
+			y128, _ := ParseDecimal128(ys, ToNearestEven)
+			want := x.Decimal(nil).Cmp(y.Decimal(nil))
+			if got := x.Cmp(y); got != want {
+				t.Errorf("(%d, %d) %s.Cmp(%s) = %d, want %d", i, j, xs, ys, got, want)
+			}
+			if got := x128.Cmp(y128); got != x128.Decimal(nil).Cmp(y128.Decimal(nil)) {
+				t.Errorf("(%d, %d) Decimal128 %s.Cmp(%s) = %d", i, j, xs, ys, got)
+			}
+		}
+	}
+}
+
+func TestDecimal64Comparable(t *testing.T) {
+	m := map[Decimal64]int{
+		NewDecimal64(100, 0): 1,
+	}
+	for _, x := range []Decimal64{
+		NewDecimal64(1, 2),
+		NewDecimal64(10, 1),
+		NewDecimal64(1000, -1),
+		NewDecimal64(25, 0).Mul(NewDecimal64(4, 0), ToNearestEven),
+		NewDecimal64(1, 3).Sub(NewDecimal64(900, 0), ToNearestEven),
+	} {
+		if m[x] != 1 {
+			t.Errorf("m[%s] = %d, want 1", x, m[x])
+		}
+	}
+	if NewDecimal64(0, 5) != (Decimal64{}) || NewDecimal128(0, -5) != (Decimal128{}) {
+		t.Error("zero values are not canonical")
+	}
+	if NewDecimal64(1, 2).Decimal128() != NewDecimal128(100, 0) {
+		t.Error("Decimal64.Decimal128: result not in canonical form")
+	}
+}
+
+func TestDecimal64Allocs(t *testing.T) {
+	x := NewDecimal64(12345678, -3)
+	y := NewDecimal64(-98765, -4)
+	u := NewDecimal128(12345678, -3)
+	v := NewDecimal128(-98765, -4)
+	for _, test := range []struct {
+		name string
+		f    func()
+	}{
+		{"Decimal64.Add", func() { x.Add(y, ToNearestEven) }},
+		{"Decimal64.Mul", func() { x.Mul(y, ToNearestEven) }},
+		{"Decimal64.Quo", func() { x.Quo(y, ToNearestEven) }},
+		{"Decimal64.Quantize", func() { x.Quantize(-2, ToNearestEven) }},
+		{"Decimal64.Cmp", func() { x.Cmp(y) }},
+		{"Decimal128.Add", func() { u.Add(v, ToNearestEven) }},
+		{"Decimal128.Mul", func() { u.Mul(v, ToNearestEven) }},
+		{"Decimal128.Quo", func() { u.Quo(v, ToNearestEven) }},
+		{"Decimal128.Cmp", func() { u.Cmp(v) }},
+	} {
+		if allocs := testing.AllocsPerRun(100, test.f); allocs != 0 {
+			t.Errorf("%s: got %v allocs; want 0", test.name, allocs)
+		}
+	}
+}
+
+func BenchmarkDecimal64(b *testing.B) {
+	x := NewDecimal64(1234567890123456, -10)
+	y := NewDecimal64(-987654321, -4)
+	for _, op := range []struct {
+		name string
+		f    func() Decimal64
+	}{
+		{"Add", func() Decimal64 { return x.Add(y, ToNearestEven) }},
+		{"Mul", func() Decimal64 { return x.Mul(y, ToNearestEven) }},
+		{"Quo", func() Decimal64 { return x.Quo(y, ToNearestEven) }},
+	} {
+		b.Run(op.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				op.f()
+			}
+		})
+	}
+}
+
+func BenchmarkDecimal128(b *testing.B) {
+	x, _ := ParseDecimal128("1234567890.123456789012345678901234", ToNearestEven)
+	y, _ := ParseDecimal128("-9876543.21", ToNearestEven)
+	for _, op := range []struct {
+		name string
+		f    func() Decimal128
+	}{
+		{"Add", func() Decimal128 { return x.Add(y, ToNearestEven) }},
+		{"Mul", func() Decimal128 { return x.Mul(y, ToNearestEven) }},
+		{"Quo", func() Decimal128 { return x.Quo(y, ToNearestEven) }},
+	} {
+		b.Run(op.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				op.f()
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/db47h/decimal"
+)
+
+// Common currency patterns.
+const (
+	CurrencyPattern   = "¤#,##0.00"
+	AccountingPattern = "¤#,##0.00;(¤#,##0.00)"
+)
+
+// A Currency describes how amounts of a given currency are rounded and
+// displayed.
+type Currency struct {
+	Code   string // ISO 4217 code
+	Symbol string // symbol, Code if empty
+
+	// Number of minor unit digits.
+	Digits int
+	// Number of digits and rounding increment, in units of 10**-CashDigits,
+	// used for cash transactions when Formatter.Cash is set. An increment
+	// of 0 or 1 rounds to CashDigits digits.
+	CashDigits, CashIncrement int
+}
+
+var currencies = func() map[string]Currency {
+	m := make(map[string]Currency)
+	for _, t := range iso4217 {
+		for _, code := range strings.Fields(t.codes) {
+			c := Currency{Code: code, Symbol: currencySymbols[code], Digits: t.digits, CashDigits: t.digits}
+			if r, ok := cashRounding[code]; ok {
+				c.CashDigits, c.CashIncrement = r.digits, r.increment
+			}
+			m[code] = c
+		}
+	}
+	return m
+}()
+
+// LookupCurrency returns the Currency for the given ISO 4217 code with its
+// ISO minor unit, CLDR cash rounding, and CLDR root locale symbol. It reports
+// whether the code is known.
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
+
+// FormatMoney returns the formatted value of an amount x of currency c.
+func (f *Formatter) FormatMoney(x *decimal.Decimal, c Currency) string {
+	return string(f.AppendMoney(nil, x, c))
+}
+
+// AppendMoney appends the formatted value of an amount x of currency c to buf
+// and returns the extended buffer.
+//
+// The number of fraction digits of f is replaced by the currency's minor unit
+// digits, or by its cash rounding if f.Cash is set. Placeholders in f's
+// affixes are replaced by the currency symbol ('¤') or code ('¤¤'). Like in
+// CLDR, a no-break space is inserted between a currency symbol that ends or
+// starts with a letter and the number.
+func (f *Formatter) AppendMoney(buf []byte, x *decimal.Decimal, c Currency) []byte {
+	g := f.money(c, c.Symbol)
+	if f.Cash && c.CashIncrement > 1 && !x.IsInf() {
+		x = roundIncrement(new(decimal.Decimal).SetMantExp(x, f.Scale), int64(c.CashIncrement), c.CashDigits, f.Mode)
+		g.Scale = 0
+	}
+	return g.Append(buf, x)
+}
+
+// ParseMoney is like Parse for amounts of currency c formatted with
+// FormatMoney. Amounts with either the currency symbol or the currency code
+// are accepted.
+func (f *Formatter) ParseMoney(z *decimal.Decimal, s string, c Currency) (*decimal.Decimal, error) {
+	r, err := f.money(c, c.Symbol).Parse(z, s)
+	if err != nil && c.Symbol != "" && c.Symbol != c.Code {
+		return f.money(c, c.Code).Parse(z, s)
+	}
+	return r, err
+}
+
+// money returns a copy of f suitable for formatting amounts of currency c with
+// the given symbol.
+func (f *Formatter) money(c Currency, symbol string) *Formatter {
+	g := *f
+	d := c.Digits
+	if f.Cash {
+		d = c.CashDigits
+	}
+	g.MinFracDigits, g.MaxFracDigits = d, d
+	if symbol == "" {
+		symbol = c.Code
+	}
+	g.PosPrefix = currencyAffix(f.PosPrefix, c.Code, symbol, true)
+	g.PosSuffix = currencyAffix(f.PosSuffix, c.Code, symbol, false)
+	g.NegPrefix = currencyAffix(f.NegPrefix, c.Code, symbol, true)
+	g.NegSuffix = currencyAffix(f.NegSuffix, c.Code, symbol, false)
+	return &g
+}
+
+// currencyAffix replaces the currency placeholders in a prefix or suffix.
+func currencyAffix(affix, code, symbol string, prefix bool) string {
+	if !strings.ContainsRune(affix, '¤') {
+		return affix
+	}
+	s := strings.Replace(affix, "¤¤", code, -1)
+	s = strings.Replace(s, "¤", symbol, -1)
+	switch {
+	case prefix && strings.HasSuffix(affix, "¤"):
+		if r, _ := utf8.DecodeLastRuneInString(s); unicode.IsLetter(r) {
+			s += "\u00a0"
+		}
+	case !prefix && strings.HasPrefix(affix, "¤"):
+		if r, _ := utf8.DecodeRuneInString(s); unicode.IsLetter(r) {
+			s = "\u00a0" + s
+		}
+	}
+	return s
+}
+
+// roundIncrement returns x rounded to a multiple of inc × 10**-digits
+// according to mode. x must be finite.
+func roundIncrement(x *decimal.Decimal, inc int64, digits int, mode decimal.RoundingMode) *decimal.Decimal {
+	if x.Sign() == 0 {
+		return x
+	}
+	// q = x / (inc × 10**-digits), truncated with at least two fractional
+	// digits. If inexact, a sticky digit is added: q then rounds to the same
+	// integer as the exact quotient in any rounding mode.
+	y := new(decimal.Decimal).SetMantExp(x, digits)
+	prec := uint(max(y.MantExp(nil), 0) + 2)
+	q := new(decimal.Decimal).SetPrec(prec).SetMode(decimal.ToZero)
+	q.Quo(y, new(decimal.Decimal).SetInt64(inc))
+	if q.Acc() != decimal.Exact {
+		sticky := new(decimal.Decimal).SetInt64(int64(q.Sign()))
+		sticky.SetMantExp(sticky, q.MantExp(nil)-int(prec)-1)
+		q.SetPrec(prec+1).Add(q, sticky)
+	}
+	n := new(decimal.Decimal).SetMode(mode).RoundInt(q)
+	if n.Sign() == 0 {
+		return n
+	}
+	// room for the digits of inc
+	n.SetPrec(n.Prec()+19).Mul(n, new(decimal.Decimal).SetInt64(inc))
+	return n.SetMantExp(n, -digits)
+}
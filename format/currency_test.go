@@ -0,0 +1,139 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/db47h/decimal"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	for _, test := range []struct {
+		code string
+		want Currency
+	}{
+		{"JPY", Currency{"JPY", "JP¥", 0, 0, 0}},
+		{"USD", Currency{"USD", "US$", 2, 2, 0}},
+		{"usd", Currency{"USD", "US$", 2, 2, 0}},
+		{"KWD", Currency{"KWD", "", 3, 3, 0}},
+		{"CLF", Currency{"CLF", "", 4, 4, 0}},
+		{"CHF", Currency{"CHF", "", 2, 2, 5}},
+		{"SEK", Currency{"SEK", "", 2, 0, 0}},
+	} {
+		c, ok := LookupCurrency(test.code)
+		if !ok || c != test.want {
+			t.Errorf("LookupCurrency(%q) = %+v, %v; want %+v", test.code, c, ok, test.want)
+		}
+	}
+	for _, code := range []string{"", "XXX", "XAU", "DEM", "US"} {
+		if c, ok := LookupCurrency(code); ok {
+			t.Errorf("LookupCurrency(%q) = %+v; want not found", code, c)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	usd, _ := LookupCurrency("USD")
+	eur, _ := LookupCurrency("EUR")
+	jpy, _ := LookupCurrency("JPY")
+	kwd, _ := LookupCurrency("KWD")
+	chf, _ := LookupCurrency("CHF")
+	sek, _ := LookupCurrency("SEK")
+	dkk, _ := LookupCurrency("DKK")
+	dollar := usd
+	dollar.Symbol = "$"
+
+	fr := MustCompile("#,##0.00 ¤")
+	fr.Decimal, fr.Group = ",", " "
+	code := MustCompile("¤¤#,##0.00")
+	cash := MustCompile(CurrencyPattern)
+	cash.Cash = true
+	up := MustCompile(CurrencyPattern)
+	up.Cash, up.Mode = true, decimal.ToPositiveInf
+
+	for _, test := range []struct {
+		f    *Formatter
+		x    string
+		c    Currency
+		want string
+	}{
+		{MustCompile(CurrencyPattern), "1234.567", usd, "US$1,234.57"},
+		{MustCompile(CurrencyPattern), "-1234.567", dollar, "-$1,234.57"},
+		{MustCompile(CurrencyPattern), "1234.567", jpy, "JP¥1,235"},
+		{MustCompile(CurrencyPattern), "1234.5675", kwd, "KWD\u00a01,234.568"},
+		{MustCompile(CurrencyPattern), "0.001", usd, "US$0.00"},
+		{MustCompile(CurrencyPattern), "-0.001", usd, "US$0.00"},
+		{MustCompile(AccountingPattern), "-1234.567", eur, "(€1,234.57)"},
+		{MustCompile(AccountingPattern), "1234.567", eur, "€1,234.57"},
+		{MustCompile(AccountingPattern), "-1234.5675", kwd, "(KWD\u00a01,234.568)"},
+		{MustCompile("#,##0.00;(#,##0.00) ¤"), "-1234", kwd, "(1,234.000) KWD"},
+		{MustCompile("#,##0.00¤"), "1", kwd, "1.000\u00a0KWD"},
+		{MustCompile("#,##0.00¤¤"), "1", eur, "1.00\u00a0EUR"},
+		{fr, "-1234567.891", eur, "-1 234 567,89 €"},
+		{code, "1234.5", usd, "USD\u00a01,234.50"},
+		{code, "1234.5", jpy, "JPY\u00a01,234"},
+		{MustCompile("¤ #,##0.00"), "1234.5", chf, "CHF 1,234.50"},
+		{MustCompile("#,##0.00"), "1234.5", jpy, "1,234"},
+		{MustCompile(CurrencyPattern), "+Inf", usd, "US$∞"},
+		{cash, "1.234", chf, "CHF\u00a01.25"},
+		{cash, "1.225", chf, "CHF\u00a01.20"},
+		{cash, "1.275", chf, "CHF\u00a01.30"},
+		{cash, "-1.2749", chf, "-CHF\u00a01.25"},
+		{cash, "0.02", chf, "CHF\u00a00.00"},
+		{cash, "-0.02", chf, "CHF\u00a00.00"},
+		{cash, "0.025", chf, "CHF\u00a00.00"},
+		{cash, "0.0250001", chf, "CHF\u00a00.05"},
+		{cash, "1e20", chf, "CHF\u00a0100,000,000,000,000,000,000.00"},
+		{cash, "12.5", sek, "SEK\u00a012"},
+		{cash, "13.5", sek, "SEK\u00a014"},
+		{cash, "12.25", dkk, "DKK\u00a012.00"},
+		{cash, "12.26", dkk, "DKK\u00a012.50"},
+		{cash, "12.75", dkk, "DKK\u00a013.00"},
+		{cash, "1234.567", usd, "US$1,234.57"},
+		{up, "1.2001", chf, "CHF\u00a01.25"},
+		{up, "-1.2499", chf, "-CHF\u00a01.20"},
+		{up, "0.001", chf, "CHF\u00a00.05"},
+		{up, "12.01", sek, "SEK\u00a013"},
+	} {
+		if got := test.f.FormatMoney(makeDecimal(test.x), test.c); got != test.want {
+			t.Errorf("%+v.FormatMoney(%s, %s) = %q; want %q", *test.f, test.x, test.c.Code, got, test.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	usd, _ := LookupCurrency("USD")
+	kwd, _ := LookupCurrency("KWD")
+	fr := MustCompile("#,##0.00 ¤")
+	fr.Decimal, fr.Group = ",", " "
+	eur, _ := LookupCurrency("EUR")
+
+	for _, test := range []struct {
+		f    *Formatter
+		s    string
+		c    Currency
+		want string
+	}{
+		{MustCompile(CurrencyPattern), "US$1,234.57", usd, "1234.57"},
+		{MustCompile(CurrencyPattern), "-US$1,234.57", usd, "-1234.57"},
+		{MustCompile(CurrencyPattern), "USD 1,234.57", usd, "1234.57"},
+		{MustCompile(CurrencyPattern), "1,234.57", usd, "1234.57"},
+		{MustCompile(AccountingPattern), "(US$1,234.57)", usd, "-1234.57"},
+		{MustCompile(AccountingPattern), "(USD 1,234.57)", usd, "-1234.57"},
+		{MustCompile(AccountingPattern), "(KWD\u00a01,234.568)", kwd, "-1234.568"},
+		{fr, "-1 234 567,89 €", eur, "-1234567.89"},
+		{fr, "1 234,5 EUR", eur, "1234.5"},
+	} {
+		got, err := test.f.ParseMoney(new(decimal.Decimal).SetPrec(20), test.s, test.c)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", test.s, test.c.Code, err)
+			continue
+		}
+		if want := makeDecimal(test.want); got.Cmp(want) != 0 {
+			t.Errorf("ParseMoney(%q, %s) = %s; want %s", test.s, test.c.Code, got.Text('g', -1), test.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package format_test
+
+import (
+	"fmt"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/format"
+)
+
+func Example() {
+	x, _ := new(decimal.Decimal).SetPrec(20).SetString("-1234567.891")
+
+	en := format.MustCompile("#,##0.00")
+	fmt.Println(en.Format(x))
+
+	de := format.MustCompile("#,##0.00")
+	de.Decimal, de.Group = ",", "."
+	fmt.Println(de.Format(x))
+
+	in := format.MustCompile("#,##,##0.###")
+	in.Digits = format.Devanagari
+	fmt.Println(in.Format(x))
+
+	acct := format.MustCompile("#,##0.00;(#,##0.00)")
+	acct.Mode = decimal.ToZero
+	fmt.Println(acct.Format(x))
+
+	y, err := de.Parse(new(decimal.Decimal).SetPrec(20), " -1.234.567,89 ")
+	fmt.Println(y.Text('f', -1), err)
+
+	// Output:
+	// -1,234,567.89
+	// -1.234.567,89
+	// -१२,३४,५६७.८९१
+	// (1,234,567.89)
+	// -1234567.89 <nil>
+}
+
+func ExampleFormatter_FormatMoney() {
+	x, _ := new(decimal.Decimal).SetPrec(20).SetString("-1234.567")
+	chf, _ := format.LookupCurrency("CHF")
+	jpy, _ := format.LookupCurrency("JPY")
+	kwd, _ := format.LookupCurrency("KWD")
+
+	acct := format.MustCompile(format.AccountingPattern)
+	fmt.Printf("%q\n", acct.FormatMoney(x, chf))
+	fmt.Printf("%q\n", acct.FormatMoney(x, jpy))
+	fmt.Printf("%q\n", acct.FormatMoney(x, kwd))
+
+	// cash rounding to 0.05
+	acct.Cash = true
+	fmt.Printf("%q\n", acct.FormatMoney(x, chf))
+
+	// Output:
+	// "(CHF\u00a01,234.57)"
+	// "(JP¥1,235)"
+	// "(KWD\u00a01,234.567)"
+	// "(CHF\u00a01,234.55)"
+}
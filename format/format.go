@@ -0,0 +1,555 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package format implements locale-aware formatting and parsing of Decimals.
+//
+// A Formatter is usually built from a CLDR number pattern like "#,##0.00" or
+// "#,##,##0.###" (see Compile), then customized with locale specific symbols:
+//
+//	f, err := format.Compile("#,##0.00")
+//	...
+//	f.Decimal, f.Group = ",", "."
+//	s := f.Format(x) // 1.234.567,89
+//
+// Monetary amounts are formatted with FormatMoney, using patterns with currency
+// placeholders and the ISO 4217 currency data returned by LookupCurrency.
+//
+// Formatting is done entirely in decimal: values are rounded with
+// (*decimal.Decimal).Append('f', prec) and never converted to float64.
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/db47h/decimal"
+)
+
+// Digits is a set of native digits, indexed by their value.
+type Digits [10]rune
+
+// Common digit sets.
+var (
+	Latin               = Digits{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}
+	ArabicIndic         = makeDigits('٠')
+	ExtendedArabicIndic = makeDigits('۰')
+	Devanagari          = makeDigits('०')
+	Bengali             = makeDigits('০')
+	Thai                = makeDigits('๐')
+	FullWidth           = makeDigits('０')
+)
+
+// knownDigits lists the digit sets recognized by the lenient parser
+// regardless of a Formatter's Digits.
+var knownDigits = [...]*Digits{&Latin, &ArabicIndic, &ExtendedArabicIndic, &Devanagari, &Bengali, &Thai, &FullWidth}
+
+func makeDigits(zero rune) (d Digits) {
+	for i := range d {
+		d[i] = zero + rune(i)
+	}
+	return d
+}
+
+// Infinity is the symbol used to format and parse infinite values.
+const Infinity = "∞"
+
+// A Formatter formats Decimals according to a number pattern and a set of
+// locale specific symbols.
+//
+// The zero value for a Formatter formats numbers like Text('f', 0) with no
+// grouping. Formatters returned by Compile use "." and "," as decimal and
+// group separators. Fields can be changed after compilation to match a given
+// locale; a Formatter must not be modified while in use.
+type Formatter struct {
+	// Affixes written before and after positive and negative numbers. Values
+	// that round to zero are formatted with the positive affixes.
+	PosPrefix, PosSuffix string
+	NegPrefix, NegSuffix string
+
+	// Minimum number of integer digits. Integer parts with fewer digits are
+	// padded with leading zeros. If zero, values less than one are formatted
+	// without integer digits unless they have no fractional digits either.
+	MinIntDigits int
+	// Minimum and maximum number of fractional digits. Values are rounded to
+	// MaxFracDigits digits, then trailing zeros in excess of MinFracDigits are
+	// removed.
+	MinFracDigits, MaxFracDigits int
+
+	// Size of the rightmost group of integer digits, and of the other groups.
+	// If GroupSize is zero, integer digits are not grouped. If
+	// SecondaryGroupSize is zero, GroupSize is used for all groups.
+	GroupSize, SecondaryGroupSize int
+
+	// Scale is a power of ten the value is multiplied by before formatting:
+	// 2 for percentages and 3 for per mille.
+	Scale int
+
+	// Decimal and group separators.
+	Decimal, Group string
+
+	// Digits is the set of digits used for output. The zero value selects
+	// Latin digits.
+	Digits Digits
+
+	// Mode is the rounding mode used to round values to MaxFracDigits.
+	Mode decimal.RoundingMode
+
+	// Cash selects the cash rounding of currencies in money formats.
+	Cash bool
+}
+
+// Compile parses a CLDR number pattern and returns a Formatter that formats
+// numbers accordingly.
+//
+// A pattern is made of a positive subpattern, optionally followed by ';' and a
+// negative subpattern. Each subpattern consists of a prefix, a number part and
+// a suffix. The number part is made of the following characters:
+//
+//	#	an optional digit
+//	0	a mandatory digit
+//	,	a group separator
+//	.	the decimal separator
+//
+// The distance between the last group separator and the end of the integer
+// part sets the group size, and the distance between the last two separators,
+// if any, the size of the other groups: "#,##,##0" selects Indian style
+// grouping.
+//
+// Other characters are copied to the affixes, except for the single quote
+// which quotes literal text (two single quotes being a literal quote), '%' and
+// '‰', which also multiply the value by 100 and 1000 respectively, and the
+// special characters listed below which are not supported. The currency signs
+// '¤' and '¤¤' are copied as is: they are placeholders for the currency symbol
+// and ISO 4217 code in money formats (see Formatter.FormatMoney) and may not be
+// quoted.
+//
+// The number part of the negative subpattern is ignored. If there is no
+// negative subpattern, negative numbers are formatted with the positive
+// affixes and a '-' prefix.
+//
+// Significant digits ('@'), scientific notation ('E' after the number part),
+// padding ('*'), rounding increments ('1' through '9') and currency names
+// ('¤¤¤') are not supported and Compile returns an error if the pattern
+// contains any of them.
+func Compile(pattern string) (*Formatter, error) {
+	f := &Formatter{Decimal: ".", Group: ","}
+	pos, neg, hasNeg, err := splitPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	prefix, number, suffix, err := parseSubpattern(pattern, pos, &f.Scale)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.parseNumber(pattern, number); err != nil {
+		return nil, err
+	}
+	f.PosPrefix, f.PosSuffix = prefix, suffix
+	if !hasNeg {
+		f.NegPrefix, f.NegSuffix = "-"+prefix, suffix
+		return f, nil
+	}
+	if f.NegPrefix, _, f.NegSuffix, err = parseSubpattern(pattern, neg, &f.Scale); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// MustCompile is like Compile but panics if the pattern cannot be parsed.
+func MustCompile(pattern string) *Formatter {
+	f, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// splitPattern splits a pattern into its positive and negative subpatterns.
+func splitPattern(pattern string) (pos, neg string, hasNeg bool, err error) {
+	quoted := false
+	sep := -1
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\'':
+			quoted = !quoted
+		case ';':
+			if quoted {
+				break
+			}
+			if sep >= 0 {
+				return "", "", false, patternError(pattern, "too many subpatterns")
+			}
+			sep = i
+		}
+	}
+	if quoted {
+		return "", "", false, patternError(pattern, "unterminated quote")
+	}
+	if sep < 0 {
+		return pattern, "", false, nil
+	}
+	return pattern[:sep], pattern[sep+1:], true, nil
+}
+
+// parseSubpattern splits a subpattern into its prefix, number part and suffix.
+// Quotes in the affixes are resolved, and *scale is set if they contain a
+// percent or per mille sign.
+func parseSubpattern(pattern, sub string, scale *int) (prefix, number, suffix string, err error) {
+	var b strings.Builder
+	quoted := false
+	start, end := -1, -1
+	for i, n := 0, 0; i < len(sub); i += n {
+		var r rune
+		r, n = utf8.DecodeRuneInString(sub[i:])
+		isNum := !quoted && strings.ContainsRune("#0123456789,.@", r)
+		if start >= 0 && end < 0 && !isNum {
+			end = i
+			if r == 'E' {
+				return "", "", "", patternError(pattern, "scientific notation not supported")
+			}
+		}
+		if r == '\'' {
+			if strings.HasPrefix(sub[i+1:], "'") {
+				b.WriteByte('\'')
+				n = 2
+			} else {
+				quoted = !quoted
+			}
+			continue
+		}
+		if quoted {
+			if r == '¤' {
+				return "", "", "", patternError(pattern, "quoted currency sign")
+			}
+			b.WriteRune(r)
+			continue
+		}
+		if isNum {
+			if r == '@' {
+				return "", "", "", patternError(pattern, "significant digits not supported")
+			}
+			switch {
+			case start < 0:
+				start = i
+				prefix = b.String()
+				b.Reset()
+			case end >= 0:
+				return "", "", "", patternError(pattern, fmt.Sprintf("unexpected %q", r))
+			}
+			continue
+		}
+		switch r {
+		case '*':
+			return "", "", "", patternError(pattern, "padding not supported")
+		case '¤':
+			if strings.HasPrefix(sub[i:], "¤¤¤") {
+				return "", "", "", patternError(pattern, "currency names not supported")
+			}
+		case '%':
+			*scale = 2
+		case '‰':
+			*scale = 3
+		}
+		b.WriteRune(r)
+	}
+	if start < 0 {
+		return "", "", "", patternError(pattern, "missing number")
+	}
+	if end < 0 {
+		end = len(sub)
+	}
+	return prefix, sub[start:end], b.String(), nil
+}
+
+// parseNumber sets f's digit counts and group sizes from the number part of a
+// pattern.
+func (f *Formatter) parseNumber(pattern, number string) error {
+	ip, fp := number, ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		ip, fp = number[:i], number[i+1:]
+	}
+	var groups []int // sizes of integer digit groups
+	n := 0
+	for i := 0; i < len(ip); i++ {
+		switch c := ip[i]; c {
+		case '#':
+			if f.MinIntDigits > 0 {
+				return patternError(pattern, "'#' after '0'")
+			}
+		case '0':
+			f.MinIntDigits++
+		case ',':
+			groups = append(groups, n)
+			n = 0
+			continue
+		default:
+			return patternError(pattern, fmt.Sprintf("%q not supported", c))
+		}
+		n++
+	}
+	if len(groups) > 0 {
+		if n == 0 || groups[len(groups)-1] == 0 && len(groups) > 1 {
+			return patternError(pattern, "empty group")
+		}
+		f.GroupSize = n
+		if len(groups) > 1 && groups[len(groups)-1] != n {
+			f.SecondaryGroupSize = groups[len(groups)-1]
+		}
+	}
+	for i := 0; i < len(fp); i++ {
+		switch c := fp[i]; c {
+		case '0':
+			if f.MaxFracDigits > f.MinFracDigits {
+				return patternError(pattern, "'0' after '#'")
+			}
+			f.MinFracDigits++
+		case '#':
+		default:
+			return patternError(pattern, fmt.Sprintf("%q in fraction", c))
+		}
+		f.MaxFracDigits++
+	}
+	return nil
+}
+
+func patternError(pattern, msg string) error {
+	return fmt.Errorf("format: invalid pattern %q: %s", pattern, msg)
+}
+
+// Format returns the formatted value of x.
+func (f *Formatter) Format(x *decimal.Decimal) string {
+	return string(f.Append(nil, x))
+}
+
+// Append appends the formatted value of x to buf and returns the extended
+// buffer.
+func (f *Formatter) Append(buf []byte, x *decimal.Decimal) []byte {
+	if x.IsInf() {
+		if x.Signbit() {
+			return append(append(append(buf, f.NegPrefix...), Infinity...), f.NegSuffix...)
+		}
+		return append(append(append(buf, f.PosPrefix...), Infinity...), f.PosSuffix...)
+	}
+
+	y := new(decimal.Decimal).Copy(x)
+	if f.Scale != 0 {
+		y.SetMantExp(y, f.Scale)
+	}
+	var tmp [64]byte
+	s := y.SetMode(f.Mode).Append(tmp[:0], 'f', max(f.MinFracDigits, f.MaxFracDigits))
+
+	neg := s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+	ip, fp := s, s[:0]
+	if i := strings.IndexByte(string(s), '.'); i >= 0 {
+		ip, fp = s[:i], s[i+1:]
+	}
+	// trim leading and trailing zeros
+	for len(ip) > 0 && ip[0] == '0' {
+		ip = ip[1:]
+	}
+	n := len(fp)
+	for n > f.MinFracDigits && fp[n-1] == '0' {
+		n--
+	}
+	if neg && len(ip) == 0 && strings.Trim(string(fp), "0") == "" {
+		neg = false // rounded to zero
+	}
+	fp = fp[:n]
+
+	prefix, suffix := f.PosPrefix, f.PosSuffix
+	if neg {
+		prefix, suffix = f.NegPrefix, f.NegSuffix
+	}
+	buf = append(buf, prefix...)
+
+	// integer part
+	nz := max(f.MinIntDigits-len(ip), 0) // leading zeros
+	if nz+len(ip) == 0 && len(fp) == 0 {
+		nz = 1
+	}
+	digits := f.digits()
+	for i, l := 0, nz+len(ip); i < l; i++ {
+		if i > 0 && f.groupBefore(l-i) {
+			buf = append(buf, f.Group...)
+		}
+		c := byte('0')
+		if i >= nz {
+			c = ip[i-nz]
+		}
+		buf = append(buf, string(digits[c-'0'])...)
+	}
+
+	// fraction
+	if len(fp) > 0 {
+		buf = append(buf, f.Decimal...)
+		for _, c := range fp {
+			buf = append(buf, string(digits[c-'0'])...)
+		}
+	}
+
+	return append(buf, suffix...)
+}
+
+// groupBefore reports whether a group separator must be written before the
+// integer digit that has n digits to its right, itself included.
+func (f *Formatter) groupBefore(n int) bool {
+	if f.GroupSize <= 0 || n <= f.GroupSize {
+		return n == f.GroupSize
+	}
+	g := f.SecondaryGroupSize
+	if g <= 0 {
+		g = f.GroupSize
+	}
+	return (n-f.GroupSize)%g == 0
+}
+
+func (f *Formatter) digits() *Digits {
+	if f.Digits == (Digits{}) {
+		return &Latin
+	}
+	return &f.Digits
+}
+
+// ErrSyntax indicates that a string does not represent a number.
+var ErrSyntax = errors.New("invalid syntax")
+
+// Parse sets z to the value of the number represented by s, as formatted by f,
+// and returns z. The precision and rounding mode of z are used as in
+// (*decimal.Decimal).Parse.
+//
+// Parse is lenient:
+//
+//   - leading and trailing white space is ignored, as well as affixes or group
+//     separators that are missing or out of place.
+//   - numbers without the negative affixes are negative if they start with '-'
+//     or '−' (U+2212).
+//   - digits can be ASCII digits, digits from f.Digits or from any of the digit
+//     sets defined in this package.
+//   - if the group separator is a space, any Unicode space is accepted.
+//
+// Values are divided by 10**f.Scale, whether or not s has a percent sign.
+//
+// If s cannot be parsed, Parse returns an error that wraps ErrSyntax. The value
+// of z is then undefined.
+func (f *Formatter) Parse(z *decimal.Decimal, s string) (*decimal.Decimal, error) {
+	t := strings.TrimFunc(s, unicode.IsSpace)
+	neg := false
+	switch {
+	case f.hasAffixes(t, f.NegPrefix, f.NegSuffix) &&
+		(f.NegPrefix != f.PosPrefix || f.NegSuffix != f.PosSuffix):
+		t, neg = trimAffixes(t, f.NegPrefix, f.NegSuffix), true
+	default:
+		t = trimAffixes(t, f.PosPrefix, f.PosSuffix)
+		if strings.HasPrefix(t, "-") || strings.HasPrefix(t, "−") {
+			_, n := utf8.DecodeRuneInString(t)
+			t, neg = strings.TrimLeftFunc(t[n:], unicode.IsSpace), true
+		} else {
+			t = strings.TrimLeftFunc(strings.TrimPrefix(t, "+"), unicode.IsSpace)
+		}
+	}
+
+	if t == Infinity {
+		return z.SetInf(neg), nil
+	}
+
+	b := make([]byte, 0, len(t)+1)
+	if neg {
+		b = append(b, '-')
+	}
+	nd, dot := 0, false
+	for i := 0; i < len(t); {
+		r, n := utf8.DecodeRuneInString(t[i:])
+		if d := f.digitValue(r); d >= 0 {
+			b = append(b, byte('0'+d))
+			nd++
+		} else if !dot && f.Decimal != "" && strings.HasPrefix(t[i:], f.Decimal) {
+			b = append(b, '.')
+			n, dot = len(f.Decimal), true
+		} else if !dot && nd > 0 && f.isGroup(t[i:]) {
+			if f.Group != "" && strings.HasPrefix(t[i:], f.Group) {
+				n = len(f.Group)
+			}
+		} else {
+			return nil, fmt.Errorf("format: parsing %q: %w", s, ErrSyntax)
+		}
+		i += n
+	}
+	if nd == 0 {
+		return nil, fmt.Errorf("format: parsing %q: %w", s, ErrSyntax)
+	}
+
+	if _, _, err := z.Parse(string(b), 10); err != nil {
+		return nil, fmt.Errorf("format: parsing %q: %v", s, err)
+	}
+	if f.Scale != 0 {
+		z.SetMantExp(z, -f.Scale)
+	}
+	return z, nil
+}
+
+// hasAffixes reports whether s starts with prefix and ends with suffix, once
+// white space is trimmed from the affixes.
+func (f *Formatter) hasAffixes(s, prefix, suffix string) bool {
+	prefix = strings.TrimFunc(prefix, unicode.IsSpace)
+	suffix = strings.TrimFunc(suffix, unicode.IsSpace)
+	if prefix == "" && suffix == "" {
+		return false
+	}
+	return len(s) >= len(prefix)+len(suffix) && strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+// trimAffixes removes prefix and suffix from s if present, as well as the
+// white space around them.
+func trimAffixes(s, prefix, suffix string) string {
+	prefix = strings.TrimFunc(prefix, unicode.IsSpace)
+	suffix = strings.TrimFunc(suffix, unicode.IsSpace)
+	if prefix != "" && strings.HasPrefix(s, prefix) {
+		s = strings.TrimLeftFunc(s[len(prefix):], unicode.IsSpace)
+	}
+	if suffix != "" && strings.HasSuffix(s, suffix) {
+		s = strings.TrimRightFunc(s[:len(s)-len(suffix)], unicode.IsSpace)
+	}
+	return s
+}
+
+// digitValue returns the value of the digit r, or -1 if r is not a digit.
+func (f *Formatter) digitValue(r rune) int {
+	for i, d := range f.digits() {
+		if r == d {
+			return i
+		}
+	}
+	for _, d := range knownDigits {
+		if d[0] <= r && r <= d[9] {
+			return int(r - d[0])
+		}
+	}
+	return -1
+}
+
+// isGroup reports whether s starts with a group separator.
+func (f *Formatter) isGroup(s string) bool {
+	if f.Group == "" {
+		return false
+	}
+	if strings.HasPrefix(s, f.Group) {
+		return true
+	}
+	g, _ := utf8.DecodeRuneInString(f.Group)
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(g) && unicode.IsSpace(r)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,238 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/decimal"
+)
+
+func makeDecimal(s string) *decimal.Decimal {
+	x, _, err := decimal.ParseDecimal(s, 0, 100, decimal.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+func TestCompile(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		want    Formatter
+	}{
+		{"0", Formatter{MinIntDigits: 1, NegPrefix: "-"}},
+		{"#,##0.00", Formatter{MinIntDigits: 1, MinFracDigits: 2, MaxFracDigits: 2, GroupSize: 3, NegPrefix: "-"}},
+		{"#,##,##0.###", Formatter{MinIntDigits: 1, MaxFracDigits: 3, GroupSize: 3, SecondaryGroupSize: 2, NegPrefix: "-"}},
+		{"#,##,##,##0", Formatter{MinIntDigits: 1, GroupSize: 3, SecondaryGroupSize: 2, NegPrefix: "-"}},
+		{"#,###,##0", Formatter{MinIntDigits: 1, GroupSize: 3, NegPrefix: "-"}},
+		{"#.##", Formatter{MaxFracDigits: 2, NegPrefix: "-"}},
+		{"00.0#", Formatter{MinIntDigits: 2, MinFracDigits: 1, MaxFracDigits: 2, NegPrefix: "-"}},
+		{"#,##0%", Formatter{MinIntDigits: 1, GroupSize: 3, Scale: 2, PosSuffix: "%", NegPrefix: "-", NegSuffix: "%"}},
+		{"0‰", Formatter{MinIntDigits: 1, Scale: 3, PosSuffix: "‰", NegPrefix: "-", NegSuffix: "‰"}},
+		{"#,##0.00;(#,##0.00)", Formatter{MinIntDigits: 1, MinFracDigits: 2, MaxFracDigits: 2, GroupSize: 3, NegPrefix: "(", NegSuffix: ")"}},
+		{"'#'0 'o''clock';'-'0' ''E'", Formatter{MinIntDigits: 1, PosPrefix: "#", PosSuffix: " o'clock", NegPrefix: "-", NegSuffix: " 'E"}},
+		{"0' E';0'E'", Formatter{MinIntDigits: 1, PosSuffix: " E", NegSuffix: "E"}},
+		{"¤¤ #,##0.00", Formatter{MinIntDigits: 1, MinFracDigits: 2, MaxFracDigits: 2, GroupSize: 3, PosPrefix: "¤¤ ", NegPrefix: "-¤¤ "}},
+		{"EUR 0.00", Formatter{MinIntDigits: 1, MinFracDigits: 2, MaxFracDigits: 2, PosPrefix: "EUR ", NegPrefix: "-EUR "}},
+	} {
+		f, err := Compile(test.pattern)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", test.pattern, err)
+			continue
+		}
+		test.want.Decimal, test.want.Group = ".", ","
+		if *f != test.want {
+			t.Errorf("Compile(%q) = %+v; want %+v", test.pattern, *f, test.want)
+		}
+	}
+
+	for _, pattern := range []string{
+		"",
+		"abc",
+		"0;0;0",
+		"'0",
+		"#,##0,",
+		"#,,##0",
+		"0#",
+		"0.#0",
+		"0.0,0",
+		"0.0.0",
+		"@@#",
+		"0.00E0",
+		"*x0",
+		"'¤'0.00",
+		"¤¤¤0.00",
+		"0.05",
+		"0 and 0",
+	} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded; want error", pattern)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	fr := MustCompile("#,##0.00")
+	fr.Decimal, fr.Group = ",", " "
+	hi := MustCompile("#,##,##0.###")
+	hi.Digits = Devanagari
+	ar := MustCompile("#,##0.00;(#,##0.00)")
+	ar.Decimal, ar.Group, ar.Digits = "٫", "٬", ArabicIndic
+	up := MustCompile("#,##0.00")
+	up.Mode = decimal.ToPositiveInf
+
+	for _, test := range []struct {
+		f    *Formatter
+		x    string
+		want string
+	}{
+		{&Formatter{}, "0", "0"},
+		{&Formatter{}, "1234.5", "1234"},
+		{&Formatter{}, "1235.5", "1236"},
+		{MustCompile("0"), "-0", "0"},
+		{MustCompile("0"), "-0.4", "0"},
+		{MustCompile("0.0"), "-0.04", "0.0"},
+		{MustCompile("0.0"), "-0.06", "-0.1"},
+		{MustCompile("#,##0.00"), "0", "0.00"},
+		{MustCompile("#,##0.00"), "1234567.891", "1,234,567.89"},
+		{MustCompile("#,##0.00"), "-1234567.895", "-1,234,567.90"},
+		{MustCompile("#,##0.00"), "999.999", "1,000.00"},
+		{MustCompile("#,##0.00"), "0.005", "0.00"},
+		{MustCompile("#,##0.00"), "0.0051", "0.01"},
+		{MustCompile("#,##0.00"), "123", "123.00"},
+		{MustCompile("#,##0.00"), "1e25", "10,000,000,000,000,000,000,000,000.00"},
+		{MustCompile("#,##0.00"), "+Inf", "∞"},
+		{MustCompile("#,##0.00"), "-Inf", "-∞"},
+		{MustCompile("#,##0.###"), "1234.5", "1,234.5"},
+		{MustCompile("#,##0.###"), "1234", "1,234"},
+		{MustCompile("#,##0.###"), "0.0001", "0"},
+		{MustCompile("#.##"), "0.5", ".5"},
+		{MustCompile("#.##"), "0", "0"},
+		{MustCompile("#.##"), "0.001", "0"},
+		{MustCompile("000.0#"), "1.5", "001.5"},
+		{MustCompile("#,##0"), "123", "123"},
+		{MustCompile("#,##0"), "1234", "1,234"},
+		{MustCompile("#,##,##0.###"), "1234567.891", "12,34,567.891"},
+		{MustCompile("#,##,##0.###"), "123", "123"},
+		{MustCompile("#,##,##0.###"), "1234", "1,234"},
+		{MustCompile("#,##,##0.###"), "123456", "1,23,456"},
+		{MustCompile("#,##0%"), "0.125", "12%"},
+		{MustCompile("#,##0.#%"), "-12.3456", "-1,234.6%"},
+		{MustCompile("0‰"), "0.0125", "12‰"},
+		{MustCompile("'#'0 'o''clock'"), "4", "#4 o'clock"},
+		{fr, "-1234567.891", "-1 234 567,89"},
+		{hi, "1234567.891", "१२,३४,५६७.८९१"},
+		{ar, "-1234.5", "(١٬٢٣٤٫٥٠)"},
+		{ar, "0.004", "٠٫٠٠"},
+		{ar, "-0.004", "٠٫٠٠"},
+		{up, "1.001", "1.01"},
+		{up, "-1.009", "-1.00"},
+	} {
+		if got := test.f.Format(makeDecimal(test.x)); got != test.want {
+			t.Errorf("%+v.Format(%s) = %q; want %q", *test.f, test.x, got, test.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	fr := MustCompile("#,##0.00")
+	fr.Decimal, fr.Group = ",", " "
+	ar := MustCompile("#,##0.00;(#,##0.00)")
+	ar.Decimal, ar.Group, ar.Digits = "٫", "٬", ArabicIndic
+	pct := MustCompile("#,##0.##%")
+
+	for _, test := range []struct {
+		f    *Formatter
+		s    string
+		want string
+	}{
+		{MustCompile("#,##0.00"), "1,234,567.89", "1234567.89"},
+		{MustCompile("#,##0.00"), "  -1,234,567.89 ", "-1234567.89"},
+		{MustCompile("#,##0.00"), "−1,23,4567.8", "-1234567.8"},
+		{MustCompile("#,##0.00"), "+1234", "1234"},
+		{MustCompile("#,##0.00"), ".5", "0.5"},
+		{MustCompile("#,##0.00"), "∞", "+Inf"},
+		{MustCompile("#,##0.00"), "-∞", "-Inf"},
+		{fr, "-1 234 567,89", "-1234567.89"},
+		{fr, "1 234 567,89", "1234567.89"},
+		{fr, "1\u00a0234,5", "1234.5"},
+		{ar, "(١٬٢٣٤٫٥٠)", "-1234.5"},
+		{ar, "( ١٢٣٤٫٥ )", "-1234.5"},
+		{ar, "١٬٢٣٤٫٥٠", "1234.5"},
+		{ar, "-١٢٣٤", "-1234"},
+		{ar, "۱۲۳", "123"},
+		{ar, "१२३", "123"},
+		{pct, "12.5%", "0.125"},
+		{pct, "-12.5 %", "-0.125"},
+		{pct, "12.5", "0.125"},
+		{MustCompile("'#'0 'o''clock'"), "#4 o'clock", "4"},
+		{MustCompile("'#'0 'o''clock'"), "-#4 o'clock", "-4"},
+	} {
+		z := new(decimal.Decimal).SetPrec(50)
+		got, err := test.f.Parse(z, test.s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.s, err)
+			continue
+		}
+		if got != z {
+			t.Errorf("Parse(%q) did not return z", test.s)
+		}
+		if want := makeDecimal(test.want); got.Cmp(want) != 0 {
+			t.Errorf("Parse(%q) = %s; want %s", test.s, got.Text('g', -1), test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		f *Formatter
+		s string
+	}{
+		{MustCompile("#,##0.00"), ""},
+		{MustCompile("#,##0.00"), "-"},
+		{MustCompile("#,##0.00"), "abc"},
+		{MustCompile("#,##0.00"), "1.2.3"},
+		{MustCompile("#,##0.00"), "1,234.5,6"},
+		{MustCompile("#,##0.00"), ",123"},
+		{MustCompile("#,##0.00"), "12a"},
+		{MustCompile("#,##0.00"), "1e5"},
+		{MustCompile("#,##0.00"), "١٬٢٣٤٫٥"}, // foreign separators
+		{ar, "1234.5"},
+	} {
+		if _, err := test.f.Parse(new(decimal.Decimal), test.s); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q): got error %v; want ErrSyntax", test.s, err)
+		}
+	}
+}
+
+// TestRoundTrip checks that parsing a formatted value yields the value
+// rounded to the formatter's maximum fraction digits.
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	patterns := []string{"#,##0.00", "#,##,##0.###", "0.####;(0.####)", "#,##0.#%"}
+	digits := []Digits{{}, ArabicIndic, ExtendedArabicIndic, Devanagari, Thai}
+	for i := 0; i < 1000; i++ {
+		f := MustCompile(patterns[r.Intn(len(patterns))])
+		f.Digits = digits[r.Intn(len(digits))]
+		if r.Intn(2) == 0 {
+			f.Decimal, f.Group = ",", "."
+		}
+		x := new(decimal.Decimal).SetPrec(30).SetInt64(r.Int63n(1e15) - 5e14)
+		x.SetMantExp(x, -r.Intn(10))
+		s := f.Format(x)
+		y, err := f.Parse(new(decimal.Decimal).SetPrec(30), s)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", x, s, err)
+		}
+		// x rounded to MaxFracDigits + Scale decimal places
+		want := new(decimal.Decimal).SetMantExp(x, f.Scale)
+		want, _ = new(decimal.Decimal).SetPrec(30).SetString(want.Text('f', f.MaxFracDigits))
+		want.SetMantExp(want, -f.Scale)
+		if y.Cmp(want) != 0 {
+			t.Fatalf("%s: Parse(%q) = %s; want %s", x, s, y, want)
+		}
+	}
+}
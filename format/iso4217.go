@@ -0,0 +1,68 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+// Active ISO 4217 currencies, by number of minor unit digits. Funds and
+// precious metals with no minor unit are not included.
+var iso4217 = [...]struct {
+	digits int
+	codes  string
+}{
+	{0, "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF"},
+	{2, "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV " +
+		"BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CNY COP COU CRC CUP CVE CZK " +
+		"DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD HKD HNL " +
+		"HTG HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL " +
+		"MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO " +
+		"NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR RON RSD RUB SAR SBD SCR SDG SEK " +
+		"SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TOP TRY TTD TWD TZS " +
+		"UAH USD USN UYU UZS VED VES WST XCD XCG YER ZAR ZMW ZWG"},
+	{3, "BHD IQD JOD KWD LYD OMR TND"},
+	{4, "CLF UYW"},
+}
+
+// Cash rounding of currencies that differ from their minor unit, from the
+// CLDR supplemental data.
+var cashRounding = map[string]struct{ digits, increment int }{
+	"CAD": {2, 5},
+	"CHF": {2, 5},
+	"COP": {0, 0},
+	"CRC": {0, 0},
+	"CZK": {0, 0},
+	"DKK": {2, 50},
+	"HUF": {0, 0},
+	"IDR": {0, 0},
+	"NOK": {0, 0},
+	"PKR": {0, 0},
+	"SEK": {0, 0},
+	"TWD": {0, 0},
+	"UZS": {0, 0},
+}
+
+// Currency symbols from the CLDR root locale. Other currencies use their ISO
+// 4217 code as a symbol.
+var currencySymbols = map[string]string{
+	"AUD": "A$",
+	"BRL": "R$",
+	"CAD": "CA$",
+	"CNY": "CN¥",
+	"EUR": "€",
+	"GBP": "£",
+	"HKD": "HK$",
+	"ILS": "₪",
+	"INR": "₹",
+	"JPY": "JP¥",
+	"KRW": "₩",
+	"MXN": "MX$",
+	"NZD": "NZ$",
+	"PHP": "₱",
+	"TWD": "NT$",
+	"USD": "US$",
+	"VND": "₫",
+	"XAF": "FCFA",
+	"XCD": "EC$",
+	"XOF": "F CFA",
+	"XPF": "CFPF",
+}
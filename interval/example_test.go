@@ -0,0 +1,29 @@
+package interval_test
+
+import (
+	"fmt"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/interval"
+)
+
+func Example() {
+	one := new(decimal.Decimal).SetPrec(10).SetInt64(1)
+	three := new(decimal.Decimal).SetPrec(10).SetInt64(3)
+
+	// 1/3 × 3 encloses 1
+	x := new(interval.Interval).Quo(interval.Point(one), interval.Point(three))
+	fmt.Println(x)
+	x.Mul(x, interval.Point(three))
+	fmt.Println(x, x.Contains(one), x.Width())
+
+	// √2 with 20 digits
+	two := new(decimal.Decimal).SetInt64(2)
+	r := &interval.Interval{Lo: new(decimal.Decimal).SetPrec(20), Hi: new(decimal.Decimal).SetPrec(20)}
+	fmt.Println(r.Sqrt(interval.Point(two)))
+
+	// Output:
+	// [0.3333333333, 0.3333333334]
+	// [0.9999999999, 1.000000001] true 1.1e-09
+	// [1.4142135623730950488, 1.4142135623730950489]
+}
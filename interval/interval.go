@@ -0,0 +1,314 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package interval implements interval arithmetic on top of decimal.Decimal.
+//
+// An Interval [Lo, Hi] is the closed set of real numbers x such that
+// Lo <= x <= Hi. The result of an operation on intervals is an interval that
+// contains the result of the operation for any combination of values in the
+// operand intervals. Lower bounds are computed with the ToNegativeInf rounding
+// mode and upper bounds with ToPositiveInf, so that rounding errors can only
+// widen the result: if the operands enclose some exact values, so does the
+// result.
+//
+// Operators that set a receiver z to a function of other intervals, like:
+//
+//	func (z *Interval) BinaryOp(x, y *Interval) *Interval
+//
+// compute the bounds of z with the precision of z.Lo and z.Hi. A nil bound is
+// allocated by the operation. If the precision of a bound is 0, it is changed
+// to the largest precision of the operands' bounds before the operation.
+// Operands and receiver may alias.
+//
+// Infinite bounds are supported: [-Inf, +Inf] is the whole real line.
+// Operations where an infinite bound would be the result of an undefined
+// operation (like +Inf - +Inf) panic with a decimal.ErrNaN.
+package interval
+
+import (
+	"github.com/db47h/decimal"
+)
+
+// An Interval represents the closed interval [Lo, Hi]. Lo must be less than or
+// equal to Hi. The zero value for an Interval has nil bounds; it is valid as a
+// receiver but not as an operand.
+type Interval struct {
+	Lo, Hi *decimal.Decimal
+}
+
+// New returns a new interval [lo, hi] with bounds set to copies of lo and hi,
+// with the same precision. New panics if lo > hi.
+func New(lo, hi *decimal.Decimal) *Interval {
+	if lo.Cmp(hi) > 0 {
+		panic("interval: lower bound greater than upper bound")
+	}
+	return &Interval{
+		Lo: new(decimal.Decimal).SetMode(decimal.ToNegativeInf).Set(lo),
+		Hi: new(decimal.Decimal).SetMode(decimal.ToPositiveInf).Set(hi),
+	}
+}
+
+// Point returns a new degenerate interval [x, x].
+func Point(x *decimal.Decimal) *Interval {
+	return New(x, x)
+}
+
+// Entire returns a new interval [-Inf, +Inf].
+func Entire() *Interval {
+	return &Interval{
+		Lo: new(decimal.Decimal).SetMode(decimal.ToNegativeInf).SetInf(true),
+		Hi: new(decimal.Decimal).SetMode(decimal.ToPositiveInf).SetInf(false),
+	}
+}
+
+// init allocates z's bounds if needed, sets their rounding modes, and sets
+// their precision to the largest precision of xs if it is 0.
+func (z *Interval) init(xs ...*decimal.Decimal) {
+	if z.Lo == nil {
+		z.Lo = new(decimal.Decimal)
+	}
+	if z.Hi == nil {
+		z.Hi = new(decimal.Decimal)
+	}
+	var prec uint
+	for _, x := range xs {
+		if p := x.Prec(); p > prec {
+			prec = p
+		}
+	}
+	for _, b := range [...]*decimal.Decimal{z.Lo, z.Hi} {
+		if b.Prec() == 0 {
+			b.SetPrec(prec)
+		}
+	}
+	z.Lo.SetMode(decimal.ToNegativeInf)
+	z.Hi.SetMode(decimal.ToPositiveInf)
+}
+
+// bound returns a new Decimal with the same precision and rounding mode as b.
+func bound(b *decimal.Decimal) *decimal.Decimal {
+	return new(decimal.Decimal).SetPrec(b.Prec()).SetMode(b.Mode())
+}
+
+// set sets z's bounds to lo and hi, which have the precision and rounding mode
+// of z.Lo and z.Hi, and returns z.
+func (z *Interval) set(lo, hi *decimal.Decimal) *Interval {
+	z.Lo.Copy(lo)
+	z.Hi.Copy(hi)
+	return z
+}
+
+// Set sets z to a copy of x, rounded outwards to the precision of z's bounds,
+// and returns z.
+func (z *Interval) Set(x *Interval) *Interval {
+	if z == x {
+		return z
+	}
+	z.init(x.Lo, x.Hi)
+	z.Lo.Set(x.Lo)
+	z.Hi.Set(x.Hi)
+	return z
+}
+
+// Add sets z to x+y and returns z.
+func (z *Interval) Add(x, y *Interval) *Interval {
+	z.init(x.Lo, x.Hi, y.Lo, y.Hi)
+	lo := bound(z.Lo).Add(x.Lo, y.Lo)
+	hi := bound(z.Hi).Add(x.Hi, y.Hi)
+	return z.set(lo, hi)
+}
+
+// Sub sets z to x-y and returns z.
+func (z *Interval) Sub(x, y *Interval) *Interval {
+	z.init(x.Lo, x.Hi, y.Lo, y.Hi)
+	lo := bound(z.Lo).Sub(x.Lo, y.Hi)
+	hi := bound(z.Hi).Sub(x.Hi, y.Lo)
+	return z.set(lo, hi)
+}
+
+// Neg sets z to -x and returns z.
+func (z *Interval) Neg(x *Interval) *Interval {
+	z.init(x.Lo, x.Hi)
+	lo := bound(z.Lo).Neg(x.Hi)
+	hi := bound(z.Hi).Neg(x.Lo)
+	return z.set(lo, hi)
+}
+
+// Mul sets z to x×y and returns z. Zero times an infinite bound is considered to
+// be zero.
+func (z *Interval) Mul(x, y *Interval) *Interval {
+	return z.corners(x, y, func(z, x, y *decimal.Decimal) bool {
+		if x.Sign() == 0 || y.Sign() == 0 {
+			z.SetInt64(0)
+		} else {
+			z.Mul(x, y)
+		}
+		return true
+	})
+}
+
+// Quo sets z to x/y and returns z. If y contains zero, or if all the bounds of
+// x and y are infinite, z is set to [-Inf, +Inf].
+func (z *Interval) Quo(x, y *Interval) *Interval {
+	if y.Contains(zero) {
+		z.init()
+		z.Lo.SetInf(true)
+		z.Hi.SetInf(false)
+		return z
+	}
+	return z.corners(x, y, func(z, x, y *decimal.Decimal) bool {
+		if x.IsInf() && y.IsInf() {
+			// the quotient is bounded by the quotients of the adjacent
+			// corners.
+			return false
+		}
+		z.Quo(x, y)
+		return true
+	})
+}
+
+var zero = new(decimal.Decimal)
+
+// corners sets z to the hull of op(a, b) for all the corners (a, b) of x×y,
+// which is the result of op on x and y if op is monotonic in each of its
+// arguments over x and y. op must set its receiver to op(a, b) rounded with the
+// receiver's rounding mode, or return false if the corner can be ignored. If
+// all the corners are ignored, z is set to [-Inf, +Inf].
+func (z *Interval) corners(x, y *Interval, op func(z, a, b *decimal.Decimal) bool) *Interval {
+	z.init(x.Lo, x.Hi, y.Lo, y.Hi)
+	lo, hi := bound(z.Lo), bound(z.Hi)
+	tlo, thi := bound(z.Lo), bound(z.Hi)
+	empty := true
+	for _, a := range [...]*decimal.Decimal{x.Lo, x.Hi} {
+		for _, b := range [...]*decimal.Decimal{y.Lo, y.Hi} {
+			if !op(tlo, a, b) {
+				continue
+			}
+			op(thi, a, b)
+			if empty || tlo.Cmp(lo) < 0 {
+				lo, tlo = tlo, lo
+			}
+			if empty || thi.Cmp(hi) > 0 {
+				hi, thi = thi, hi
+			}
+			empty = false
+		}
+	}
+	if empty {
+		// no corner gives a bound of the result
+		z.Lo.SetInf(true)
+		z.Hi.SetInf(false)
+		return z
+	}
+	return z.set(lo, hi)
+}
+
+// Sqrt sets z to the square root of x and returns z. Negative values in x are
+// ignored: if x.Lo < 0, z.Lo is set to 0. Sqrt panics with a decimal.ErrNaN
+// if x.Hi < 0.
+func (z *Interval) Sqrt(x *Interval) *Interval {
+	z.init(x.Lo, x.Hi)
+	hi := sqrt(bound(z.Hi), x.Hi)
+	lo := bound(z.Lo)
+	if x.Lo.Sign() > 0 {
+		sqrt(lo, x.Lo)
+	}
+	return z.set(lo, hi)
+}
+
+// sqrt sets z to the square root of x rounded with z's rounding mode, which
+// must be ToNegativeInf or ToPositiveInf, and returns z.
+//
+// Decimal.Sqrt is not correctly rounded, so its result is adjusted by
+// comparing its exact square with x.
+func sqrt(z, x *decimal.Decimal) *decimal.Decimal {
+	z.Sqrt(x)
+	if !z.IsInf() && z.Sign() > 0 {
+		// sq(t) compares t² with x
+		sq := new(decimal.Decimal).SetPrec(2 * z.Prec())
+		cmp := func(t *decimal.Decimal) int { return sq.Mul(t, t).Cmp(x) }
+		t := new(decimal.Decimal)
+		if z.Mode() == decimal.ToPositiveInf {
+			// smallest z such that z² >= x
+			for cmp(z) < 0 {
+				z.NextUp(z)
+			}
+			for t.NextDown(z); t.Sign() > 0 && cmp(t) >= 0; t.NextDown(z) {
+				z.Set(t)
+			}
+		} else {
+			// largest z such that z² <= x
+			for cmp(z) > 0 {
+				z.NextDown(z)
+			}
+			for t.NextUp(z); cmp(t) <= 0; t.NextUp(z) {
+				z.Set(t)
+			}
+		}
+	}
+	return z
+}
+
+// Contains reports whether x contains the value d.
+func (x *Interval) Contains(d *decimal.Decimal) bool {
+	return x.Lo.Cmp(d) <= 0 && d.Cmp(x.Hi) <= 0
+}
+
+// Encloses reports whether x contains all the values of y.
+func (x *Interval) Encloses(y *Interval) bool {
+	return x.Lo.Cmp(y.Lo) <= 0 && y.Hi.Cmp(x.Hi) <= 0
+}
+
+// Intersect sets z to the intersection of x and y and returns z and true. If
+// the intersection is empty, z is unchanged and the result is false. The
+// bounds of z are rounded outwards to their precision.
+func (z *Interval) Intersect(x, y *Interval) (*Interval, bool) {
+	lo, hi := x.Lo, x.Hi
+	if y.Lo.Cmp(lo) > 0 {
+		lo = y.Lo
+	}
+	if y.Hi.Cmp(hi) < 0 {
+		hi = y.Hi
+	}
+	if lo.Cmp(hi) > 0 {
+		return z, false
+	}
+	z.init(lo, hi)
+	return z.set(bound(z.Lo).Set(lo), bound(z.Hi).Set(hi)), true
+}
+
+// Hull sets z to the smallest interval that contains x and y, and returns z.
+// The bounds of z are rounded outwards to their precision.
+func (z *Interval) Hull(x, y *Interval) *Interval {
+	lo, hi := x.Lo, x.Hi
+	if y.Lo.Cmp(lo) < 0 {
+		lo = y.Lo
+	}
+	if y.Hi.Cmp(hi) > 0 {
+		hi = y.Hi
+	}
+	z.init(lo, hi)
+	return z.set(bound(z.Lo).Set(lo), bound(z.Hi).Set(hi))
+}
+
+// Width returns a new Decimal set to Hi - Lo, rounded up to the largest
+// precision of x's bounds. The result is +Inf if x is unbounded.
+func (x *Interval) Width() *decimal.Decimal {
+	prec := x.Lo.Prec()
+	if p := x.Hi.Prec(); p > prec {
+		prec = p
+	}
+	w := new(decimal.Decimal).SetPrec(prec).SetMode(decimal.ToPositiveInf)
+	if x.Lo.Cmp(x.Hi) == 0 {
+		// also handles [±Inf, ±Inf]
+		return w
+	}
+	return w.Sub(x.Hi, x.Lo)
+}
+
+// String returns x formatted as "[Lo, Hi]", with the shortest decimal
+// representation of the bounds.
+func (x *Interval) String() string {
+	return "[" + x.Lo.Text('g', -1) + ", " + x.Hi.Text('g', -1) + "]"
+}
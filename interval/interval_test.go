@@ -0,0 +1,277 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/decimal"
+)
+
+func dec(s string) *decimal.Decimal {
+	x, ok := new(decimal.Decimal).SetPrec(50).SetString(s)
+	if !ok {
+		panic(s)
+	}
+	return x
+}
+
+func iv(lo, hi string) *Interval {
+	return New(dec(lo), dec(hi))
+}
+
+func randDecimal(r *rand.Rand) *decimal.Decimal {
+	prec := 1 + r.Intn(20)
+	var m big.Int
+	m.Rand(r, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(prec)), nil))
+	if r.Intn(2) == 0 {
+		m.Neg(&m)
+	}
+	x := new(decimal.Decimal).SetPrec(uint(prec)).SetInt(&m)
+	return x.SetMantExp(x, r.Intn(11)-5-x.MantExp(nil))
+}
+
+func randInterval(r *rand.Rand) *Interval {
+	lo, hi := randDecimal(r), randDecimal(r)
+	if lo.Cmp(hi) > 0 {
+		lo, hi = hi, lo
+	}
+	return New(lo, hi)
+}
+
+func rat(x *decimal.Decimal) *big.Rat {
+	r, _ := x.Rat(nil)
+	return r
+}
+
+// checkTight checks that z is [min(vs), max(vs)] with its bounds rounded
+// outwards.
+func checkTight(t *testing.T, name string, z *Interval, vs []*big.Rat) {
+	t.Helper()
+	min, max := vs[0], vs[0]
+	for _, v := range vs[1:] {
+		if v.Cmp(min) < 0 {
+			min = v
+		}
+		if v.Cmp(max) > 0 {
+			max = v
+		}
+	}
+	lo := new(decimal.Decimal).SetPrec(z.Lo.Prec()).SetMode(decimal.ToNegativeInf).SetRat(min)
+	hi := new(decimal.Decimal).SetPrec(z.Hi.Prec()).SetMode(decimal.ToPositiveInf).SetRat(max)
+	if z.Lo.Cmp(lo) != 0 || z.Hi.Cmp(hi) != 0 {
+		t.Fatalf("%s = %s; want %s", name, z, &Interval{lo, hi})
+	}
+}
+
+func TestArith(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		x, y := randInterval(r), randInterval(r)
+		prec := uint(1 + r.Intn(10))
+		z := func() *Interval {
+			return &Interval{new(decimal.Decimal).SetPrec(prec), new(decimal.Decimal).SetPrec(prec)}
+		}
+		var sums, diffs, prods, quos []*big.Rat
+		for _, a := range []*decimal.Decimal{x.Lo, x.Hi} {
+			for _, b := range []*decimal.Decimal{y.Lo, y.Hi} {
+				sums = append(sums, new(big.Rat).Add(rat(a), rat(b)))
+				diffs = append(diffs, new(big.Rat).Sub(rat(a), rat(b)))
+				prods = append(prods, new(big.Rat).Mul(rat(a), rat(b)))
+				if b.Sign() != 0 {
+					quos = append(quos, new(big.Rat).Quo(rat(a), rat(b)))
+				}
+			}
+		}
+		checkTight(t, x.String()+" + "+y.String(), z().Add(x, y), sums)
+		checkTight(t, x.String()+" - "+y.String(), z().Sub(x, y), diffs)
+		checkTight(t, x.String()+" × "+y.String(), z().Mul(x, y), prods)
+		checkTight(t, "-"+x.String(), z().Neg(x), []*big.Rat{new(big.Rat).Neg(rat(x.Lo)), new(big.Rat).Neg(rat(x.Hi))})
+		if y.Contains(zero) {
+			if q := z().Quo(x, y); !q.Lo.IsInf() || !q.Hi.IsInf() {
+				t.Fatalf("%s / %s = %s; want [-Inf, +Inf]", x, y, q)
+			}
+		} else {
+			checkTight(t, x.String()+" / "+y.String(), z().Quo(x, y), quos)
+		}
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		x := randInterval(r)
+		if x.Hi.Sign() < 0 {
+			x.Hi.Neg(x.Hi)
+		}
+		z := &Interval{new(decimal.Decimal).SetPrec(uint(1 + r.Intn(10))), nil}
+		z.Sqrt(x)
+		// lo² <= x.Lo < nextUp(lo)² and (hi-ulp)² < x.Hi <= hi²
+		sq := func(d *decimal.Decimal) *big.Rat { r := rat(d); return r.Mul(r, r) }
+		if x.Lo.Sign() <= 0 {
+			if z.Lo.Sign() != 0 {
+				t.Fatalf("sqrt %s = %s; want lower bound 0", x, z)
+			}
+		} else if sq(z.Lo).Cmp(rat(x.Lo)) > 0 || sq(new(decimal.Decimal).NextUp(z.Lo)).Cmp(rat(x.Lo)) <= 0 {
+			t.Fatalf("sqrt %s = %s: lower bound not tight", x, z)
+		}
+		if sq(z.Hi).Cmp(rat(x.Hi)) < 0 || z.Hi.Sign() > 0 && sq(new(decimal.Decimal).NextDown(z.Hi)).Cmp(rat(x.Hi)) >= 0 {
+			t.Fatalf("sqrt %s = %s: upper bound not tight", x, z)
+		}
+	}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(decimal.ErrNaN); !ok {
+				t.Errorf("Sqrt([-2, -1]) did not panic with ErrNaN")
+			}
+		}()
+		new(Interval).Sqrt(iv("-2", "-1"))
+	}()
+}
+
+func TestSpecialValues(t *testing.T) {
+	var pinf, ninf = dec("+Inf"), dec("-Inf")
+	for _, test := range []struct {
+		name string
+		z    *Interval
+		want *Interval
+	}{
+		{"[0, 1] × [1, +Inf]", new(Interval).Mul(iv("0", "1"), New(dec("1"), pinf)), New(dec("0"), pinf)},
+		{"[0, 0] × Entire", new(Interval).Mul(iv("0", "0"), Entire()), iv("0", "0")},
+		{"[-1, 2] × [-Inf, -3]", new(Interval).Mul(iv("-1", "2"), New(ninf, dec("-3"))), Entire()},
+		{"[1, 2] / [1, +Inf]", new(Interval).Quo(iv("1", "2"), New(dec("1"), pinf)), iv("0", "2")},
+		{"[1, +Inf] / [2, +Inf]", new(Interval).Quo(New(dec("1"), pinf), New(dec("2"), pinf)), New(dec("0"), pinf)},
+		{"[1, 2] / [-1, 0]", new(Interval).Quo(iv("1", "2"), iv("-1", "0")), Entire()},
+		{"[+Inf, +Inf] / [+Inf, +Inf]", new(Interval).Quo(New(pinf, pinf), New(pinf, pinf)), Entire()},
+		{"[-Inf, -Inf] / [+Inf, +Inf]", new(Interval).Quo(New(ninf, ninf), New(pinf, pinf)), Entire()},
+		{"Entire / [-Inf, -Inf]", new(Interval).Quo(Entire(), New(ninf, ninf)), Entire()},
+		{"[1, 2] + Entire", new(Interval).Add(iv("1", "2"), Entire()), Entire()},
+		{"sqrt [-1, 4]", new(Interval).Sqrt(iv("-1", "4")), iv("0", "2")},
+		{"sqrt [0, +Inf]", new(Interval).Sqrt(New(dec("0"), pinf)), New(dec("0"), pinf)},
+	} {
+		if test.z.Lo.Cmp(test.want.Lo) != 0 || test.z.Hi.Cmp(test.want.Hi) != 0 {
+			t.Errorf("%s = %s; want %s", test.name, test.z, test.want)
+		}
+	}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(decimal.ErrNaN); !ok {
+				t.Errorf("Entire - Entire did not panic with ErrNaN")
+			}
+		}()
+		new(Interval).Sub(New(pinf, pinf), New(pinf, pinf))
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("New(2, 1) did not panic")
+			}
+		}()
+		New(dec("2"), dec("1"))
+	}()
+}
+
+func TestPrecAndAliasing(t *testing.T) {
+	third := new(decimal.Decimal).SetPrec(5).Quo(dec("1"), dec("3"))
+	x := New(third, third)
+
+	// precision 0 receivers take the operands' precision
+	var z Interval
+	z.Add(x, iv("1", "1"))
+	if z.Lo.Prec() != 50 || z.Hi.Prec() != 50 {
+		t.Errorf("precision: got [%d, %d]; want [50, 50]", z.Lo.Prec(), z.Hi.Prec())
+	}
+	// ...and keep their precision afterwards
+	z = Interval{new(decimal.Decimal).SetPrec(3), nil}
+	z.Mul(x, iv("3", "3"))
+	if z.Lo.Prec() != 3 || z.Hi.Prec() != 50 || z.String() != "[0.999, 0.99999]" {
+		t.Errorf("[1/3]×3 = %s (prec [%d, %d]); want [0.999, 0.99999] (prec [3, 50])", &z, z.Lo.Prec(), z.Hi.Prec())
+	}
+
+	// z == x and z == y
+	z.Set(iv("1", "2"))
+	z.Sub(&z, &z)
+	if z.String() != "[-1, 1]" {
+		t.Errorf("z - z = %s; want [-1, 1]", &z)
+	}
+	z.Mul(&z, &z)
+	if z.String() != "[-1, 1]" {
+		t.Errorf("z × z = %s; want [-1, 1]", &z)
+	}
+	z.Neg(z.Add(&z, iv("2", "3")))
+	if z.String() != "[-4, -1]" {
+		t.Errorf("-(z + [2, 3]) = %s; want [-4, -1]", &z)
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	x, y := iv("1", "3"), iv("2", "4")
+	if !x.Contains(dec("1")) || !x.Contains(dec("3")) || x.Contains(dec("3.0001")) || x.Contains(dec("-Inf")) {
+		t.Errorf("bad Contains for %s", x)
+	}
+	if !Entire().Contains(dec("+Inf")) {
+		t.Errorf("Entire does not contain +Inf")
+	}
+	if !iv("0", "5").Encloses(x) || x.Encloses(y) || !x.Encloses(x) {
+		t.Errorf("bad Encloses")
+	}
+
+	var z Interval
+	if _, ok := z.Intersect(x, y); !ok || z.String() != "[2, 3]" {
+		t.Errorf("%s ∩ %s = %s, %v; want [2, 3], true", x, y, &z, ok)
+	}
+	if _, ok := z.Intersect(x, iv("3", "5")); !ok || z.String() != "[3, 3]" {
+		t.Errorf("%s ∩ [3, 5] = %s, %v; want [3, 3], true", x, &z, ok)
+	}
+	if _, ok := z.Intersect(x, iv("4", "5")); ok || z.String() != "[3, 3]" {
+		t.Errorf("%s ∩ [4, 5] = %s, %v; want [3, 3], false", x, &z, ok)
+	}
+	if z.Hull(x, iv("-1", "0")); z.String() != "[-1, 3]" {
+		t.Errorf("%s ∪ [-1, 0] = %s; want [-1, 3]", x, &z)
+	}
+
+	// outward rounding
+	z = Interval{new(decimal.Decimal).SetPrec(2), new(decimal.Decimal).SetPrec(2)}
+	z.Hull(iv("1.234", "1.5"), iv("1.1", "5.678"))
+	if z.String() != "[1.1, 5.7]" {
+		t.Errorf("Hull = %s; want [1.1, 5.7]", &z)
+	}
+	z.Intersect(iv("-1.234", "1.5"), iv("-5", "5.678"))
+	if z.String() != "[-1.3, 1.5]" {
+		t.Errorf("Intersect = %s; want [-1.3, 1.5]", &z)
+	}
+
+	for _, test := range []struct {
+		x    *Interval
+		want string
+	}{
+		{iv("1", "3"), "2"},
+		{iv("-1.5", "-1.5"), "0"},
+		{iv("1", "1.0001"), "0.0001"},
+		{New(dec("1"), dec("+Inf")), "+Inf"},
+		{New(dec("+Inf"), dec("+Inf")), "0"},
+	} {
+		if w := test.x.Width(); w.Cmp(dec(test.want)) != 0 {
+			t.Errorf("width %s = %s; want %s", test.x, w, test.want)
+		}
+	}
+	x = &Interval{new(decimal.Decimal).SetPrec(2).SetInt64(1), new(decimal.Decimal).SetPrec(2).SetFloat64(2.3)}
+	x.Lo.SetMantExp(x.Lo, -1) // 0.1
+	if w := x.Width(); w.String() != "2.2" {
+		t.Errorf("width %s = %s; want 2.2", x, w)
+	}
+	x.Hi.SetFloat64(9.9)
+	if w := x.Width(); w.String() != "9.8" {
+		t.Errorf("width %s = %s; want 9.8", x, w)
+	}
+	x.Lo.SetFloat64(-0.01)
+	if w := x.Width(); w.String() != "10" {
+		t.Errorf("width %s = %s; want 10 (rounded up)", x, w)
+	}
+}
@@ -0,0 +1,38 @@
+package lazy_test
+
+import (
+	"fmt"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/lazy"
+)
+
+// Rump's example: evaluating this polynomial naively yields wrong results even
+// with 30 digits of precision.
+func Example() {
+	a, b := lazy.Int64(77617), lazy.Int64(33096)
+	c := func(s string) *lazy.Expr {
+		x, _ := new(decimal.Decimal).SetPrec(10).SetString(s)
+		return lazy.Const(x)
+	}
+	b2 := b.Mul(b)
+	b4 := b2.Mul(b2)
+	b6 := b4.Mul(b2)
+	b8 := b4.Mul(b4)
+	a2 := a.Mul(a)
+	// 333.75b⁶ + a²(11a²b² - b⁶ - 121b⁴ - 2) + 5.5b⁸ + a/2b
+	f := c("333.75").Mul(b6).
+		Add(a2.Mul(lazy.Int64(11).Mul(a2).Mul(b2).Sub(b6).Sub(lazy.Int64(121).Mul(b4)).Sub(lazy.Int64(2)))).
+		Add(c("5.5").Mul(b8)).
+		Add(a.Quo(lazy.Int64(2).Mul(b)))
+
+	z := new(decimal.Decimal).SetPrec(30)
+	if _, err := f.Eval(z, 0); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(z.Text('g', -1), z.Acc())
+
+	// Output:
+	// -0.82739605994682136814116509548 Below
+}
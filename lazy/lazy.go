@@ -0,0 +1,292 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lazy implements lazily evaluated real expressions with results
+// correctly rounded to any precision.
+//
+// An Expr records a graph of operations on decimal constants. Nothing is
+// computed until Eval is called with the target precision and rounding mode.
+// Eval then uses Ziv's strategy: it evaluates the expression with interval
+// arithmetic at some working precision, yielding an interval that is known to
+// contain the exact value of the expression. If both bounds of the interval
+// round to the same Decimal at the target precision, that Decimal is the
+// correctly rounded result. Otherwise, the working precision is increased and
+// the evaluation restarts.
+//
+// This makes it possible to compute correctly rounded results for expressions
+// that suffer from catastrophic cancellation, like (1e20 + 1) - 1e20, without
+// having to guess the intermediate precision required.
+//
+// Expr values are immutable and can be safely shared between expressions and
+// goroutines. Common subexpressions are evaluated only once per working
+// precision. Since the enclosures of a subexpression used twice are not
+// independent, x.Sub(x) evaluates to exactly zero and x.Mul(x) is never
+// negative.
+package lazy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/interval"
+)
+
+var (
+	// ErrNoConvergence is returned by Eval when the correctly rounded value of
+	// an expression could not be determined within the working precision
+	// limit. This happens for instance if the exact value is a tie for the
+	// rounding mode or, for directed rounding modes, a Decimal with the target
+	// precision, but is not computed exactly at any working precision.
+	ErrNoConvergence = errors.New("lazy: no convergence")
+	// ErrUnknownAccuracy is returned by Eval when the correctly rounded value
+	// of an expression has been determined but not its accuracy. This happens
+	// when the exact value of the expression is a Decimal with the target
+	// precision but is not computed exactly at any working precision.
+	ErrUnknownAccuracy = errors.New("lazy: unknown accuracy")
+	// ErrDivisionByZero is returned by Eval when a divisor is exactly zero.
+	ErrDivisionByZero = errors.New("lazy: division by zero")
+)
+
+// DefaultMaxPrec is the working precision limit used by Eval if none is given.
+const DefaultMaxPrec = 10000
+
+type op byte
+
+const (
+	opConst op = iota
+	opAdd
+	opSub
+	opMul
+	opQuo
+	opNeg
+	opSqrt
+)
+
+var opSym = [...]string{opAdd: " + ", opSub: " - ", opMul: " × ", opQuo: " / "}
+
+// An Expr is a lazily evaluated real expression. The zero value for an Expr is
+// the constant 0.
+type Expr struct {
+	op   op
+	x, y *Expr
+	c    *decimal.Decimal // constant value for opConst; nil for 0
+}
+
+// Const returns an expression for the exact value of x. x is copied and can be
+// modified afterwards.
+func Const(x *decimal.Decimal) *Expr {
+	return &Expr{op: opConst, c: new(decimal.Decimal).Copy(x)}
+}
+
+// Int64 returns an expression for the integer x.
+func Int64(x int64) *Expr {
+	return Const(new(decimal.Decimal).SetPrec(19).SetInt64(x))
+}
+
+// Add returns an expression for x+y.
+func (x *Expr) Add(y *Expr) *Expr { return &Expr{op: opAdd, x: x, y: y} }
+
+// Sub returns an expression for x-y.
+func (x *Expr) Sub(y *Expr) *Expr { return &Expr{op: opSub, x: x, y: y} }
+
+// Mul returns an expression for x×y.
+func (x *Expr) Mul(y *Expr) *Expr { return &Expr{op: opMul, x: x, y: y} }
+
+// Quo returns an expression for x/y.
+func (x *Expr) Quo(y *Expr) *Expr { return &Expr{op: opQuo, x: x, y: y} }
+
+// Neg returns an expression for -x.
+func (x *Expr) Neg() *Expr { return &Expr{op: opNeg, x: x} }
+
+// Sqrt returns an expression for the square root of x.
+func (x *Expr) Sqrt() *Expr { return &Expr{op: opSqrt, x: x} }
+
+// String returns a fully parenthesized representation of x.
+func (x *Expr) String() string {
+	switch x.op {
+	case opConst:
+		if x.c == nil {
+			return "0"
+		}
+		return x.c.Text('g', -1)
+	case opNeg:
+		return "-" + x.x.String()
+	case opSqrt:
+		return "√" + x.x.String()
+	}
+	return "(" + x.x.String() + opSym[x.op] + x.y.String() + ")"
+}
+
+// Eval sets z to the value of x rounded according to z's precision and
+// rounding mode, and returns z. The result is correctly rounded and z's
+// accuracy reports its error relative to the exact value of x. If z's
+// precision is 0, it is changed to the largest precision of the constants in
+// x (or decimal.DefaultDecimalPrec if they are all 0) before the evaluation.
+// Since expressions represent real numbers, a zero result is always +0.
+//
+// The working precision starts a few digits above z's precision and doubles
+// after each unsuccessful evaluation, up to maxPrec digits. If maxPrec is 0,
+// DefaultMaxPrec is used. Eval returns an error if:
+//
+//   - the value of x could not be determined within the working precision
+//     limit. The error is ErrNoConvergence and the value of z is undefined;
+//   - the value of x has been determined but not its accuracy. The error is
+//     ErrUnknownAccuracy, z is set to the correctly rounded value of x and its
+//     accuracy is undefined;
+//   - a divisor is exactly zero. The error is ErrDivisionByZero;
+//   - an operation is undefined, like the square root of a negative value or
+//     the sum of infinities with opposite signs. The error is a decimal.ErrNaN.
+func (x *Expr) Eval(z *decimal.Decimal, maxPrec uint) (_ *decimal.Decimal, err error) {
+	prec := z.Prec()
+	if prec == 0 {
+		prec = x.maxPrec(make(map[*Expr]bool))
+		if prec == 0 {
+			prec = decimal.DefaultDecimalPrec
+		}
+		z.SetPrec(prec)
+	}
+	if maxPrec == 0 {
+		maxPrec = DefaultMaxPrec
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			if nan, ok := e.(decimal.ErrNaN); ok {
+				err = nan
+				return
+			}
+			panic(e)
+		}
+	}()
+
+	lo := new(decimal.Decimal).SetPrec(prec).SetMode(z.Mode())
+	hi := new(decimal.Decimal).SetPrec(prec).SetMode(z.Mode())
+	for w := prec + guardDigits; ; w *= 2 {
+		if w > maxPrec {
+			// do at least one evaluation at maxPrec
+			w = maxPrec
+		}
+		v, err := x.eval(w, make(map[*Expr]*interval.Interval))
+		if err != nil {
+			return z, err
+		}
+		// v.Lo <= x <= v.Hi
+		lo.Set(v.Lo)
+		hi.Set(v.Hi)
+		if lo.Sign() == 0 {
+			lo.Abs(lo)
+		}
+		if hi.Sign() == 0 {
+			hi.Abs(hi)
+		}
+		if lo.Cmp(hi) == 0 {
+			switch {
+			case v.Lo.Cmp(v.Hi) == 0:
+				// x == v.Lo, lo's accuracy applies
+				return z.Copy(lo), nil
+			case lo.Acc() == decimal.Below:
+				// lo < v.Lo <= x
+				return z.Copy(lo), nil
+			case hi.Acc() == decimal.Above:
+				// x <= v.Hi < hi
+				return z.Copy(hi), nil
+			}
+			if w >= maxPrec {
+				return z.Copy(lo), ErrUnknownAccuracy
+			}
+		}
+		if w >= maxPrec {
+			return z, fmt.Errorf("%w at precision %d", ErrNoConvergence, w)
+		}
+	}
+}
+
+// guardDigits is the number of digits above the target precision used for the
+// first evaluation.
+const guardDigits = 10
+
+// maxPrec returns the largest precision of the constants in x.
+func (x *Expr) maxPrec(seen map[*Expr]bool) uint {
+	if seen[x] {
+		return 0
+	}
+	seen[x] = true
+	if x.op == opConst {
+		if x.c == nil {
+			return 0
+		}
+		return x.c.Prec()
+	}
+	p := x.x.maxPrec(seen)
+	if x.y != nil {
+		if q := x.y.maxPrec(seen); q > p {
+			p = q
+		}
+	}
+	return p
+}
+
+// eval returns an interval enclosing the value of x, computed with the working
+// precision prec. Intervals for subexpressions already evaluated are looked up
+// in memo.
+func (x *Expr) eval(prec uint, memo map[*Expr]*interval.Interval) (*interval.Interval, error) {
+	if v := memo[x]; v != nil {
+		return v, nil
+	}
+	z := &interval.Interval{
+		Lo: new(decimal.Decimal).SetPrec(prec),
+		Hi: new(decimal.Decimal).SetPrec(prec),
+	}
+	if x.op == opConst {
+		c := x.c
+		if c == nil {
+			c = new(decimal.Decimal)
+		}
+		// the bounds are c rounded outwards
+		z.Set(&interval.Interval{Lo: c, Hi: c})
+		memo[x] = z
+		return z, nil
+	}
+
+	a, err := x.x.eval(prec, memo)
+	if err != nil {
+		return nil, err
+	}
+	var b *interval.Interval
+	if x.y != nil {
+		if b, err = x.y.eval(prec, memo); err != nil {
+			return nil, err
+		}
+	}
+	switch x.op {
+	case opAdd:
+		z.Add(a, b)
+	case opSub:
+		if x.x == x.y && !a.Lo.IsInf() && !a.Hi.IsInf() {
+			// x - x is exactly zero, whereas [a, b] - [a, b] = [a-b, b-a].
+			z.Lo.SetInt64(0)
+			z.Hi.SetInt64(0)
+			break
+		}
+		z.Sub(a, b)
+	case opMul:
+		z.Mul(a, b)
+		if x.x == x.y && z.Lo.Sign() < 0 {
+			// x × x is not negative
+			z.Lo.SetInt64(0)
+		}
+	case opQuo:
+		if b.Lo.Sign() == 0 && b.Hi.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		z.Quo(a, b)
+	case opNeg:
+		z.Neg(a)
+	case opSqrt:
+		z.Sqrt(a)
+	}
+	memo[x] = z
+	return z, nil
+}
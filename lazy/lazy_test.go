@@ -0,0 +1,211 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lazy
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/decimal"
+)
+
+var modes = []decimal.RoundingMode{
+	decimal.ToNearestEven, decimal.ToNearestAway, decimal.ToZero,
+	decimal.AwayFromZero, decimal.ToNegativeInf, decimal.ToPositiveInf,
+}
+
+func dec(s string) *decimal.Decimal {
+	x, ok := new(decimal.Decimal).SetPrec(50).SetString(s)
+	if !ok {
+		panic(s)
+	}
+	return x
+}
+
+func randConst(r *rand.Rand) (*Expr, *big.Rat) {
+	prec := 1 + r.Intn(25)
+	var m big.Int
+	m.Rand(r, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(prec)), nil))
+	if r.Intn(2) == 0 {
+		m.Neg(&m)
+	}
+	x := new(decimal.Decimal).SetPrec(uint(prec)).SetInt(&m)
+	x.SetMantExp(x, r.Intn(41)-20-x.MantExp(nil))
+	v, _ := x.Rat(nil)
+	return Const(x), v
+}
+
+// randExpr returns a random rational expression and its exact value, or a nil
+// value if it contains a division by zero.
+func randExpr(r *rand.Rand, depth int) (*Expr, *big.Rat) {
+	if depth == 0 || r.Intn(4) == 0 {
+		return randConst(r)
+	}
+	x, xv := randExpr(r, depth-1)
+	if r.Intn(8) == 0 {
+		if xv == nil {
+			return x.Neg(), nil
+		}
+		return x.Neg(), new(big.Rat).Neg(xv)
+	}
+	y, yv := x, xv
+	if r.Intn(4) != 0 {
+		y, yv = randExpr(r, depth-1)
+	}
+	if xv == nil || yv == nil {
+		return x.Add(y), nil
+	}
+	v := new(big.Rat)
+	switch r.Intn(4) {
+	case 0:
+		return x.Add(y), v.Add(xv, yv)
+	case 1:
+		return x.Sub(y), v.Sub(xv, yv)
+	case 2:
+		return x.Mul(y), v.Mul(xv, yv)
+	}
+	if yv.Sign() == 0 {
+		return x.Quo(y), nil
+	}
+	return x.Quo(y), v.Quo(xv, yv)
+}
+
+func TestEval(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		x, v := randExpr(r, 5)
+		prec := uint(1 + r.Intn(40))
+		mode := modes[r.Intn(len(modes))]
+		z := new(decimal.Decimal).SetPrec(prec).SetMode(mode)
+		_, err := x.Eval(z, 500)
+		if v == nil {
+			if err != ErrDivisionByZero {
+				t.Fatalf("%v: got error %v; want %v", x, err, ErrDivisionByZero)
+			}
+			continue
+		}
+		want := new(decimal.Decimal).SetPrec(prec).SetMode(mode).SetRat(v)
+		switch {
+		case err == nil:
+			if z.Cmp(want) != 0 || z.Acc() != want.Acc() || z.Sign() == 0 && z.Signbit() {
+				t.Fatalf("%v (prec %d, %s) = %s (%s); want %s (%s)", x, prec, mode, z, z.Acc(), want, want.Acc())
+			}
+		case err == ErrUnknownAccuracy:
+			if z.Cmp(want) != 0 || want.Acc() != decimal.Exact {
+				t.Fatalf("%v (prec %d, %s) = %s, %v; want %s (%s)", x, prec, mode, z, err, want, want.Acc())
+			}
+		case errors.Is(err, ErrNoConvergence):
+			// only exact or tie values may not converge
+			ulp := new(decimal.Decimal).SetPrec(prec + 1).SetRat(v)
+			if ulp.Acc() != decimal.Exact {
+				t.Fatalf("%v (prec %d, %s): unexpected error %v; want %s (%s)", x, prec, mode, err, want, want.Acc())
+			}
+		default:
+			t.Fatalf("%v: unexpected error %v", x, err)
+		}
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	a := Const(dec("1e20"))
+	x := a.Add(Int64(1)).Sub(a)
+	for _, mode := range modes {
+		z, err := x.Eval(new(decimal.Decimal).SetPrec(5).SetMode(mode), 0)
+		if err != nil || z.Cmp(dec("1")) != 0 || z.Acc() != decimal.Exact {
+			t.Errorf("%v (%s) = %s (%s), %v; want 1 (Exact)", x, mode, z, z.Acc(), err)
+		}
+	}
+
+	// 1/3 - 0.333…3 = 1e-40/3
+	third := Int64(1).Quo(Int64(3))
+	x = third.Sub(Const(dec("0.3333333333333333333333333333333333333333")))
+	z, err := x.Eval(new(decimal.Decimal).SetPrec(10), 0)
+	if err != nil || z.Cmp(dec("3.333333333e-41")) != 0 || z.Acc() != decimal.Below {
+		t.Errorf("%v = %s (%s), %v; want 3.333333333e-41 (Below)", x, z, z.Acc(), err)
+	}
+
+	// exact zero
+	x = third.Sub(third)
+	for _, mode := range modes {
+		z, err := x.Eval(new(decimal.Decimal).SetPrec(5).SetMode(mode), 0)
+		if err != nil || z.Sign() != 0 || z.Signbit() || z.Acc() != decimal.Exact {
+			t.Errorf("%v (%s) = %s (%s), %v; want 0 (Exact)", x, mode, z, z.Acc(), err)
+		}
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	two := Int64(2)
+	s := two.Sqrt()
+	z, err := s.Eval(new(decimal.Decimal).SetPrec(30), 0)
+	if err != nil || z.Text('g', -1) != "1.41421356237309504880168872421" || z.Acc() != decimal.Above {
+		t.Errorf("√2 = %s (%s), %v", z, z.Acc(), err)
+	}
+	z, err = Int64(16).Quo(Int64(100)).Sqrt().Eval(new(decimal.Decimal).SetPrec(30).SetMode(decimal.ToZero), 0)
+	if err != nil || z.Cmp(dec("0.4")) != 0 || z.Acc() != decimal.Exact {
+		t.Errorf("√0.16 = %s (%s), %v; want 0.4 (Exact)", z, z.Acc(), err)
+	}
+	// √2 × √2 is 2, but can only be enclosed.
+	z, err = s.Mul(s).Eval(new(decimal.Decimal).SetPrec(10), 100)
+	if err != ErrUnknownAccuracy || z.Cmp(dec("2")) != 0 {
+		t.Errorf("√2 × √2 = %s, %v; want 2, %v", z, err, ErrUnknownAccuracy)
+	}
+	if _, err = s.Mul(s).Eval(new(decimal.Decimal).SetPrec(10).SetMode(decimal.ToZero), 100); !errors.Is(err, ErrNoConvergence) {
+		t.Errorf("√2 × √2 (ToZero): got error %v; want %v", err, ErrNoConvergence)
+	}
+	if _, err = Int64(-1).Sqrt().Eval(new(decimal.Decimal), 0); !errors.As(err, new(decimal.ErrNaN)) {
+		t.Errorf("√-1: got error %v; want ErrNaN", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	one, three := Int64(1), Int64(3)
+	for _, test := range []struct {
+		x   *Expr
+		err error
+	}{
+		{one.Quo(new(Expr)), ErrDivisionByZero},
+		{one.Quo(one.Sub(one)), ErrDivisionByZero},
+		{one.Quo(one.Quo(three).Mul(three).Sub(one)), ErrNoConvergence},
+		{Const(dec("+Inf")).Add(Const(dec("-Inf"))), decimal.ErrNaN{}},
+	} {
+		_, err := test.x.Eval(new(decimal.Decimal).SetPrec(10), 200)
+		if _, ok := test.err.(decimal.ErrNaN); ok {
+			if _, ok := err.(decimal.ErrNaN); !ok {
+				t.Errorf("%v: got error %v; want ErrNaN", test.x, err)
+			}
+		} else if !errors.Is(err, test.err) {
+			t.Errorf("%v: got error %v; want %v", test.x, err, test.err)
+		}
+	}
+}
+
+func TestPrecAndString(t *testing.T) {
+	x := Const(new(decimal.Decimal).SetPrec(7).SetInt64(1)).Quo(Int64(7))
+	if s := x.String(); s != "(1 / 7)" {
+		t.Errorf("String() = %q; want %q", s, "(1 / 7)")
+	}
+	// precision 0
+	z, err := x.Eval(new(decimal.Decimal), 0)
+	if err != nil || z.Prec() != 19 || z.Text('g', -1) != "0.1428571428571428571" {
+		t.Errorf("1/7 = %s (prec %d), %v", z, z.Prec(), err)
+	}
+	if z, err := new(Expr).Eval(new(decimal.Decimal), 0); err != nil || z.Prec() != decimal.DefaultDecimalPrec || z.Sign() != 0 {
+		t.Errorf("0 = %s (prec %d), %v", z, z.Prec(), err)
+	}
+	s := Int64(2).Sqrt()
+	if str := s.Mul(s).Neg().String(); str != "-(√2 × √2)" {
+		t.Errorf("String() = %q", str)
+	}
+	// the source Decimal can be modified
+	d := dec("3")
+	x = Const(d)
+	d.SetInt64(4)
+	if z, _ := x.Eval(new(decimal.Decimal), 0); z.Cmp(dec("3")) != 0 {
+		t.Errorf("Const(3) = %s; want 3", z)
+	}
+}
@@ -0,0 +1,32 @@
+package money_test
+
+import (
+	"fmt"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/money"
+)
+
+func Example() {
+	price, _ := money.Parse("100.00", "USD")
+	rate, _ := new(decimal.Decimal).SetPrec(10).SetString("0.0825")
+	tax := price.Mul(rate, decimal.ToNearestAway)
+	total, _ := price.Add(tax)
+	fmt.Println(total)
+
+	// split the bill three ways
+	for _, part := range total.Split(3) {
+		fmt.Println(part)
+	}
+
+	eur, _ := money.Parse("1", "EUR")
+	_, err := total.Add(eur)
+	fmt.Println(err)
+
+	// Output:
+	// USD 108.25
+	// USD 36.09
+	// USD 36.08
+	// USD 36.08
+	// money: add USD and EUR: currency mismatch
+}
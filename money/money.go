@@ -0,0 +1,327 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package money provides an immutable monetary amount type built on top of
+// decimal.Decimal.
+//
+// A Money value is an amount of a given ISO 4217 currency with a fixed number
+// of fraction digits: the currency's minor unit (2 for USD, 0 for JPY, 3 for
+// KWD). Amounts are therefore always exact multiples of the minor unit, and
+// operations that would produce a fraction of it either round explicitly
+// (Mul, NewRounded) or distribute the remainder (Allocate, Split).
+//
+// Operations on amounts of different currencies fail with an error that wraps
+// ErrCurrencyMismatch.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/format"
+)
+
+var (
+	// ErrCurrencyMismatch is returned by operations on amounts of different
+	// currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrUnknownCurrency is returned by constructors when the currency code is
+	// not a known ISO 4217 code.
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrInexact is returned by constructors when an amount is not a multiple
+	// of the currency's minor unit.
+	ErrInexact = errors.New("amount not a multiple of the minor unit")
+	// ErrInfinite is returned by constructors when an amount is infinite.
+	ErrInfinite = errors.New("infinite amount")
+)
+
+// Money is an amount of a given currency. Money values are immutable and can be
+// safely copied and shared.
+//
+// The zero value is a zero amount with no currency. Binary operations with an
+// amount of any other currency fail like for mismatched currencies. Other
+// values must be created with one of the constructors.
+type Money struct {
+	units *big.Int // amount in minor units, nil for the zero value; never modified
+	cur   format.Currency
+}
+
+// zero is the amount of the zero Money value.
+var zero = new(big.Int)
+
+func lookup(code string) (format.Currency, error) {
+	c, ok := format.LookupCurrency(code)
+	if !ok {
+		return c, fmt.Errorf("money: %q: %w", code, ErrUnknownCurrency)
+	}
+	return c, nil
+}
+
+// New returns the Money value for an amount x of the currency with the given
+// ISO 4217 code. x must be finite and a multiple of the currency's minor unit.
+func New(x *decimal.Decimal, code string) (Money, error) {
+	c, err := lookup(code)
+	if err != nil {
+		return Money{}, err
+	}
+	u, exact := toUnits(x, c.Digits, decimal.ToZero)
+	if u == nil {
+		return Money{}, fmt.Errorf("money: %s %s: %w", code, x.Text('g', -1), ErrInfinite)
+	}
+	if !exact {
+		return Money{}, fmt.Errorf("money: %s %s: %w", code, x.Text('g', -1), ErrInexact)
+	}
+	return Money{u, c}, nil
+}
+
+// NewRounded is like New but rounds x to the currency's minor unit with the
+// given rounding mode. x must be finite.
+func NewRounded(x *decimal.Decimal, code string, mode decimal.RoundingMode) (Money, error) {
+	c, err := lookup(code)
+	if err != nil {
+		return Money{}, err
+	}
+	u, _ := toUnits(x, c.Digits, mode)
+	if u == nil {
+		return Money{}, fmt.Errorf("money: %s %s: %w", code, x.Text('g', -1), ErrInfinite)
+	}
+	return Money{u, c}, nil
+}
+
+// FromMinor returns the Money value for an amount expressed in minor units of
+// the currency with the given ISO 4217 code (cents for USD).
+func FromMinor(units int64, code string) (Money, error) {
+	c, err := lookup(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{big.NewInt(units), c}, nil
+}
+
+// Parse returns the Money value for the amount represented by s, in the same
+// format as for (*decimal.Decimal).SetString. The amount must be a multiple of
+// the currency's minor unit.
+func Parse(s, code string) (Money, error) {
+	// enough precision for all the digits of s
+	x, ok := new(decimal.Decimal).SetPrec(uint(len(s))).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("money: cannot parse %q", s)
+	}
+	return New(x, code)
+}
+
+// toUnits returns x × 10**digits rounded to an integer using the given
+// rounding mode, and reports whether the result is exact. It returns nil if x
+// is infinite.
+func toUnits(x *decimal.Decimal, digits int, mode decimal.RoundingMode) (*big.Int, bool) {
+	if x.IsInf() {
+		return nil, false
+	}
+	y := new(decimal.Decimal).SetMantExp(x, digits) // exact
+	if y.IsInt() {
+		u, _ := y.Int(nil)
+		return u, true
+	}
+	u, _ := new(decimal.Decimal).SetMode(mode).RoundInt(y).Int(nil)
+	return u, false
+}
+
+// minorUnits returns m's amount in minor units. The result must not be
+// modified.
+func (m Money) minorUnits() *big.Int {
+	if m.units == nil {
+		return zero
+	}
+	return m.units
+}
+
+func (m Money) money(u *big.Int) Money {
+	return Money{u, m.cur}
+}
+
+// check returns an error wrapping ErrCurrencyMismatch if m and n are amounts
+// of different currencies.
+func (m Money) check(op string, n Money) error {
+	if m.cur.Code != n.cur.Code {
+		return fmt.Errorf("money: %s %s and %s: %w", op, m.cur.Code, n.cur.Code, ErrCurrencyMismatch)
+	}
+	return nil
+}
+
+// Currency returns m's currency.
+func (m Money) Currency() format.Currency {
+	return m.cur
+}
+
+// Code returns the ISO 4217 code of m's currency.
+func (m Money) Code() string {
+	return m.cur.Code
+}
+
+// Amount returns m's amount as a new Decimal with the minimum precision
+// required to represent it exactly.
+func (m Money) Amount() *decimal.Decimal {
+	x := new(decimal.Decimal).SetInt(m.minorUnits())
+	if x.Sign() != 0 {
+		x.SetPrec(x.MinPrec())
+	}
+	return x.SetMantExp(x, -m.cur.Digits)
+}
+
+// Minor returns m's amount in minor units (cents for USD).
+func (m Money) Minor() *big.Int {
+	return new(big.Int).Set(m.minorUnits())
+}
+
+// Sign returns -1, 0 or +1 depending on whether m is negative, zero or
+// positive.
+func (m Money) Sign() int {
+	return m.minorUnits().Sign()
+}
+
+// IsZero reports whether m is zero.
+func (m Money) IsZero() bool {
+	return m.minorUnits().Sign() == 0
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return m.money(new(big.Int).Neg(m.minorUnits()))
+}
+
+// Abs returns |m|.
+func (m Money) Abs() Money {
+	if m.minorUnits().Sign() >= 0 {
+		return m
+	}
+	return m.Neg()
+}
+
+// Add returns m + n.
+func (m Money) Add(n Money) (Money, error) {
+	if err := m.check("add", n); err != nil {
+		return Money{}, err
+	}
+	return m.money(new(big.Int).Add(m.minorUnits(), n.minorUnits())), nil
+}
+
+// Sub returns m - n.
+func (m Money) Sub(n Money) (Money, error) {
+	if err := m.check("subtract", n); err != nil {
+		return Money{}, err
+	}
+	return m.money(new(big.Int).Sub(m.minorUnits(), n.minorUnits())), nil
+}
+
+// Cmp compares m and n and returns -1 if m < n, 0 if m == n and +1 if m > n.
+func (m Money) Cmp(n Money) (int, error) {
+	if err := m.check("compare", n); err != nil {
+		return 0, err
+	}
+	return m.minorUnits().Cmp(n.minorUnits()), nil
+}
+
+// Equal reports whether m and n are the same amount of the same currency.
+func (m Money) Equal(n Money) bool {
+	return m.cur.Code == n.cur.Code && m.minorUnits().Cmp(n.minorUnits()) == 0
+}
+
+// Mul returns m × x, rounded to the currency's minor unit with the given
+// rounding mode. Mul panics if x is infinite.
+func (m Money) Mul(x *decimal.Decimal, mode decimal.RoundingMode) Money {
+	if x.IsInf() {
+		panic("money: Mul: infinite factor")
+	}
+	a := m.Amount()
+	z := new(decimal.Decimal).SetPrec(a.Prec()+x.MinPrec()).Mul(a, x) // exact
+	u, _ := toUnits(z, m.cur.Digits, mode)
+	return m.money(u)
+}
+
+// Split is like Allocate with n equal ratios: it divides m into n parts that
+// differ by at most one minor unit, larger parts first. n must be > 0.
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		panic("money: Split: n <= 0")
+	}
+	r := make([]int64, n)
+	for i := range r {
+		r[i] = 1
+	}
+	parts, _ := m.Allocate(r...)
+	return parts
+}
+
+// Allocate divides m in parts proportional to the given ratios, without
+// losing or creating minor units: the sum of the parts is always m.
+//
+// The parts are first rounded toward zero to a whole number of minor units.
+// The remaining units are then distributed one at a time to the parts with the
+// largest remainders (the largest remainder method); ties are broken in favor
+// of the first parts.
+//
+// Allocate returns an error if there are no ratios, if any ratio is negative or
+// if their sum is zero.
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	if len(ratios) == 0 {
+		return nil, errors.New("money: Allocate: no ratios")
+	}
+	total := new(big.Int)
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, fmt.Errorf("money: Allocate: negative ratio %d", r)
+		}
+		total.Add(total, big.NewInt(r))
+	}
+	if total.Sign() == 0 {
+		return nil, errors.New("money: Allocate: ratios sum to zero")
+	}
+
+	// allocate |m| and restore the sign at the end
+	abs := new(big.Int).Abs(m.minorUnits())
+	parts := make([]*big.Int, len(ratios))
+	rems := make([]*big.Int, len(ratios))
+	left := new(big.Int).Set(abs)
+	for i, r := range ratios {
+		parts[i], rems[i] = new(big.Int).QuoRem(new(big.Int).Mul(abs, big.NewInt(r)), total, new(big.Int))
+		left.Sub(left, parts[i])
+	}
+	// left < len(ratios)
+	idx := make([]int, len(ratios))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return rems[idx[i]].Cmp(rems[idx[j]]) > 0 })
+	one := big.NewInt(1)
+	for i := 0; left.Sign() > 0; i++ {
+		parts[idx[i]].Add(parts[idx[i]], one)
+		left.Sub(left, one)
+	}
+
+	res := make([]Money, len(parts))
+	for i, p := range parts {
+		if m.minorUnits().Sign() < 0 {
+			p.Neg(p)
+		}
+		res[i] = m.money(p)
+	}
+	return res, nil
+}
+
+// String returns m's amount with its currency code, like "USD 1234.50". The
+// zero value is formatted as "0".
+func (m Money) String() string {
+	if m.cur.Code == "" {
+		return m.Amount().Text('f', m.cur.Digits)
+	}
+	return m.cur.Code + " " + m.Amount().Text('f', m.cur.Digits)
+}
+
+// Format returns m formatted by f. See (*format.Formatter).FormatMoney.
+func (m Money) Format(f *format.Formatter) string {
+	return f.FormatMoney(m.Amount(), m.cur)
+}
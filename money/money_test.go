@@ -0,0 +1,342 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package money
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/decimal"
+	"github.com/db47h/decimal/format"
+)
+
+func dec(s string) *decimal.Decimal {
+	x, ok := new(decimal.Decimal).SetPrec(50).SetString(s)
+	if !ok {
+		panic(s)
+	}
+	return x
+}
+
+func mustParse(s, code string) Money {
+	m, err := Parse(s, code)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		x, code string
+		want    string
+		err     error
+	}{
+		{"12.34", "USD", "USD 12.34", nil},
+		{"12.3", "usd", "USD 12.30", nil},
+		{"-12", "JPY", "JPY -12", nil},
+		{"1.234", "KWD", "KWD 1.234", nil},
+		{"1e30", "EUR", "EUR 1000000000000000000000000000000.00", nil},
+		{"0", "EUR", "EUR 0.00", nil},
+		{"12.345", "USD", "", ErrInexact},
+		{"12.5", "JPY", "", ErrInexact},
+		{"1e-100", "EUR", "", ErrInexact},
+		{"Inf", "EUR", "", ErrInfinite},
+		{"-Inf", "EUR", "", ErrInfinite},
+		{"1", "XYZ", "", ErrUnknownCurrency},
+	} {
+		m, err := New(dec(test.x), test.code)
+		if !errors.Is(err, test.err) {
+			t.Errorf("New(%s, %s): got error %v; want %v", test.x, test.code, err, test.err)
+			continue
+		}
+		if err == nil && m.String() != test.want {
+			t.Errorf("New(%s, %s) = %s; want %s", test.x, test.code, m, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		x, code string
+		mode    decimal.RoundingMode
+		want    string
+	}{
+		{"12.345", "USD", decimal.ToNearestEven, "USD 12.34"},
+		{"12.345", "USD", decimal.ToNearestAway, "USD 12.35"},
+		{"-12.345", "USD", decimal.ToNearestAway, "USD -12.35"},
+		{"12.341", "USD", decimal.ToPositiveInf, "USD 12.35"},
+		{"0.001", "USD", decimal.ToNearestEven, "USD 0.00"},
+		{"0.001", "USD", decimal.AwayFromZero, "USD 0.01"},
+		{"-0.001", "USD", decimal.AwayFromZero, "USD -0.01"},
+		{"2.5", "JPY", decimal.ToNearestEven, "JPY 2"},
+		{"1.0005", "KWD", decimal.ToNearestEven, "KWD 1.000"},
+	} {
+		m, err := NewRounded(dec(test.x), test.code, test.mode)
+		if err != nil || m.String() != test.want {
+			t.Errorf("NewRounded(%s, %s, %s) = %s, %v; want %s", test.x, test.code, test.mode, m, err, test.want)
+		}
+	}
+
+	for _, x := range []string{"Inf", "-Inf"} {
+		if _, err := NewRounded(dec(x), "USD", decimal.ToNearestEven); !errors.Is(err, ErrInfinite) {
+			t.Errorf("NewRounded(%s, USD): got error %v; want %v", x, err, ErrInfinite)
+		}
+	}
+
+	m, err := FromMinor(-1234, "USD")
+	if err != nil || m.String() != "USD -12.34" || m.Minor().Int64() != -1234 {
+		t.Errorf("FromMinor(-1234, USD) = %s, %v", m, err)
+	}
+	if got := m.Amount(); got.Cmp(dec("-12.34")) != 0 {
+		t.Errorf("%s.Amount() = %s", m, got)
+	}
+	if _, err := Parse("1,2", "USD"); err == nil {
+		t.Errorf("Parse(1,2) succeeded")
+	}
+}
+
+func TestArith(t *testing.T) {
+	a, b := mustParse("10.25", "USD"), mustParse("-3.10", "USD")
+	e := mustParse("1", "EUR")
+
+	if s, err := a.Add(b); err != nil || s.String() != "USD 7.15" {
+		t.Errorf("%s + %s = %s, %v", a, b, s, err)
+	}
+	if s, err := b.Sub(a); err != nil || s.String() != "USD -13.35" {
+		t.Errorf("%s - %s = %s, %v", b, a, s, err)
+	}
+	if c, err := b.Cmp(a); err != nil || c != -1 {
+		t.Errorf("%s.Cmp(%s) = %d, %v", b, a, c, err)
+	}
+	if !b.Neg().Equal(b.Abs()) || b.Abs().Sign() != 1 || b.Sign() != -1 {
+		t.Errorf("Neg/Abs/Sign error")
+	}
+	if a.Equal(mustParse("10.25", "CAD")) {
+		t.Errorf("%s equal to CAD amount", a)
+	}
+	for _, err := range []error{
+		func() error { _, err := a.Add(e); return err }(),
+		func() error { _, err := a.Sub(e); return err }(),
+		func() error { _, err := a.Cmp(e); return err }(),
+	} {
+		if !errors.Is(err, ErrCurrencyMismatch) {
+			t.Errorf("got error %v; want ErrCurrencyMismatch", err)
+		}
+	}
+
+	// immutability
+	if a.String() != "USD 10.25" || b.String() != "USD -3.10" {
+		t.Errorf("operands modified: %s, %s", a, b)
+	}
+	a.Minor().SetInt64(0)
+	a.Amount().SetInt64(0)
+	if a.String() != "USD 10.25" {
+		t.Errorf("a modified: %s", a)
+	}
+
+	for _, test := range []struct {
+		m    Money
+		x    string
+		mode decimal.RoundingMode
+		want string
+	}{
+		{a, "0.2", decimal.ToNearestEven, "USD 2.05"},
+		{a, "0.0825", decimal.ToNearestEven, "USD 0.85"}, // 0.845625
+		{a, "0.07", decimal.ToNearestEven, "USD 0.72"},   // 0.7175
+		{a, "0.07", decimal.ToZero, "USD 0.71"},
+		{b, "1.5", decimal.ToNearestEven, "USD -4.65"},
+		{b, "0.005", decimal.ToNearestAway, "USD -0.02"},                     // -0.0155
+		{mustParse("0.05", "USD"), "0.1", decimal.ToNearestEven, "USD 0.00"}, // 0.005
+		{mustParse("0.15", "USD"), "0.1", decimal.ToNearestEven, "USD 0.02"}, // 0.015
+		{mustParse("0", "USD"), "123.456", decimal.ToNearestEven, "USD 0.00"},
+		{a, "0", decimal.ToNearestEven, "USD 0.00"},
+		{mustParse("1234567890123456789012345678901234567890", "JPY"), "1.1", decimal.ToNearestEven,
+			"JPY 1358024679135802467913580246791358024679"},
+	} {
+		if got := test.m.Mul(dec(test.x), test.mode); got.String() != test.want {
+			t.Errorf("%s × %s (%s) = %s; want %s", test.m, test.x, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestZero(t *testing.T) {
+	var z Money
+	if z.Sign() != 0 || !z.IsZero() || z.Code() != "" || z.Minor().Sign() != 0 || z.Amount().Sign() != 0 {
+		t.Errorf("zero value is not zero")
+	}
+	if !z.Abs().Equal(z) || !z.Neg().Equal(z) || !z.Equal(Money{}) {
+		t.Errorf("Neg/Abs/Equal error")
+	}
+	if s, err := z.Add(z); err != nil || !s.IsZero() {
+		t.Errorf("%s + %s = %s, %v", z, z, s, err)
+	}
+	if c, err := z.Cmp(z); err != nil || c != 0 {
+		t.Errorf("%s.Cmp(%s) = %d, %v", z, z, c, err)
+	}
+	if got := z.Mul(dec("1.5"), decimal.ToNearestEven); !got.IsZero() {
+		t.Errorf("%s × 1.5 = %s", z, got)
+	}
+	if parts := z.Split(3); len(parts) != 3 || !parts[0].IsZero() || !parts[2].IsZero() {
+		t.Errorf("Split(3) = %v", parts)
+	}
+	if got := z.String(); got != "0" {
+		t.Errorf("String() = %q; want %q", got, "0")
+	}
+	if got := z.Format(format.MustCompile("#,##0.00")); got != "0" {
+		t.Errorf("Format() = %q; want %q", got, "0")
+	}
+
+	a := mustParse("1", "USD")
+	if a.Equal(z) || z.Equal(a) {
+		t.Errorf("%s equal to zero value", a)
+	}
+	for _, err := range []error{
+		func() error { _, err := a.Add(z); return err }(),
+		func() error { _, err := z.Sub(a); return err }(),
+		func() error { _, err := z.Cmp(a); return err }(),
+	} {
+		if !errors.Is(err, ErrCurrencyMismatch) {
+			t.Errorf("got error %v; want ErrCurrencyMismatch", err)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	for _, test := range []struct {
+		m      string
+		code   string
+		ratios []int64
+		want   []string
+	}{
+		{"100", "USD", []int64{1, 1, 1}, []string{"33.34", "33.33", "33.33"}},
+		{"0.05", "USD", []int64{3, 7}, []string{"0.02", "0.03"}},
+		{"-0.05", "USD", []int64{3, 7}, []string{"-0.02", "-0.03"}},
+		{"0.05", "USD", []int64{1, 1}, []string{"0.03", "0.02"}},
+		{"0.01", "USD", []int64{1, 1, 1}, []string{"0.01", "0.00", "0.00"}},
+		{"10", "USD", []int64{70, 20, 10}, []string{"7.00", "2.00", "1.00"}},
+		{"1", "JPY", []int64{1, 0, 1}, []string{"1", "0", "0"}},
+		{"100", "JPY", []int64{1, 2, 3, 4}, []string{"10", "20", "30", "40"}},
+		{"101", "JPY", []int64{1, 1, 1, 1, 1, 1}, []string{"17", "17", "17", "17", "17", "16"}},
+		{"1000", "JPY", []int64{1, 1, 1}, []string{"334", "333", "333"}},
+		// remainders 2/3, 1/3: the second part has the largest remainder
+		{"5", "JPY", []int64{1, 2}, []string{"2", "3"}},
+		{"0", "EUR", []int64{5, 1}, []string{"0.00", "0.00"}},
+	} {
+		m := mustParse(test.m, test.code)
+		parts, err := m.Allocate(test.ratios...)
+		if err != nil {
+			t.Errorf("%s.Allocate(%v): %v", m, test.ratios, err)
+			continue
+		}
+		if len(parts) != len(test.want) {
+			t.Errorf("%s.Allocate(%v) = %v; want %v", m, test.ratios, parts, test.want)
+			continue
+		}
+		for i, p := range parts {
+			if want := mustParse(test.want[i], test.code); !p.Equal(want) {
+				t.Errorf("%s.Allocate(%v) = %v; want %v", m, test.ratios, parts, test.want)
+				break
+			}
+		}
+	}
+
+	m := mustParse("1", "USD")
+	for _, ratios := range [][]int64{nil, {0, 0}, {1, -1}} {
+		if _, err := m.Allocate(ratios...); err == nil {
+			t.Errorf("Allocate(%v) succeeded", ratios)
+		}
+	}
+}
+
+// TestAllocateSum checks that allocations neither lose nor create minor units
+// and that parts are within one minor unit of their exact share.
+func TestAllocateSum(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		m, _ := FromMinor(r.Int63n(1e12)-5e11, "EUR")
+		ratios := make([]int64, 1+r.Intn(10))
+		total := int64(0)
+		for j := range ratios {
+			ratios[j] = r.Int63n(1000)
+			total += ratios[j]
+		}
+		if total == 0 {
+			ratios[0] = 1
+			total = 1
+		}
+		parts, err := m.Allocate(ratios...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := new(big.Int)
+		for j, p := range parts {
+			if p.Code() != "EUR" {
+				t.Fatalf("bad currency %s", p.Code())
+			}
+			sum.Add(sum, p.Minor())
+			// |p - m×r/total| < 1 minor unit
+			share := new(big.Rat).SetFrac(new(big.Int).Mul(m.Minor(), big.NewInt(ratios[j])), big.NewInt(total))
+			d := new(big.Rat).Sub(new(big.Rat).SetInt(p.Minor()), share)
+			if d.Abs(d).Cmp(big.NewRat(1, 1)) >= 0 {
+				t.Fatalf("%s.Allocate(%v): part %d = %s too far from %s", m, ratios, j, p, share.FloatString(2))
+			}
+		}
+		if sum.Cmp(m.Minor()) != 0 {
+			t.Fatalf("%s.Allocate(%v) = %v: sum is %s", m, ratios, parts, sum)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	m := mustParse("-100", "USD")
+	parts := m.Split(3)
+	want := []string{"-33.34", "-33.33", "-33.33"}
+	for i, p := range parts {
+		if !p.Equal(mustParse(want[i], "USD")) {
+			t.Fatalf("%s.Split(3) = %v; want %v", m, parts, want)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Split(0) did not panic")
+		}
+	}()
+	m.Split(0)
+}
+
+func TestFormat(t *testing.T) {
+	m := mustParse("-1234.5", "EUR")
+	if got, want := m.Format(format.MustCompile(format.AccountingPattern)), "(€1,234.50)"; got != want {
+		t.Errorf("%s.Format() = %s; want %s", m, got, want)
+	}
+}
+
+func TestToUnits(t *testing.T) {
+	for _, test := range []struct {
+		x      string
+		digits int
+		mode   decimal.RoundingMode
+		want   string
+		exact  bool
+	}{
+		{"10.25", 2, decimal.ToNearestEven, "1025", true},
+		{"10.255", 2, decimal.ToNearestEven, "1026", false},
+		{"10.245", 2, decimal.ToNearestEven, "1024", false},
+		{"10.245", 2, decimal.ToNearestAway, "1025", false},
+		{"-10.245", 2, decimal.ToNearestAway, "-1025", false},
+		{"-10.241", 2, decimal.ToNegativeInf, "-1025", false},
+		{"0.004", 2, decimal.ToPositiveInf, "1", false},
+		{"-0.004", 2, decimal.ToZero, "0", false},
+		{"0.4", 0, decimal.ToNearestEven, "0", false},
+		{"12345678901234567890123.4", 0, decimal.ToZero, "12345678901234567890123", false},
+	} {
+		x, _ := new(decimal.Decimal).SetPrec(30).SetString(test.x)
+		u, exact := toUnits(x, test.digits, test.mode)
+		if u.String() != test.want || exact != test.exact {
+			t.Errorf("toUnits(%s, %d, %s) = %s, %v; want %s, %v", test.x, test.digits, test.mode, u, exact, test.want, test.exact)
+		}
+	}
+}
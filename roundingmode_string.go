@@ -14,11 +14,14 @@ func _() {
 	_ = x[AwayFromZero-3]
 	_ = x[ToNegativeInf-4]
 	_ = x[ToPositiveInf-5]
+	_ = x[ToNearestTowardZero-6]
+	_ = x[Round05Up-7]
+	_ = x[ToOdd-8]
 }
 
-const _RoundingMode_name = "ToNearestEvenToNearestAwayToZeroAwayFromZeroToNegativeInfToPositiveInf"
+const _RoundingMode_name = "ToNearestEvenToNearestAwayToZeroAwayFromZeroToNegativeInfToPositiveInfToNearestTowardZeroRound05UpToOdd"
 
-var _RoundingMode_index = [...]uint8{0, 13, 26, 32, 44, 57, 70}
+var _RoundingMode_index = [...]uint8{0, 13, 26, 32, 44, 57, 70, 89, 98, 103}
 
 func (i RoundingMode) String() string {
 	if i >= RoundingMode(len(_RoundingMode_index)-1) {
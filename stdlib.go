@@ -56,13 +56,26 @@ const (
 type RoundingMode byte
 
 // These constants define supported rounding modes.
+//
+// ToNearestTowardZero rounds to nearest, with ties rounded toward zero.
+// Round05Up rounds toward zero, unless the last digit of the truncated result
+// is 0 or 5, in which case it rounds away from zero. ToOdd rounds toward zero,
+// unless the last digit of the truncated result is even, in which case it
+// rounds away from zero. Exact results are never rounded.
+//
+// Rounding a result to odd with at least two extra digits, then rounding it
+// to its final precision in any other mode yields the same result as rounding
+// the exact value directly: there are no double rounding errors.
 const (
-	ToNearestEven RoundingMode = iota // == IEEE 754-2008 roundTiesToEven
-	ToNearestAway                     // == IEEE 754-2008 roundTiesToAway
-	ToZero                            // == IEEE 754-2008 roundTowardZero
-	AwayFromZero                      // no IEEE 754-2008 equivalent
-	ToNegativeInf                     // == IEEE 754-2008 roundTowardNegative
-	ToPositiveInf                     // == IEEE 754-2008 roundTowardPositive
+	ToNearestEven       RoundingMode = iota // == IEEE 754-2008 roundTiesToEven
+	ToNearestAway                           // == IEEE 754-2008 roundTiesToAway
+	ToZero                                  // == IEEE 754-2008 roundTowardZero
+	AwayFromZero                            // no IEEE 754-2008 equivalent
+	ToNegativeInf                           // == IEEE 754-2008 roundTowardNegative
+	ToPositiveInf                           // == IEEE 754-2008 roundTowardPositive
+	ToNearestTowardZero                     // no IEEE 754-2008 equivalent (ROUND_HALF_DOWN)
+	Round05Up                               // no IEEE 754-2008 equivalent (ROUND_05UP)
+	ToOdd                                   // no IEEE 754-2008 equivalent
 )
 
 //go:generate stringer -type=RoundingMode
@@ -247,8 +260,6 @@ func (err ErrNaN) Error() string {
 
 type nat []Word
 
-const divRecursiveThreshold = 100
-
 // karatsubaLen computes an approximation to the maximum k <= n such that
 // k = p<<i for a number p <= threshold and an i >= 0. Thus, the
 // result is the largest number that can be divided repeatedly by 2 before
@@ -357,29 +368,36 @@ var pow5tab = [...]uint64{
 	7450580596923828125,
 }
 
-// pow5 sets z to 5**n and returns z.
+// floatPow5 sets z to 5**n × 2**-exp and returns exp, with 0.5 <= z < 1.
 // n must not be negative.
-func floatPow5(z *big.Float, n uint64) *big.Float {
+//
+// All intermediate results are rounded using z's precision and rounding mode.
+// If z's rounding mode is big.ToZero or big.AwayFromZero, the result is
+// therefore a lower or upper bound of 5**n. Since the binary exponent is kept
+// separately, floatPow5 does not overflow.
+func floatPow5(z *big.Float, n uint64) (exp int64) {
 	const m = uint64(len(pow5tab) - 1)
 	if n <= m {
-		return z.SetUint64(pow5tab[n])
+		return int64(z.SetUint64(pow5tab[n]).MantExp(z))
 	}
 	// n > m
 
-	z.SetUint64(pow5tab[m])
+	exp = int64(z.SetUint64(pow5tab[m]).MantExp(z))
 	n -= m
 
-	// use more bits for f than for z
-	// TODO(gri) what is the right number?
-	f := new(big.Float).SetPrec(z.Prec() + 64).SetUint64(5)
+	f := new(big.Float).SetPrec(z.Prec()).SetMode(z.Mode()).SetUint64(5)
+	fexp := int64(f.MantExp(f)) // 5**(2**i) = f × 2**fexp
 
 	for n > 0 {
 		if n&1 != 0 {
 			z.Mul(z, f)
+			exp += fexp + int64(z.MantExp(z))
+		}
+		if n >>= 1; n > 0 {
+			f.Mul(f, f)
+			fexp = 2*fexp + int64(f.MantExp(f))
 		}
-		f.Mul(f, f)
-		n >>= 1
 	}
 
-	return z
+	return exp
 }
@@ -0,0 +1,132 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import "sync"
+
+// This file implements correctly rounded sums, dot products and polynomial
+// evaluation on top of Accumulator.
+
+// sumBuf holds the buffers used by Sum, Dot and Horner. They are reused across
+// calls through sumPool.
+type sumBuf struct {
+	a Accumulator
+	t Decimal
+}
+
+var sumPool sync.Pool
+
+func getSumBuf() *sumBuf {
+	if v := sumPool.Get(); v != nil {
+		b := v.(*sumBuf)
+		b.a.Reset()
+		return b
+	}
+	return new(sumBuf)
+}
+
+func putSumBuf(b *sumBuf) {
+	sumPool.Put(b)
+}
+
+// maxPrec returns the largest precision of xs, or prec if it is larger.
+func maxPrec(prec uint32, xs []*Decimal) uint32 {
+	for _, x := range xs {
+		prec = umax32(prec, x.prec)
+	}
+	return prec
+}
+
+// Sum sets z to the rounded sum of xs and returns z. The sum is computed
+// exactly and rounded only once, according to z's precision and rounding mode,
+// regardless of the number of terms; z's accuracy reports the result error
+// relative to the exact sum. If z's precision is 0, it is changed to the
+// largest precision of xs before the operation. The sum of an empty slice is
+// +0.
+//
+// Sum panics with ErrNaN if xs contains infinities with opposite signs. The
+// value of z is undefined in that case.
+//
+// See Accumulator for the rules that apply to the sign of an exact zero sum.
+func Sum(z *Decimal, xs []*Decimal) *Decimal {
+	b := getSumBuf()
+	defer putSumBuf(b)
+	for _, x := range xs {
+		b.a.Add(x)
+	}
+	if z.prec == 0 {
+		z.prec = maxPrec(0, xs)
+	}
+	return b.a.Decimal(z)
+}
+
+// Dot sets z to the rounded dot product of xs and ys, that is the sum of the
+// products xs[i]×ys[i], and returns z. The products and their sum are computed
+// exactly and rounded only once as for Sum. If z's precision is 0, it is
+// changed to the largest precision of xs and ys before the operation.
+//
+// Dot panics if xs and ys have different lengths. It panics with ErrNaN if
+// one of the products is zero times infinity, or if the products include
+// infinities with opposite signs. The value of z is undefined in that case.
+func Dot(z *Decimal, xs, ys []*Decimal) *Decimal {
+	if len(xs) != len(ys) {
+		panic("decimal.Dot: slices of different lengths")
+	}
+	b := getSumBuf()
+	defer putSumBuf(b)
+	for i, x := range xs {
+		b.a.AddProduct(x, ys[i])
+	}
+	if z.prec == 0 {
+		z.prec = maxPrec(maxPrec(0, xs), ys)
+	}
+	return b.a.Decimal(z)
+}
+
+// Horner sets z to the rounded value of the polynomial
+//
+//	coeffs[0] + coeffs[1]×x + coeffs[2]×x**2 + … + coeffs[n-1]×x**(n-1)
+//
+// and returns z. The polynomial is evaluated exactly with Horner's method and
+// the result rounded only once, according to z's precision and rounding mode.
+// If z's precision is 0, it is changed to the largest precision of x and
+// coeffs before the operation. The value of a polynomial with no coefficients
+// is +0.
+//
+// Since intermediate results are exact, their length grows by the number of
+// digits of x at each step: the cost of Horner is quadratic in the degree of
+// the polynomial.
+//
+// Horner panics with ErrNaN if an intermediate result is zero times infinity
+// or the sum of infinities with opposite signs. The value of z is undefined in
+// that case.
+func Horner(z *Decimal, x *Decimal, coeffs []*Decimal) *Decimal {
+	b := getSumBuf()
+	defer putSumBuf(b)
+	prec := z.prec
+	if prec == 0 {
+		prec = maxPrec(x.prec, coeffs)
+	}
+	if len(coeffs) == 0 {
+		z.prec = prec
+		return b.a.Decimal(z)
+	}
+	// t = coeffs[n-1]
+	// t = t×x + coeffs[i], for i = n-2 … 0
+	t := &b.t
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		b.a.Reset()
+		if i < len(coeffs)-1 {
+			b.a.AddProduct(t, x)
+		}
+		b.a.Add(coeffs[i])
+		if i > 0 {
+			t.prec = 0 // exact
+			b.a.Decimal(t)
+		}
+	}
+	z.prec = prec
+	return b.a.Decimal(z)
+}
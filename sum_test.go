@@ -0,0 +1,159 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestSumDotHorner(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	modes := []RoundingMode{ToNearestEven, ToNearestAway, ToZero, AwayFromZero, ToNegativeInf, ToPositiveInf}
+	for i := 0; i < 300; i++ {
+		n := r.Intn(30)
+		maxExp := []int{3, 30, 300}[r.Intn(3)]
+		xs := make([]*Decimal, n)
+		ys := make([]*Decimal, n)
+		sum, dot := new(big.Rat), new(big.Rat)
+		for j := range xs {
+			xs[j] = rndDecimal(r, uint(1+r.Intn(40)), maxExp)
+			ys[j] = rndDecimal(r, uint(1+r.Intn(40)), maxExp)
+			xr, _ := xs[j].Rat(nil)
+			yr, _ := ys[j].Rat(nil)
+			sum.Add(sum, xr)
+			dot.Add(dot, xr.Mul(xr, yr))
+		}
+		// polynomial in x with coefficients ys
+		x := rndDecimal(r, uint(1+r.Intn(10)), 2)
+		xr, _ := x.Rat(nil)
+		poly := new(big.Rat)
+		for j := n - 1; j >= 0; j-- {
+			c, _ := ys[j].Rat(nil)
+			poly.Mul(poly, xr).Add(poly, c)
+		}
+
+		prec := uint(1 + r.Intn(40))
+		mode := modes[r.Intn(len(modes))]
+		for _, test := range []struct {
+			name string
+			f    func(z *Decimal) *Decimal
+			want *big.Rat
+		}{
+			{"Sum", func(z *Decimal) *Decimal { return Sum(z, xs) }, sum},
+			{"Dot", func(z *Decimal) *Decimal { return Dot(z, xs, ys) }, dot},
+			{"Horner", func(z *Decimal) *Decimal { return Horner(z, x, ys) }, poly},
+		} {
+			z := new(Decimal).SetPrec(prec).SetMode(mode)
+			want := new(Decimal).SetPrec(prec).SetMode(mode).SetRat(test.want)
+			if got := test.f(z); got != z || z.Cmp(want) != 0 || z.Acc() != want.Acc() {
+				t.Fatalf("%s (prec %d, %s) = %s (%s); want %s (%s)", test.name, prec, mode, z, z.Acc(), want, want.Acc())
+			}
+		}
+	}
+}
+
+func TestSumDotHornerSpecialValues(t *testing.T) {
+	var (
+		one  = makeDecimal("1")
+		two  = new(Decimal).SetPrec(50).SetInt64(2)
+		tiny = makeDecimal("1e-60")
+		pinf = makeDecimal("+Inf")
+		ninf = makeDecimal("-Inf")
+		nz   = makeDecimal("-0")
+	)
+
+	// precision 0
+	z := Sum(new(Decimal), []*Decimal{new(Decimal).SetPrec(5).SetInt64(1), new(Decimal).SetPrec(7).SetInt64(1)})
+	if z.Prec() != 7 || z.Cmp(two) != 0 {
+		t.Errorf("Sum(1, 1) = %s (prec %d); want 2 (prec 7)", z, z.Prec())
+	}
+	if z := Dot(new(Decimal), []*Decimal{two}, []*Decimal{one}); z.Prec() != 1000 {
+		t.Errorf("Dot: got prec %d; want 1000", z.Prec())
+	}
+	if z := Horner(new(Decimal), two, nil); z.Prec() != 50 || !alike(z, makeDecimal("0")) {
+		t.Errorf("Horner(2, nil) = %s (prec %d); want 0 (prec 50)", z, z.Prec())
+	}
+
+	// a single rounding
+	z = new(Decimal).SetPrec(10)
+	if Sum(z, []*Decimal{one, tiny, new(Decimal).Neg(one)}); z.Cmp(tiny) != 0 || z.Acc() != Exact {
+		t.Errorf("Sum(1, 1e-60, -1) = %s (%s); want 1e-60 (Exact)", z, z.Acc())
+	}
+	// (1+1e-60)×(1-1e-60) - 1 = -1e-120
+	a := new(Decimal).SetPrec(100).Add(one, tiny)
+	b := new(Decimal).SetPrec(100).Sub(one, tiny)
+	if Dot(z, []*Decimal{a, one}, []*Decimal{b, makeDecimal("-1")}); z.Cmp(makeDecimal("-1e-120")) != 0 {
+		t.Errorf("Dot = %s; want -1e-120", z)
+	}
+	// 1 - 2x + x² at x = 1+1e-60: 1e-120
+	if Horner(z, a, []*Decimal{one, makeDecimal("-2"), one}); z.Cmp(makeDecimal("1e-120")) != 0 {
+		t.Errorf("Horner = %s; want 1e-120", z)
+	}
+
+	// aliasing
+	z = new(Decimal).SetPrec(10).SetInt64(3)
+	if Horner(z, z, []*Decimal{one, z, z}); z.Cmp(makeDecimal("37")) != 0 {
+		t.Errorf("Horner(z, z, {1, z, z}) = %s; want 37", z)
+	}
+
+	// special values
+	for _, test := range []struct {
+		name string
+		z    *Decimal
+		want string
+	}{
+		{"Sum()", Sum(new(Decimal), nil), "0"},
+		{"Sum(-0, -0)", Sum(new(Decimal), []*Decimal{nz, nz}), "-0"},
+		{"Sum(1, +Inf)", Sum(new(Decimal), []*Decimal{one, pinf}), "+Inf"},
+		{"Dot(-Inf, 2)", Dot(new(Decimal), []*Decimal{ninf}, []*Decimal{two}), "-Inf"},
+		{"Horner(+Inf, {1, -1})", Horner(new(Decimal), pinf, []*Decimal{one, new(Decimal).Neg(one)}), "-Inf"},
+		{"Horner(-0, {-0})", Horner(new(Decimal), nz, []*Decimal{nz}), "-0"},
+	} {
+		if want := makeDecimal(test.want); !alike(test.z, want) {
+			t.Errorf("%s = %s; want %s", test.name, test.z, test.want)
+		}
+	}
+
+	for _, f := range []func(){
+		func() { Sum(new(Decimal), []*Decimal{pinf, ninf}) },
+		func() { Dot(new(Decimal), []*Decimal{pinf}, []*Decimal{nz}) },
+		func() { Horner(new(Decimal), pinf, []*Decimal{ninf, one}) },
+		func() { Horner(new(Decimal), pinf, []*Decimal{one, nz}) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(ErrNaN); !ok {
+					t.Errorf("expected ErrNaN panic")
+				}
+			}()
+			f()
+		}()
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Dot with different lengths did not panic")
+			}
+		}()
+		Dot(new(Decimal), []*Decimal{one}, nil)
+	}()
+}
+
+func BenchmarkDot(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	xs := make([]*Decimal, 1000)
+	ys := make([]*Decimal, 1000)
+	for i := range xs {
+		xs[i] = rndDecimal(r, uint(1+r.Intn(20)), 5)
+		ys[i] = rndDecimal(r, uint(1+r.Intn(20)), 5)
+	}
+	z := new(Decimal).SetPrec(34)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Dot(z, xs, ys)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The default thresholds for the current GOARCH are in thresholds_$GOARCH.go,
+// generated by running go generate on a quiet machine. thresholds_generic.go
+// provides defaults for architectures without such a file.
+//
+//go:generate go test -run=^TestDecCalibrate$ -cpu=1 -timeout=0 -calibrate -calibrate.out=thresholds_$GOARCH.go
+
+// Thresholds holds the operand lengths, in Words, at which multiplication,
+// squaring, division and conversion to and from binary switch algorithms. A
+// Word holds 19 decimal digits on 64 bits platforms and 9 on 32 bits
+// platforms.
+//
+// The defaults are calibrated for each GOARCH. They can be overridden with the
+// DECIMAL_THRESHOLDS environment variable, read at program start, as a comma
+// separated list of name=value pairs where names are the lowercase field
+// names; for example:
+//
+//	DECIMAL_THRESHOLDS=karatsuba=40,ntt=2000
+//
+// Unknown names and invalid values are ignored.
+//
+type Thresholds struct {
+	Karatsuba    int // Karatsuba multiplication
+	BasicSqr     int // basic squaring instead of multiplication
+	KaratsubaSqr int // Karatsuba squaring
+	Toom3        int // Toom-Cook 3-way multiplication
+	Toom3Sqr     int // Toom-Cook 3-way squaring
+	NTT          int // number theoretic transform multiplication
+	NTTSqr       int // number theoretic transform squaring
+	DivRecursive int // recursive division
+	DivNewton    int // division by Newton's method
+	Conv         int // divide and conquer conversion to and from big.Word slices
+}
+
+// minThresholds holds the smallest values that the algorithms support.
+var minThresholds = Thresholds{
+	Karatsuba:    2,
+	BasicSqr:     1,
+	KaratsubaSqr: 2,
+	Toom3:        9,
+	Toom3Sqr:     9,
+	NTT:          2,
+	NTTSqr:       2,
+	DivRecursive: 4,
+	DivNewton:    2,
+	Conv:         2,
+}
+
+func (t *Thresholds) fields() []*int {
+	return []*int{
+		&t.Karatsuba, &t.BasicSqr, &t.KaratsubaSqr,
+		&t.Toom3, &t.Toom3Sqr, &t.NTT, &t.NTTSqr,
+		&t.DivRecursive, &t.DivNewton, &t.Conv,
+	}
+}
+
+// thresholdNames are the DECIMAL_THRESHOLDS names of the fields of Thresholds,
+// in the order returned by fields.
+var thresholdNames = [...]string{
+	"karatsuba", "basicsqr", "karatsubasqr",
+	"toom3", "toom3sqr", "ntt", "nttsqr",
+	"divrecursive", "divnewton", "conv",
+}
+
+func currentThresholds() []*int {
+	return []*int{
+		&decKaratsubaThreshold, &decBasicSqrThreshold, &decKaratsubaSqrThreshold,
+		&decToom3Threshold, &decToom3SqrThreshold, &decNTTThreshold, &decNTTSqrThreshold,
+		&decDivRecursiveThreshold, &decDivNewtonThreshold, &decConvThreshold,
+	}
+}
+
+// SetThresholds sets the algorithm thresholds to the non-zero fields of t and
+// returns the previous settings. Values below the minimum supported by an
+// algorithm are raised to that minimum. SetThresholds(Thresholds{}) returns the
+// current settings without changing them.
+//
+// SetThresholds is not safe for concurrent use with any other function or
+// method of the package; it is meant to be called during program
+// initialization.
+//
+func SetThresholds(t Thresholds) Thresholds {
+	var old Thresholds
+	cur, prev, min := currentThresholds(), old.fields(), minThresholds.fields()
+	for i, p := range t.fields() {
+		*prev[i] = *cur[i]
+		if v := *p; v > 0 {
+			if v < *min[i] {
+				v = *min[i]
+			}
+			*cur[i] = v
+		}
+	}
+	return old
+}
+
+// parseThresholds parses a DECIMAL_THRESHOLDS value.
+func parseThresholds(s string) Thresholds {
+	var t Thresholds
+	fields := t.fields()
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		k := strings.ToLower(strings.TrimSpace(kv[:i]))
+		v, err := strconv.Atoi(strings.TrimSpace(kv[i+1:]))
+		if err != nil || v <= 0 {
+			continue
+		}
+		for j, n := range thresholdNames {
+			if n == k {
+				*fields[j] = v
+				break
+			}
+		}
+	}
+	return t
+}
+
+func init() {
+	if s := os.Getenv("DECIMAL_THRESHOLDS"); s != "" {
+		SetThresholds(parseThresholds(s))
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !386,!amd64
+
+package decimal
+
+// archThresholds holds the default thresholds for architectures that have not
+// been calibrated. This file is maintained by hand: dec_calibrate_test.go only
+// updates its build constraint.
+var archThresholds = Thresholds{
+	Karatsuba:    30,
+	BasicSqr:     10,
+	KaratsubaSqr: 50,
+	Toom3:        150,          // estimate, not calibrated
+	Toom3Sqr:     100,          // estimate, not calibrated
+	NTT:          only64(1000), // estimate, not calibrated
+	NTTSqr:       only64(1000), // estimate, not calibrated
+	DivRecursive: 100,
+	DivNewton:    only64(35000), // estimate, not calibrated
+	Conv:         40,
+}
+
+// only64 returns th on 64 bits platforms. On 32 bits platforms, it returns a
+// threshold that disables the algorithm: on 386, NTT multiplication and
+// Newton division never beat the fallback algorithms.
+func only64(th int) int {
+	if _W == 32 {
+		return 1e9
+	}
+	return th
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want Thresholds
+	}{
+		{"", Thresholds{}},
+		{"karatsuba=40", Thresholds{Karatsuba: 40}},
+		{" NTT = 2000 ,toom3sqr=70", Thresholds{NTT: 2000, Toom3Sqr: 70}},
+		{"conv=12,divrecursive=80,divnewton=9000", Thresholds{Conv: 12, DivRecursive: 80, DivNewton: 9000}},
+		{"basicsqr=5,karatsubasqr=60,toom3=200,nttsqr=3000", Thresholds{BasicSqr: 5, KaratsubaSqr: 60, Toom3: 200, NTTSqr: 3000}},
+		{"karatsuba,ntt=x,toom3=-1,foo=12,conv=0", Thresholds{}},
+	} {
+		if got := parseThresholds(tc.s); got != tc.want {
+			t.Errorf("parseThresholds(%q) = %+v, want %+v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestSetThresholds(t *testing.T) {
+	def := SetThresholds(Thresholds{})
+	defer SetThresholds(def)
+	// the defaults are overridden by DECIMAL_THRESHOLDS
+	want := archThresholds
+	env := parseThresholds(os.Getenv("DECIMAL_THRESHOLDS"))
+	wf, min := want.fields(), minThresholds.fields()
+	for i, p := range env.fields() {
+		if *p > 0 {
+			*wf[i] = max(*p, *min[i])
+		}
+	}
+	if def != want {
+		t.Fatalf("default thresholds = %+v, want %+v", def, want)
+	}
+
+	old := SetThresholds(Thresholds{Karatsuba: 1, NTT: 5000})
+	if old != def {
+		t.Fatalf("SetThresholds returned %+v, want %+v", old, def)
+	}
+	want = def
+	want.Karatsuba, want.NTT = minThresholds.Karatsuba, 5000
+	if got := SetThresholds(Thresholds{}); got != want {
+		t.Fatalf("got thresholds %+v, want %+v", got, want)
+	}
+	if decKaratsubaThreshold != want.Karatsuba || decNTTThreshold != want.NTT {
+		t.Fatalf("thresholds not applied: %d, %d", decKaratsubaThreshold, decNTTThreshold)
+	}
+}
+
+// TestMinThresholds checks that all algorithms give the same results when used
+// down to their minimum threshold.
+func TestMinThresholds(t *testing.T) {
+	var x, y []dec
+	for _, n := range []int{1, 2, 3, 9, 17, 40, 111, 300} {
+		x = append(x, rndDec1(n))
+		y = append(y, rndDec1(n*2/3+1))
+	}
+	type result struct{ mul, sqr, q, r dec }
+	run := func() (res []result) {
+		for i := range x {
+			var rr result
+			rr.mul = dec(nil).mul(x[i], y[i])
+			rr.sqr = dec(nil).sqr(x[i])
+			rr.q, rr.r = dec(nil).div(nil, rr.mul.add(rr.mul, x[i]), y[i])
+			res = append(res, rr)
+			if got := dec(nil).setNat(decToNat(nil, x[i])); got.cmp(x[i]) != 0 {
+				t.Fatalf("conversion of %d words: got %v, want %v", len(x[i]), got, x[i])
+			}
+		}
+		return res
+	}
+
+	def := SetThresholds(Thresholds{})
+	defer SetThresholds(def)
+	want := run()
+	SetThresholds(Thresholds{1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+	got := run()
+	for i := range want {
+		if got[i].mul.cmp(want[i].mul) != 0 ||
+			got[i].sqr.cmp(want[i].sqr) != 0 ||
+			got[i].q.cmp(want[i].q) != 0 ||
+			got[i].r.cmp(want[i].r) != 0 {
+			t.Errorf("%d words: results differ with minimum thresholds", len(x[i]))
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+// A Workspace holds the scratch memory of arithmetic operations. Operations
+// performed with the methods of a Workspace take all their temporaries from
+// it, instead of the package's shared pools, and once the Workspace has grown
+// to the size needed by the operands, they do not allocate memory (except for
+// the receiver's mantissa if it needs to grow).
+//
+// The exception are the goroutines started by multiplications of very large
+// operands when concurrency is enabled (see SetConcurrency), which still use
+// the shared pools.
+//
+// The zero value for a Workspace is ready to use. A nil *Workspace is valid
+// too: its methods then behave like the corresponding Decimal methods. A
+// Workspace must not be used by several goroutines concurrently.
+//
+// Example:
+//
+//	var w decimal.Workspace
+//	for i := range x {
+//		w.Mul(z, x[i], y[i])
+//		w.Add(sum, sum, z)
+//	}
+//
+type Workspace struct {
+	decs  []*dec         // free temporaries
+	temps []*dec         // temporaries of dec.divRecursive
+	toom3 []*decToom3Buf // free decToom3 buffers
+	ntt   []*nttBuf      // free decNTT buffers
+	sqrt  [3]Decimal     // temporaries of Decimal.sqrtInverse
+}
+
+// Add sets z to the rounded sum x+y and returns z. See Decimal.Add.
+func (w *Workspace) Add(z, x, y *Decimal) *Decimal {
+	return z.add(w, x, y)
+}
+
+// Sub sets z to the rounded difference x-y and returns z. See Decimal.Sub.
+func (w *Workspace) Sub(z, x, y *Decimal) *Decimal {
+	return z.sub(w, x, y)
+}
+
+// Mul sets z to the rounded product x*y and returns z. See Decimal.Mul.
+func (w *Workspace) Mul(z, x, y *Decimal) *Decimal {
+	return z.mul(w, x, y)
+}
+
+// Quo sets z to the rounded quotient x/y and returns z. See Decimal.Quo.
+func (w *Workspace) Quo(z, x, y *Decimal) *Decimal {
+	return z.quo(w, x, y)
+}
+
+// Sqrt sets z to the rounded square root of x, and returns z. See
+// Decimal.Sqrt.
+func (w *Workspace) Sqrt(z, x *Decimal) *Decimal {
+	return z.sqrt(w, x)
+}
+
+// Reset releases the memory held by w.
+func (w *Workspace) Reset() {
+	*w = Workspace{}
+}
+
+// getDec is like the getDec function, but takes the temporary from w if w is
+// not nil.
+func (w *Workspace) getDec(n int) *dec {
+	if w == nil {
+		return getDec(n)
+	}
+	var z *dec
+	if k := len(w.decs); k > 0 {
+		z = w.decs[k-1]
+		w.decs = w.decs[:k-1]
+	} else {
+		z = new(dec)
+	}
+	*z = z.make(n)
+	return z
+}
+
+// putDec returns a temporary obtained with w.getDec.
+func (w *Workspace) putDec(x *dec) {
+	if w == nil {
+		putDec(x)
+		return
+	}
+	w.decs = append(w.decs, x)
+}
+
+// getTemps returns a slice of n nil *dec. If w is not nil, the slice is
+// reused by the next call, so that only one such slice may be in use at a
+// time.
+func (w *Workspace) getTemps(n int) []*dec {
+	if w == nil {
+		return make([]*dec, n)
+	}
+	if cap(w.temps) < n {
+		w.temps = make([]*dec, n)
+	}
+	t := w.temps[:n]
+	for i := range t {
+		t[i] = nil
+	}
+	return t
+}
+
+func (w *Workspace) getDecToom3Buf() *decToom3Buf {
+	if w == nil {
+		return getDecToom3Buf()
+	}
+	if k := len(w.toom3); k > 0 {
+		b := w.toom3[k-1]
+		w.toom3 = w.toom3[:k-1]
+		return b
+	}
+	return new(decToom3Buf)
+}
+
+func (w *Workspace) putDecToom3Buf(b *decToom3Buf) {
+	if w == nil {
+		putDecToom3Buf(b)
+		return
+	}
+	b.x, b.y = [3]decInt{}, [3]decInt{}
+	w.toom3 = append(w.toom3, b)
+}
+
+func (w *Workspace) getNTTBuf() *nttBuf {
+	if w == nil {
+		return getNTTBuf()
+	}
+	if k := len(w.ntt); k > 0 {
+		b := w.ntt[k-1]
+		w.ntt = w.ntt[:k-1]
+		return b
+	}
+	return new(nttBuf)
+}
+
+func (w *Workspace) putNTTBuf(b *nttBuf) {
+	if w == nil {
+		putNTTBuf(b)
+		return
+	}
+	w.ntt = append(w.ntt, b)
+}
+
+// sqrtTemps returns the three temporaries of z.sqrtInverse, with space for
+// twice the precision prec.
+func (w *Workspace) sqrtTemps(prec uint32) (t, u, v *Decimal) {
+	if w == nil {
+		return newDecimal(prec), newDecimal(prec), newDecimal(prec)
+	}
+	for i := range w.sqrt {
+		d := &w.sqrt[i]
+		*d = Decimal{mant: d.mant.make(int(prec/_DW) * 2)}
+	}
+	return &w.sqrt[0], &w.sqrt[1], &w.sqrt[2]
+}
+
+// mulMant sets z.mant to the product of the mantissae of x and y.
+func (w *Workspace) mulMant(z, x, y *Decimal) {
+	m, tp := w.mantBuf(z, x.mant, y.mant)
+	if x == y {
+		m = m.sqrWith(w, x.mant)
+	} else {
+		m = m.mulWith(w, x.mant, y.mant)
+	}
+	w.setMant(z, x, y, m, tp)
+}
+
+// mantBuf returns the storage in which to compute a new mantissa for z from
+// the operands a and b. If z.mant aliases one of them, dec.mul and dec.div
+// would allocate a new mantissa: a temporary from w is returned instead, to
+// be exchanged with z.mant by setMant.
+func (w *Workspace) mantBuf(z *Decimal, a, b dec) (m dec, tp *dec) {
+	m = z.mant
+	if w != nil && (alias(m, a) || alias(m, b)) {
+		tp = w.getDec(0)
+		m = *tp
+	}
+	return m, tp
+}
+
+// setMant sets z.mant to m, computed in the storage returned by mantBuf for
+// an operation on x and y.
+func (w *Workspace) setMant(z, x, y *Decimal, m dec, tp *dec) {
+	if tp != nil {
+		// z's previous mantissa may only be reused if it is not shared with
+		// another Decimal.
+		*tp = nil
+		if z == x || z == y {
+			*tp = z.mant[:0]
+		}
+		w.putDec(tp)
+	}
+	z.mant = m
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Denis Bernard <db047h@gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decimal
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+var workspacePrecs = []uint{10, 34, 100, 1000, 4000}
+
+// workspaceThresholds lowers the thresholds so that Toom-3, NTT, recursive
+// division and Newton division are used with the precisions above.
+var workspaceThresholds = Thresholds{
+	Karatsuba: 2, BasicSqr: 1, KaratsubaSqr: 2, Toom3: 9, Toom3Sqr: 9,
+	NTT: 30, NTTSqr: 30, DivRecursive: 4, DivNewton: 8,
+}
+
+// workspaceOps returns the operations tested with and without a Workspace.
+func workspaceOps(z, x, y *Decimal) []struct {
+	name string
+	f    func(w *Workspace)
+} {
+	return []struct {
+		name string
+		f    func(w *Workspace)
+	}{
+		{"Add", func(w *Workspace) { w.Add(z, x, y) }},
+		{"Sub", func(w *Workspace) { w.Sub(z, x, y) }},
+		{"Mul", func(w *Workspace) { w.Mul(z, x, y) }},
+		{"Sqr", func(w *Workspace) { w.Mul(z, x, x) }},
+		{"Quo", func(w *Workspace) { w.Quo(z, x, y) }},
+		{"Sqrt", func(w *Workspace) { w.Sqrt(z, x) }},
+		{"aliased Add", func(w *Workspace) { w.Add(z.Set(x), z, y) }},
+		{"aliased Sub", func(w *Workspace) { w.Sub(z.Set(y), x, z) }},
+		{"aliased Mul", func(w *Workspace) { w.Mul(z.Set(x), z, y) }},
+		{"aliased Sqr", func(w *Workspace) { w.Mul(z.Set(x), z, z) }},
+		{"aliased Quo", func(w *Workspace) { w.Quo(z.Set(y), x, z) }},
+		{"aliased Sqrt", func(w *Workspace) { w.Sqrt(z.Set(x), z) }},
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	def := SetThresholds(Thresholds{})
+	defer SetThresholds(def)
+	r := rand.New(rand.NewSource(1))
+	for _, th := range []Thresholds{def, workspaceThresholds} {
+		SetThresholds(th)
+		var w Workspace
+		for _, prec := range workspacePrecs {
+			x, y := rndDecimal(r, prec, 10), rndDecimal(r, prec+prec/3, 10)
+			x.Abs(x) // for Sqrt
+			z := new(Decimal).SetPrec(prec)
+			for _, op := range workspaceOps(z, x, y) {
+				want := new(Decimal)
+				op.f(nil)
+				want.Copy(z)
+				for i := 0; i < 2; i++ {
+					op.f(&w)
+					if z.Cmp(want) != 0 || z.Acc() != want.Acc() {
+						t.Fatalf("%d digits, %s: got %v (%v), want %v (%v)", prec, op.name, z, z.Acc(), want, want.Acc())
+					}
+				}
+				if x.Prec() != prec || y.Prec() != prec+prec/3 {
+					t.Fatalf("%d digits, %s: operands modified", prec, op.name)
+				}
+			}
+		}
+	}
+}
+
+func TestWorkspaceAllocs(t *testing.T) {
+	def := SetThresholds(Thresholds{})
+	defer SetThresholds(def)
+	r := rand.New(rand.NewSource(1))
+	for _, th := range []Thresholds{def, workspaceThresholds} {
+		SetThresholds(th)
+		var w Workspace
+		for _, prec := range workspacePrecs {
+			x, y := rndDecimal(r, prec, 10), rndDecimal(r, prec+prec/3, 10)
+			x.Abs(x) // for Sqrt
+			z := new(Decimal).SetPrec(prec)
+			for _, op := range workspaceOps(z, x, y) {
+				op.f(&w) // warm up
+				if allocs := testing.AllocsPerRun(10, func() { op.f(&w) }); allocs != 0 {
+					t.Errorf("%d digits, %s: got %v allocs; want 0", prec, op.name, allocs)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkWorkspace(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, prec := range workspacePrecs {
+		x, y := rndDecimal(r, prec, 10), rndDecimal(r, prec+prec/3, 10)
+		x.Abs(x) // for Sqrt
+		z := new(Decimal).SetPrec(prec)
+		var w Workspace
+		for _, op := range workspaceOps(z, x, y)[2:6] {
+			op := op
+			b.Run(fmt.Sprintf("%s/%d", op.name, prec), func(b *testing.B) {
+				b.ReportAllocs()
+				op.f(&w)
+				for i := 0; i < b.N; i++ {
+					op.f(&w)
+				}
+			})
+		}
+	}
+}